@@ -0,0 +1,42 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+)
+
+// Generate runs a complete generation for cfg.Prompt: it sends the prompt to
+// the model, writes the files into cfg.OutputDir and calls onFile, if not nil,
+// after each file is written, in order. Start from DefaultConfig to get the
+// settings of the command line tool. The prompt is not read from stdin, so it
+// must be set.
+func Generate(ctx context.Context, cfg Config, onFile func(File)) ([]File, error) {
+	if cfg.Prompt == "" {
+		return nil, errors.New("prompt is required")
+	}
+	gen, err := newModelGenerator(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer gen.Close()
+
+	files, err := generateFiles(ctx, cfg, gen)
+	if err != nil {
+		return nil, err
+	}
+	return files, writeGenerated(osFS{}, cfg, files, onFile)
+}
+
+// writeGenerated writes the files like writeFiles and calls onFile, if not
+// nil, with each file that was written, in order.
+func writeGenerated(fsys FS, cfg Config, files []File, onFile func(File)) error {
+	var report func(File, writeResult)
+	if onFile != nil {
+		report = func(file File, result writeResult) {
+			if result.Status == writeWritten {
+				onFile(file)
+			}
+		}
+	}
+	return writeFilesFunc(fsys, cfg, files, report)
+}
@@ -0,0 +1,41 @@
+package agentcoder
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteGeneratedCallsOnFileInOrder(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	files := []File{
+		{Name: "b.go", Code: "package b\n"},
+		{Name: "../escape.go", Code: "package escape\n"},
+		{Name: "a/a.go", Code: "package a\n"},
+	}
+	var written []string
+	onFile := func(file File) {
+		// The file is on disk when onFile is called
+		if data, err := fsys.ReadFile(filepath.Join("out", file.Name)); err != nil || string(data) != file.Code {
+			t.Errorf("%s = %q, %v when onFile is called, want the written file", file.Name, data, err)
+		}
+		written = append(written, file.Name)
+	}
+	captureStdout(t, func() {
+		if err := writeGenerated(fsys, cfg, files, onFile); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if want := []string{"b.go", "a/a.go"}; !reflect.DeepEqual(written, want) {
+		t.Errorf("onFile called with %v, want %v", written, want)
+	}
+}
+
+func TestGenerateRequiresPrompt(t *testing.T) {
+	if _, err := Generate(context.Background(), DefaultConfig(), nil); err == nil {
+		t.Error("Generate without a prompt succeeded")
+	}
+}
@@ -0,0 +1,47 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// maxAttachmentBytes is the limit of inline data in a request.
+const maxAttachmentBytes = 20 << 20
+
+// attachmentTypes maps the extensions of the supported attachments to their
+// MIME types.
+var attachmentTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".webp": "image/webp",
+	".heic": "image/heic",
+	".heif": "image/heif",
+	".pdf":  "application/pdf",
+}
+
+// loadAttachments reads the images and PDFs attached with --attach as parts
+// sent alongside the prompt.
+func loadAttachments(paths []string) ([]genai.Part, error) {
+	var parts []genai.Part
+	total := 0
+	for _, path := range paths {
+		mimeType, ok := attachmentTypes[strings.ToLower(filepath.Ext(path))]
+		if !ok {
+			return nil, fmt.Errorf("unsupported attachment %s, expected an image or PDF", path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading attachment: %w", err)
+		}
+		if total += len(data); total > maxAttachmentBytes {
+			return nil, fmt.Errorf("attachments exceed the limit of %d MB", maxAttachmentBytes>>20)
+		}
+		parts = append(parts, genai.Blob{MIMEType: mimeType, Data: data})
+	}
+	return parts, nil
+}
@@ -0,0 +1,53 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAttachmentSentAsBlob(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\nfake image")
+	path := filepath.Join(t.TempDir(), "screen.PNG")
+	if err := os.WriteFile(path, png, 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.Attachments = []string{path}
+	gen, api := newTestGenerator(t, cfg, textResponse("[]", "STOP"))
+	if _, err := gen.Generate(context.Background(), "Build this UI."); err != nil {
+		t.Fatal(err)
+	}
+	body := api.bodies[0]
+	for _, want := range []string{`"text":"Build this UI."`, `"mimeType":"image/png"`, `"data":"` + base64.StdEncoding.EncodeToString(png) + `"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request %s does not contain %s", body, want)
+		}
+	}
+	if strings.Index(body, "Build this UI.") > strings.Index(body, "image/png") {
+		t.Error("attachment sent before the prompt")
+	}
+}
+
+func TestAttachmentErrors(t *testing.T) {
+	dir := t.TempDir()
+	text := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(text, []byte("notes"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	big := filepath.Join(dir, "big.pdf")
+	if err := os.WriteFile(big, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Truncate(big, maxAttachmentBytes+1); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{text, big, filepath.Join(dir, "missing.png")} {
+		if _, err := loadAttachments([]string{path}); err == nil {
+			t.Errorf("loadAttachments() accepted %s", filepath.Base(path))
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// hasGoFiles reports whether any of the files is Go source.
+func hasGoFiles(files []File) bool {
+	for _, file := range files {
+		if strings.EqualFold(filepath.Ext(file.Name), ".go") {
+			return true
+		}
+	}
+	return false
+}
+
+// goBuild builds every package in dir and returns the compiler output. The
+// build is killed after timeout.
+func goBuild(dir string, timeout time.Duration) (string, error) {
+	out, err := runCommand(timeout, dir, "go", "build", "./...")
+	return string(out), err
+}
+
+// mergeFiles replaces the files in files with their fixed versions by name and
+// appends fixed files that were not present before.
+func mergeFiles(files, fixed []File) []File {
+	merged := append([]File(nil), files...)
+	index := make(map[string]int, len(merged))
+	for i, file := range merged {
+		index[file.Name] = i
+	}
+	for _, file := range fixed {
+		if i, ok := index[file.Name]; ok {
+			merged[i] = file
+			continue
+		}
+		index[file.Name] = len(merged)
+		merged = append(merged, file)
+	}
+	return merged
+}
+
+// autoFix builds the generated Go code and, while the build fails, sends the
+// compiler errors back to the model and rewrites the files it corrects. It
+// returns the final set of files.
+func autoFix(ctx context.Context, cfg Config, gen generator, files []File) ([]File, error) {
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "go.mod")); err != nil {
+		return files, fmt.Errorf("auto-fix requires a go.mod in %s", cfg.OutputDir)
+	}
+	for attempt := 1; ; attempt++ {
+		out, err := goBuild(cfg.OutputDir, cfg.commandTimeout())
+		if errors.Is(err, errCommandTimeout) {
+			return files, err
+		}
+		if err == nil {
+			fmt.Println("\nBuild succeeded")
+			return files, nil
+		}
+		if attempt > cfg.AutoFixIterations {
+			return files, fmt.Errorf("build still failing after %d fix attempt(s):\n%s", cfg.AutoFixIterations, out)
+		}
+
+		fmt.Printf("\nBuild failed, asking the model for fixes (attempt %d of %d):\n%s", attempt, cfg.AutoFixIterations, out)
+		r, err := gen.Generate(ctx, fixPrompt(files, out))
+		if err != nil {
+			return files, fmt.Errorf("generating fixes: %w", err)
+		}
+		fixed, err := parseResponse(cfg, r.Text)
+		if err != nil {
+			return files, err
+		}
+		fixed = transformFiles(cfg, fixed)
+		if fixed, err = filterFiles(cfg, fixed); err != nil {
+			return files, err
+		}
+		files = mergeFiles(files, fixed)
+		if err := writeFiles(osFS{}, cfg, fixed); err != nil {
+			return files, err
+		}
+	}
+}
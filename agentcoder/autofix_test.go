@@ -0,0 +1,76 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAutoFix(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.AutoFixIterations = 2
+	if err := os.WriteFile(filepath.Join(cfg.OutputDir, "go.mod"), []byte("module example.com/fix\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := []File{{Name: "main.go", Code: "package main\n\nfunc main() {\n\tundefinedFunction()\n}\n"}}
+	if err := writeFiles(osFS{}, cfg, broken); err != nil {
+		t.Fatal(err)
+	}
+	fixed := File{Name: "main.go", Code: "package main\n\nfunc main() {}\n"}
+	gen := &fakeGenerator{replies: []*reply{filesReply(t, fixed)}}
+
+	files, err := autoFix(context.Background(), cfg, gen, broken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gen.prompts) != 1 || !strings.Contains(gen.prompts[0], "undefined: undefinedFunction") {
+		t.Errorf("prompts = %q, want one prompt with the compiler error", gen.prompts)
+	}
+	if len(files) != 1 || files[0] != fixed {
+		t.Errorf("files = %v, want the fixed file", files)
+	}
+	got, err := os.ReadFile(filepath.Join(cfg.OutputDir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != fixed.Code {
+		t.Errorf("main.go = %q, want the fixed code", got)
+	}
+}
+
+func TestAutoFixGivesUp(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.AutoFixIterations = 1
+	if err := os.WriteFile(filepath.Join(cfg.OutputDir, "go.mod"), []byte("module example.com/fix\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	broken := File{Name: "main.go", Code: "package main\n\nfunc main() { x }\n"}
+	if err := writeFiles(osFS{}, cfg, []File{broken}); err != nil {
+		t.Fatal(err)
+	}
+	gen := &fakeGenerator{replies: []*reply{filesReply(t, broken)}}
+	if _, err := autoFix(context.Background(), cfg, gen, []File{broken}); err == nil || !strings.Contains(err.Error(), "after 1 fix attempt") {
+		t.Errorf("autoFix() error = %v, want the build to still fail", err)
+	}
+}
+
+func TestMergeFiles(t *testing.T) {
+	files := []File{{Name: "a", Code: "1"}, {Name: "b", Code: "2"}}
+	merged := mergeFiles(files, []File{{Name: "b", Code: "3"}, {Name: "c", Code: "4"}})
+	want := []File{{Name: "a", Code: "1"}, {Name: "b", Code: "3"}, {Name: "c", Code: "4"}}
+	if len(merged) != len(want) {
+		t.Fatalf("merged = %v, want %v", merged, want)
+	}
+	for i := range want {
+		if merged[i] != want[i] {
+			t.Errorf("merged = %v, want %v", merged, want)
+		}
+	}
+	if files[1].Code != "2" {
+		t.Error("mergeFiles modified its argument")
+	}
+}
@@ -0,0 +1,163 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// batchCheckpointFile is the name of the file in the output directory that
+// records the prompts of a batch that have been completed.
+const batchCheckpointFile = ".agent_coder_batch.json"
+
+// batchCheckpoint records the progress of a batch run so it can be resumed.
+type batchCheckpoint struct {
+	BatchDir  string   `json:"batch_dir"` // Directory of the prompt files
+	Completed []string `json:"completed"` // Prompt files whose output was written
+}
+
+// batchPrompts returns the prompt files in dir, in name order.
+func batchPrompts(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".txt", ".md", ".prompt":
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+	}
+	return names, nil
+}
+
+// batchOutputName returns the name of the subdirectory of the output
+// directory that the files of the prompt file are written to.
+func batchOutputName(name string) string {
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+// checkBatchCollisions returns an error if two prompt files, such as a.txt
+// and a.md, would write into the same subdirectory.
+func checkBatchCollisions(prompts []string) error {
+	seen := make(map[string]string, len(prompts))
+	for _, name := range prompts {
+		dir := batchOutputName(name)
+		if prev, ok := seen[dir]; ok {
+			return fmt.Errorf("prompt files %s and %s would both write to %s, rename one of them", prev, name, dir)
+		}
+		seen[dir] = name
+	}
+	return nil
+}
+
+// loadCheckpoint reads the checkpoint at path, returning an empty one if it
+// does not exist.
+func loadCheckpoint(path string) (batchCheckpoint, error) {
+	var cp batchCheckpoint
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("parsing checkpoint %s: %w", path, err)
+	}
+	return cp, nil
+}
+
+// saveCheckpoint writes the checkpoint to path.
+func saveCheckpoint(path string, cp batchCheckpoint) error {
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// runBatch generates the files for every prompt file in the batch directory,
+// writing the output of each into a subdirectory of the output directory named
+// after the prompt file. Completed prompts are recorded in a checkpoint after
+// each one, and with --resume the prompts completed by an earlier run are
+// skipped. The batch stops between prompts when ctx is cancelled.
+func runBatch(ctx context.Context, cfg Config, gen *modelGenerator) error {
+	prompts, err := batchPrompts(cfg.BatchDir)
+	if err != nil {
+		return fmt.Errorf("reading batch directory: %w", err)
+	}
+	if err := checkBatchCollisions(prompts); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cfg.OutputDir, os.FileMode(cfg.DirMode)); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	cpPath := filepath.Join(cfg.OutputDir, batchCheckpointFile)
+	cp := batchCheckpoint{BatchDir: cfg.BatchDir}
+	if cfg.Resume {
+		if cp, err = loadCheckpoint(cpPath); err != nil {
+			return err
+		}
+		if cp.BatchDir != "" && cp.BatchDir != cfg.BatchDir {
+			return fmt.Errorf("checkpoint %s belongs to batch directory %s", cpPath, cp.BatchDir)
+		}
+		cp.BatchDir = cfg.BatchDir
+	}
+
+	failed := 0
+	for i, name := range prompts {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("batch interrupted after %d of %d prompt(s), continue with --resume: %w", len(cp.Completed), len(prompts), err)
+		}
+		if slices.Contains(cp.Completed, name) {
+			fmt.Printf("Skipping %s, completed by an earlier run\n", name)
+			continue
+		}
+		fmt.Printf("\n=== Prompt %d of %d: %s ===\n", i+1, len(prompts), name)
+		if err := runBatchPrompt(ctx, cfg, gen, name); err != nil {
+			fmt.Printf("Error: %s: %v\n", name, err)
+			failed++
+			continue
+		}
+		cp.Completed = append(cp.Completed, name)
+		if err := saveCheckpoint(cpPath, cp); err != nil {
+			return fmt.Errorf("saving checkpoint: %w", err)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d prompt(s) failed, retry them with --resume", failed, len(prompts))
+	}
+	return nil
+}
+
+// runBatchPrompt generates and writes the files for one prompt file of the
+// batch.
+func runBatchPrompt(ctx context.Context, cfg Config, gen *modelGenerator, name string) error {
+	data, err := os.ReadFile(filepath.Join(cfg.BatchDir, name))
+	if err != nil {
+		return err
+	}
+	return generateInto(ctx, cfg, gen, string(data), filepath.Join(cfg.OutputDir, batchOutputName(name)))
+}
+
+// generateInto generates the files for prompt and writes them into dir
+// instead of the configured output directory.
+func generateInto(ctx context.Context, cfg Config, gen *modelGenerator, prompt, dir string) error {
+	cfg.Prompt = prompt
+	cfg.OutputDir = dir
+	files, err := generateFiles(ctx, cfg, gen)
+	if err != nil {
+		return err
+	}
+	return writeFiles(osFS{}, cfg, files)
+}
@@ -0,0 +1,91 @@
+package agentcoder
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// batchConfig returns a config for a batch of the prompt files.
+func batchConfig(t *testing.T, prompts map[string]string) Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.BatchDir = t.TempDir()
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	for name, prompt := range prompts {
+		if err := os.WriteFile(filepath.Join(cfg.BatchDir, name), []byte(prompt), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+func TestBatchResume(t *testing.T) {
+	cfg := batchConfig(t, map[string]string{"a.txt": "Write a.", "b.md": "Write b.", "c.prompt": "Write c.", "notes.csv": "ignored"})
+
+	// The second prompt fails
+	gen, api := newTestGenerator(t, cfg,
+		textResponse(`[{"file_name": "a.go", "source_code": "package a\n"}]`, "STOP"),
+		errorResponse(http.StatusBadRequest, "Invalid argument."),
+		textResponse(`[{"file_name": "c.go", "source_code": "package c\n"}]`, "STOP"),
+	)
+	if err := runBatch(context.Background(), cfg, gen); err == nil {
+		t.Fatal("runBatch() succeeded with a failed prompt")
+	}
+	if api.requests() != 3 {
+		t.Fatalf("%d requests, want 3", api.requests())
+	}
+	cp, err := loadCheckpoint(filepath.Join(cfg.OutputDir, batchCheckpointFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.txt", "c.prompt"}; !reflect.DeepEqual(cp.Completed, want) {
+		t.Errorf("completed = %v, want %v", cp.Completed, want)
+	}
+
+	// Resuming only processes the failed prompt
+	cfg.Resume = true
+	gen, api = newTestGenerator(t, cfg, textResponse(`[{"file_name": "b.go", "source_code": "package b\n"}]`, "STOP"))
+	if err := runBatch(context.Background(), cfg, gen); err != nil {
+		t.Fatal(err)
+	}
+	if api.requests() != 1 || !strings.Contains(api.bodies[0], "Write b.") {
+		t.Errorf("resumed requests = %v, want only the prompt of b.md", api.bodies)
+	}
+	tree := readTree(t, cfg.OutputDir)
+	for _, path := range []string{"a/a.go", "b/b.go", "c/c.go"} {
+		if _, ok := tree[path]; !ok {
+			t.Errorf("%s not written, output is %v", path, tree)
+		}
+	}
+}
+
+func TestBatchResumeOtherBatch(t *testing.T) {
+	cfg := batchConfig(t, map[string]string{"a.txt": "Write a."})
+	cp := batchCheckpoint{BatchDir: "other", Completed: []string{"a.txt"}}
+	if err := saveCheckpoint(filepath.Join(cfg.OutputDir, batchCheckpointFile), cp); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Resume = true
+	gen, _ := newTestGenerator(t, cfg)
+	if err := runBatch(context.Background(), cfg, gen); err == nil {
+		t.Error("checkpoint of another batch directory accepted")
+	}
+}
+
+func TestBatchOutputCollision(t *testing.T) {
+	cfg := batchConfig(t, map[string]string{"a.txt": "Write a.", "a.md": "Write another a."})
+	gen, api := newTestGenerator(t, cfg)
+	err := runBatch(context.Background(), cfg, gen)
+	if err == nil || !strings.Contains(err.Error(), "a.md and a.txt would both write to a") {
+		t.Errorf("runBatch() error = %v, want a collision of a.md and a.txt", err)
+	}
+	if api.requests() != 0 {
+		t.Errorf("%d requests sent despite the collision", api.requests())
+	}
+}
@@ -0,0 +1,47 @@
+package agentcoder
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// binaryRatio is the share of non-printable characters above which generated
+// content is considered binary rather than source code.
+const binaryRatio = 0.1
+
+// isBinary reports whether code looks like binary data or garbage: more than
+// binaryRatio of its characters are control characters other than whitespace,
+// or bytes that are not valid UTF-8.
+func isBinary(code string) bool {
+	if code == "" {
+		return false
+	}
+	total, bad := 0, 0
+	for i := 0; i < len(code); {
+		r, size := utf8.DecodeRuneInString(code[i:])
+		i += size
+		total++
+		if r == utf8.RuneError && size == 1 || unicode.IsControl(r) && !unicode.IsSpace(r) {
+			bad++
+		}
+	}
+	return float64(bad) > binaryRatio*float64(total)
+}
+
+// filterBinary drops the generated files whose content looks binary with a
+// warning, or fails the whole write on the first one with abort.
+func filterBinary(files []File, abort bool) ([]File, error) {
+	kept := files[:0:0]
+	for _, file := range files {
+		if !isBinary(file.Code) {
+			kept = append(kept, file)
+			continue
+		}
+		if abort {
+			return nil, fmt.Errorf("aborting write: %s looks like binary data rather than source code", file.Name)
+		}
+		fmt.Printf("Warning: skipping %s, which looks like binary data rather than source code\n", file.Name)
+	}
+	return kept, nil
+}
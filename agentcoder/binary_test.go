@@ -0,0 +1,54 @@
+package agentcoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"empty", "", false},
+		{"source code", "package main\n\nfunc main() {\n\tprintln(\"hi\")\r\n}\n", false},
+		{"unicode", "// Grüße, 世界\nlet s = \"😀\";\n", false},
+		{"many NUL bytes", "PK" + strings.Repeat("\x00", 64) + "data", true},
+		{"invalid UTF-8", strings.Repeat("\xff\xfe", 20), true},
+		{"few control characters", "a\x00" + strings.Repeat("b", 20), false},
+	}
+	for _, tt := range tests {
+		if got := isBinary(tt.code); got != tt.want {
+			t.Errorf("isBinary(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBinaryOutputGuard(t *testing.T) {
+	files := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "logo.png", Code: "\x89PNG" + strings.Repeat("\x00", 100)},
+	}
+
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	var kept []File
+	var err error
+	out := captureStdout(t, func() { kept, err = filterFiles(cfg, files) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fileNames(kept); !reflect.DeepEqual(got, []string{"main.go"}) {
+		t.Errorf("kept %v, want [main.go]", got)
+	}
+	if !strings.Contains(out, "Warning: skipping logo.png") {
+		t.Errorf("output %q does not warn about logo.png", out)
+	}
+
+	cfg.AbortOnBinaryOutput = true
+	if _, err := filterFiles(cfg, files); err == nil || !strings.Contains(err.Error(), "logo.png looks like binary data") {
+		t.Errorf("filterFiles() with --abort-on-binary-output = %v, want an error about logo.png", err)
+	}
+}
@@ -0,0 +1,126 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errDailyBudget is returned for requests refused because of --daily-budget.
+var errDailyBudget = errors.New("daily budget reached")
+
+// dailyBudget caps the cost of all runs on a day. The spend of the current day
+// is kept in a state file shared by the runs, and starts again at midnight in
+// the configured time zone.
+type dailyBudget struct {
+	path     string         // State file
+	limit    float64        // Budget per day in US dollars
+	location *time.Location // Time zone of the days
+	now      func() time.Time
+
+	// mu serializes the updates of the state file, which read the spend
+	// and write it back increased.
+	mu sync.Mutex
+}
+
+// budgetState is the content of the state file of a dailyBudget.
+type budgetState struct {
+	Date  string  `json:"date"`  // Day the spend was recorded on, as YYYY-MM-DD
+	Spent float64 `json:"spent"` // Cost of the requests made that day in US dollars
+}
+
+// newDailyBudget returns the budget configured with --daily-budget, stored in
+// --budget-file or, by default, in the user configuration directory.
+func newDailyBudget(cfg Config) (*dailyBudget, error) {
+	location, err := time.LoadLocation(cfg.BudgetTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid budget time zone: %w", err)
+	}
+	path := cfg.BudgetFile
+	if path == "" {
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return nil, fmt.Errorf("locating the budget file: %w", err)
+		}
+		path = filepath.Join(dir, "agent_coder", "budget.json")
+	}
+	return &dailyBudget{path: path, limit: cfg.DailyBudget, location: location, now: time.Now}, nil
+}
+
+// today returns the current day in the time zone of the budget.
+func (b *dailyBudget) today() string {
+	return b.now().In(b.location).Format(time.DateOnly)
+}
+
+// spent returns the cost recorded for the current day.
+func (b *dailyBudget) spent() (float64, error) {
+	data, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var state budgetState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return 0, fmt.Errorf("parsing budget file %s: %w", b.path, err)
+	}
+	if state.Date != b.today() {
+		return 0, nil
+	}
+	return state.Spent, nil
+}
+
+// record adds cost to the spend of the current day and returns the new total.
+// The state file is replaced atomically through a temporary file of its own,
+// so that concurrent runs never write into the same one.
+func (b *dailyBudget) record(cost float64) (float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	spent, err := b.spent()
+	if err != nil {
+		return 0, err
+	}
+	spent += cost
+	data, err := json.MarshalIndent(budgetState{Date: b.today(), Spent: spent}, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(b.path), 0755); err != nil {
+		return 0, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), filepath.Base(b.path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if err = tmp.Chmod(0644); err == nil {
+		_, err = tmp.Write(data)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return spent, os.Rename(tmp.Name(), b.path)
+}
+
+// start refuses to start a run once the budget of the day is used up and
+// reports what remains of it otherwise.
+func (b *dailyBudget) start() error {
+	spent, err := b.spent()
+	if err != nil {
+		return err
+	}
+	if spent >= b.limit {
+		return fmt.Errorf("%w: $%.4f of the daily budget of $%.2f has been spent on %s; it resets at midnight (%s)", errDailyBudget, spent, b.limit, b.today(), b.location)
+	}
+	fmt.Printf("Daily budget: $%.4f of $%.2f spent today, $%.4f remaining\n", spent, b.limit, b.limit-spent)
+	return nil
+}
@@ -0,0 +1,184 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBudget returns a daily budget of limit dollars kept in a temporary
+// state file, on a day that never ends.
+func testBudget(t *testing.T, limit float64) *dailyBudget {
+	t.Helper()
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	return &dailyBudget{
+		path:     filepath.Join(t.TempDir(), "state", "budget.json"),
+		limit:    limit,
+		location: time.UTC,
+		now:      func() time.Time { return now },
+	}
+}
+
+func TestDailyBudgetRecord(t *testing.T) {
+	b := testBudget(t, 1)
+	for _, cost := range []float64{0.25, 0.5} {
+		if _, err := b.record(cost); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if spent, err := b.spent(); err != nil || spent != 0.75 {
+		t.Errorf("spent() = %v, %v, want 0.75", spent, err)
+	}
+
+	// The spend starts again on the next day
+	tomorrow := b.now().Add(24 * time.Hour)
+	b.now = func() time.Time { return tomorrow }
+	if spent, err := b.spent(); err != nil || spent != 0 {
+		t.Errorf("spent() the next day = %v, %v, want 0", spent, err)
+	}
+}
+
+func TestDailyBudgetConcurrentRecord(t *testing.T) {
+	b := testBudget(t, 100)
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := b.record(1); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if spent, err := b.spent(); err != nil || spent != 50 {
+		t.Errorf("spent() = %v, %v, want every one of the 50 records", spent, err)
+	}
+	entries, err := os.ReadDir(filepath.Dir(b.path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("%d files next to the state file, want no temporary files left", len(entries)-1)
+	}
+}
+
+func TestDailyBudgetStart(t *testing.T) {
+	b := testBudget(t, 1)
+	if _, err := b.record(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.start(); err == nil {
+		t.Error("start() succeeded with the budget used up")
+	}
+}
+
+// budgetConfig returns a configuration with a daily budget of limit dollars
+// of which spent has been spent today, in UTC.
+func budgetConfig(t *testing.T, limit, spent float64) Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Model = "gemini-2.5-pro"
+	cfg.Prompt = "Write a package."
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	cfg.DailyBudget = limit
+	cfg.BudgetTimezone = "UTC"
+	cfg.BudgetFile = filepath.Join(t.TempDir(), "budget.json")
+	data, err := json.Marshal(budgetState{Date: time.Now().UTC().Format(time.DateOnly), Spent: spent})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cfg.BudgetFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestDailyBudgetRefusesRunNearCap(t *testing.T) {
+	// 1000 prompt tokens cost $0.00125, which the $0.0002 left cannot cover
+	cfg := budgetConfig(t, 1, 0.9998)
+	var gen *modelGenerator
+	var api *fakeAPI
+	out := captureStdout(t, func() { gen, api = newTestGenerator(t, cfg, tokenCount(1000)) })
+	if !strings.Contains(out, "Daily budget: $0.9998 of $1.00 spent today, $0.0002 remaining") {
+		t.Errorf("output %q does not report the remaining budget", out)
+	}
+	var err error
+	captureStdout(t, func() { _, err = generateFiles(context.Background(), cfg, gen) })
+	if !errors.Is(err, errDailyBudget) {
+		t.Fatalf("generateFiles() error = %v, want the daily budget", err)
+	}
+	if !strings.Contains(err.Error(), "bringing the day from $0.9998 over the budget of $1.00") {
+		t.Errorf("error %q does not explain why the request was refused", err)
+	}
+	if n := api.requests(); n != 1 {
+		t.Errorf("%d requests, want only the token count", n)
+	}
+}
+
+func TestDailyBudgetRefusesToStart(t *testing.T) {
+	cfg := budgetConfig(t, 1, 1.25)
+	_, client := newFakeAPI(t)
+	_, err := newClientGenerator(client, cfg)
+	if !errors.Is(err, errDailyBudget) || !strings.Contains(err.Error(), "it resets at midnight (UTC)") {
+		t.Errorf("newClientGenerator() error = %v, want the daily budget used up", err)
+	}
+}
+
+func TestDailyBudgetRecordsRun(t *testing.T) {
+	cfg := budgetConfig(t, 1, 0.5)
+	var gen *modelGenerator
+	captureStdout(t, func() {
+		gen, _ = newTestGenerator(t, cfg, tokenCount(100), textResponse(`[{"file_name": "a.go", "source_code": "package a\n"}]`, "STOP"))
+	})
+	var err error
+	out := captureStdout(t, func() { _, err = generateFiles(context.Background(), cfg, gen) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The reply used 10 prompt and 20 output tokens
+	if !strings.Contains(out, "Spent today: $0.5002 of $1.00, $0.4998 remaining") {
+		t.Errorf("output %q does not report the spend of the day", out)
+	}
+	b, err := newDailyBudget(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spent, err := b.spent(); err != nil || math.Abs(spent-0.5002125) > 1e-9 {
+		t.Errorf("spent() = %v, %v, want 0.5002125", spent, err)
+	}
+}
+
+func TestDailyBudgetTimezone(t *testing.T) {
+	b := testBudget(t, 1)
+	b.location = time.FixedZone("UTC+2", 2*60*60)
+	evening := time.Date(2026, 10, 15, 21, 30, 0, 0, time.UTC)
+	b.now = func() time.Time { return evening }
+	if _, err := b.record(0.5); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.today(); got != "2026-10-15" {
+		t.Errorf("today() at %v = %s, want 2026-10-15", evening, got)
+	}
+
+	// Midnight in UTC+2 is 22:00 UTC
+	later := evening.Add(time.Hour)
+	b.now = func() time.Time { return later }
+	if spent, err := b.spent(); err != nil || spent != 0 {
+		t.Errorf("spent() after midnight in %s = %v, %v, want 0", b.location, spent, err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BudgetTimezone = "Mars/Olympus_Mons"
+	if _, err := newDailyBudget(cfg); err == nil || !strings.Contains(err.Error(), "invalid budget time zone") {
+		t.Errorf("newDailyBudget() = %v, want an invalid time zone error", err)
+	}
+}
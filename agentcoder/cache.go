@@ -0,0 +1,140 @@
+package agentcoder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// responseCache stores the text of model replies on disk, keyed by everything
+// that determines the reply, so repeated requests are answered without the API.
+// Replies older than ttl are expired, unless ttl is zero.
+type responseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cachedReply is a reply as stored in the cache.
+type cachedReply struct {
+	Model         string                `json:"model"`                    // Model that served the request
+	CreatedAt     time.Time             `json:"created_at"`               // Time the reply was received
+	Text          string                `json:"text"`                     // Text of the reply
+	FinishReason  genai.FinishReason    `json:"finish_reason,omitempty"`  // Why the model stopped generating
+	SafetyRatings []*genai.SafetyRating `json:"safety_ratings,omitempty"` // Safety ratings of the reply
+}
+
+// cacheKey hashes the model settings, the prompt and the attachments of a
+// request, and the version of the tool, so that replies cached by a build
+// that may have requested or parsed them differently are not reused.
+func cacheKey(name string, config genai.GenerationConfig, parts []genai.Part) string {
+	h := sha256.New()
+	settings, _ := json.Marshal(struct {
+		Version string
+		Model   string
+		Config  genai.GenerationConfig
+	}{toolVersion(), name, config})
+	h.Write(settings)
+	for _, part := range parts {
+		switch p := part.(type) {
+		case genai.Text:
+			h.Write([]byte("text\x00"))
+			h.Write([]byte(p))
+		case genai.Blob:
+			h.Write([]byte(p.MIMEType + "\x00"))
+			h.Write(p.Data)
+		}
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the file the reply with the given key is stored in.
+func (c *responseCache) path(key string) string {
+	return filepath.Join(c.dir, key+".json")
+}
+
+// get returns the cached reply with the given key, if any. An expired reply
+// is removed.
+func (c *responseCache) get(key string) (*reply, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var cached cachedReply
+	if err := json.Unmarshal(data, &cached); err != nil {
+		return nil, false
+	}
+	if c.ttl > 0 && time.Since(cached.CreatedAt) > c.ttl {
+		os.Remove(c.path(key))
+		return nil, false
+	}
+	r := &reply{Model: cached.Model, Text: cached.Text, FinishReason: cached.FinishReason, SafetyRatings: cached.SafetyRatings}
+	if r.FinishReason == genai.FinishReasonUnspecified {
+		r.FinishReason = genai.FinishReasonStop
+	}
+	return r, true
+}
+
+// put stores the reply under the given key, with its safety ratings so they
+// are checked again when it is reused.
+func (c *responseCache) put(key string, r *reply) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cachedReply{Model: r.Model, CreatedAt: time.Now().UTC(), Text: r.Text, FinishReason: r.FinishReason, SafetyRatings: r.SafetyRatings}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.path(key), data, 0644)
+}
+
+// clear removes every cached reply and returns how many there were.
+func (c *responseCache) clear() (int, error) {
+	entries, err := os.ReadDir(c.dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil {
+			return removed, err
+		}
+		removed++
+	}
+	return removed, nil
+}
+
+// runCache implements the cache subcommand, whose clear action removes every
+// response cached in the cache directory.
+func runCache(args []string) {
+	cfg, rest, err := parseConfig(args, nil)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(rest) != 1 || rest[0] != "clear" {
+		fmt.Println("Usage: cache [flags] clear")
+		os.Exit(1)
+	}
+	removed, err := (&responseCache{dir: cfg.CacheDir}).clear()
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Removed %d cached response(s) from %s\n", removed, cfg.CacheDir)
+}
@@ -0,0 +1,171 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// cacheConfig returns a config caching responses in a temporary directory.
+func cacheConfig(t *testing.T) Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a package."
+	cfg.OutputDir = t.TempDir()
+	cfg.Cache = true
+	cfg.SkipIdentical = true
+	cfg.CacheDir = filepath.Join(t.TempDir(), "cache")
+	cfg.NoRaw = true
+	return cfg
+}
+
+func TestCachedRerunChangesNothing(t *testing.T) {
+	cfg := cacheConfig(t)
+	response := textResponse(`[{"file_name": "a.go", "source_code": "package a\n"}, {"file_name": "b.txt", "source_code": "b"}]`, "STOP")
+
+	run := func(gen *modelGenerator) string {
+		return captureStdout(t, func() {
+			files, err := generate(context.Background(), cfg, gen)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFiles(osFS{}, cfg, files); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+	gen, _ := newTestGenerator(t, cfg, response)
+	if out := run(gen); !strings.Contains(out, "2 file(s) changed") {
+		t.Errorf("first run output %q, want 2 files changed", out)
+	}
+
+	// The second run is answered from the cache without any request
+	gen, api := newTestGenerator(t, cfg)
+	out := run(gen)
+	if !strings.Contains(out, "Using cached response") || !strings.Contains(out, "0 file(s) changed") {
+		t.Errorf("second run output %q, want a cached response and 0 files changed", out)
+	}
+	if api.requests() != 0 {
+		t.Errorf("%d requests on the cached run", api.requests())
+	}
+}
+
+func TestCachedReplySafetyCheck(t *testing.T) {
+	cfg := cacheConfig(t)
+	response := apiResponse{body: `{"candidates": [{"content": {"role": "model", "parts": [{"text": "[]"}]}, "finishReason": "STOP",
+		"safetyRatings": [{"category": "HARM_CATEGORY_DANGEROUS_CONTENT", "probability": "HIGH"}]}]}`}
+	gen, _ := newTestGenerator(t, cfg, response)
+	if _, err := gen.Generate(context.Background(), cfg.Prompt); err != nil {
+		t.Fatal(err)
+	}
+
+	// A stricter run does not accept the cached reply
+	cfg.FailOnSafety = "medium"
+	gen, api := newTestGenerator(t, cfg)
+	_, err := gen.Generate(context.Background(), cfg.Prompt)
+	if err == nil || !strings.Contains(err.Error(), "safety check failed") {
+		t.Errorf("Generate() error = %v, want a failed safety check", err)
+	}
+	if api.requests() != 0 {
+		t.Errorf("%d requests, want the reply taken from the cache", api.requests())
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c := &responseCache{dir: t.TempDir()}
+	if err := c.put("key", &reply{Model: "m", Text: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	r, ok := c.get("key")
+	if !ok || r.Text != "text" || r.Model != "m" {
+		t.Fatalf("get() = %+v, %v, want the stored reply", r, ok)
+	}
+	c.ttl = 1
+	if _, ok := c.get("key"); ok {
+		t.Error("expired reply returned")
+	}
+	if n, err := c.clear(); err != nil || n != 0 {
+		t.Errorf("clear() = %d, %v, want the expired reply already removed", n, err)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	tests := []struct {
+		name string
+		age  time.Duration
+		ttl  time.Duration
+		want bool
+	}{
+		{"no ttl", 48 * time.Hour, 0, true},
+		{"fresh", time.Minute, time.Hour, true},
+		{"expired", 2 * time.Hour, time.Hour, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &responseCache{dir: t.TempDir(), ttl: tt.ttl}
+			data, err := json.Marshal(cachedReply{Model: "m", CreatedAt: time.Now().Add(-tt.age), Text: "text"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(c.path("key"), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := c.get("key"); ok != tt.want {
+				t.Errorf("get() of a reply %v old with ttl %v = %v, want %v", tt.age, tt.ttl, ok, tt.want)
+			}
+			if _, err := os.Stat(c.path("key")); os.IsNotExist(err) == tt.want {
+				t.Errorf("reply file kept = %v, want %v", err == nil, tt.want)
+			}
+		})
+	}
+}
+
+func TestCacheKeyVersion(t *testing.T) {
+	defer func(v string) { version = v }(version)
+	parts := []genai.Part{genai.Text("Write a package.")}
+	version = "v1.0.0"
+	old := cacheKey("gemini-2.5-pro", genai.GenerationConfig{}, parts)
+	if again := cacheKey("gemini-2.5-pro", genai.GenerationConfig{}, parts); again != old {
+		t.Errorf("cacheKey() = %s, then %s for the same request", old, again)
+	}
+	version = "v1.1.0"
+	if got := cacheKey("gemini-2.5-pro", genai.GenerationConfig{}, parts); got == old {
+		t.Error("cacheKey() did not change with the version")
+	}
+}
+
+func TestCacheClear(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	c := &responseCache{dir: dir}
+	for _, key := range []string{"a", "b", "c"} {
+		if err := c.put(key, &reply{Model: "m", Text: key}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := captureStdout(t, func() { runCache([]string{"-cache-dir", dir, "clear"}) })
+	if want := "Removed 3 cached response(s) from " + dir; !strings.Contains(out, want) {
+		t.Errorf("output %q does not contain %q", out, want)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("cache directory still holds %d entries after clear", len(entries))
+	}
+	if _, ok := c.get("a"); ok {
+		t.Error("cleared reply returned")
+	}
+
+	// Clearing a cache that was never written is not an error
+	if n, err := (&responseCache{dir: filepath.Join(dir, "missing")}).clear(); err != nil || n != 0 {
+		t.Errorf("clear() of a missing directory = %d, %v, want 0, nil", n, err)
+	}
+}
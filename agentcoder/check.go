@@ -0,0 +1,87 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+)
+
+// checkReport is the pass/fail report of the check subcommand.
+type checkReport struct {
+	Pass  bool          `json:"pass"`            // Whether every file was generated and validated without problems
+	Error string        `json:"error,omitempty"` // Why generation or validation failed as a whole
+	Files []writeResult `json:"files"`           // Outcome of writing and validating each file in memory
+}
+
+// checkFiles writes the files to an in-memory filesystem, validating and
+// formatting them as a real run would, and reports whether all of them could
+// be written without a parse problem.
+func checkFiles(cfg Config, files []File) checkReport {
+	report := checkReport{Pass: true, Files: []writeResult{}}
+	cfg.Validate = true
+	err := writeFilesFunc(newMemFS(), cfg, files, func(_ File, result writeResult) {
+		report.Files = append(report.Files, result)
+		if result.Status != writeWritten && result.Status != writeUnchanged && result.Status != writeLinked {
+			report.Pass = false
+		}
+		for _, issue := range result.Issues {
+			if issue.Category == "parse" {
+				report.Pass = false
+			}
+		}
+	})
+	if err != nil {
+		report.Pass = false
+		report.Error = err.Error()
+	}
+	return report
+}
+
+// writeCheckReport writes the report as indented JSON to w.
+func writeCheckReport(w io.Writer, report checkReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// runCheck implements the check subcommand, which generates the files for the
+// prompt read from stdin and validates them in memory without writing
+// anything, for CI gating. The JSON report goes to stdout and the log to
+// stderr, and it exits with status 1 if the check fails.
+func runCheck(args []string) {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	cfg, _, err := parseConfig(args, nil)
+	report := checkReport{Files: []writeResult{}}
+	if err == nil {
+		// The prompt would be saved to the output directory
+		cfg.SavePrompt = false
+		report = generateCheck(context.Background(), cfg)
+	} else {
+		report.Error = "loading config: " + err.Error()
+	}
+	if err := writeCheckReport(stdout, report); err != nil || !report.Pass {
+		os.Exit(1)
+	}
+}
+
+// generateCheck generates the files and checks them, reporting a failure to
+// generate or filter them as a failed check.
+func generateCheck(ctx context.Context, cfg Config) checkReport {
+	gen, err := newModelGenerator(ctx, cfg)
+	if err != nil {
+		return checkReport{Error: err.Error(), Files: []writeResult{}}
+	}
+	defer gen.Close()
+	return checkGenerated(ctx, cfg, gen)
+}
+
+// checkGenerated is generateCheck with the generator given.
+func checkGenerated(ctx context.Context, cfg Config, gen *modelGenerator) checkReport {
+	files, err := generateFiles(ctx, cfg, gen)
+	if err != nil {
+		return checkReport{Error: err.Error(), Files: []writeResult{}}
+	}
+	return checkFiles(cfg, files)
+}
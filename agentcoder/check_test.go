@@ -0,0 +1,99 @@
+package agentcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// runCheckWith checks the files generated by the fake API answering with
+// the responses, and returns the report decoded from its JSON.
+func runCheckWith(t *testing.T, cfg Config, responses ...apiResponse) checkReport {
+	t.Helper()
+	gen, _ := newTestGenerator(t, cfg, responses...)
+	var report checkReport
+	captureStdout(t, func() { report = checkGenerated(context.Background(), cfg, gen) })
+
+	var buf bytes.Buffer
+	if err := writeCheckReport(&buf, report); err != nil {
+		t.Fatal(err)
+	}
+	var decoded checkReport
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("report is not JSON: %v\n%s", err, buf.String())
+	}
+	return decoded
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		response apiResponse
+		pass     bool
+		issue    string
+		err      string
+	}{
+		{
+			name:     "valid",
+			response: textResponse(`[{"file_name": "main.go", "source_code": "package main\n\nfunc main() {}\n"}, {"file_name": "config.json", "source_code": "{\"a\": 1}"}]`, "STOP"),
+			pass:     true,
+		},
+		{
+			name:     "Go that does not parse",
+			response: textResponse(`[{"file_name": "main.go", "source_code": "package main\n\nfunc main() {\n"}]`, "STOP"),
+			issue:    "main.go",
+		},
+		{
+			name:     "malformed JSON",
+			response: textResponse(`[{"file_name": "main.go", "source_code": "package main\n"}, {"file_name": "config.json", "source_code": "{\"a\": "}]`, "STOP"),
+			issue:    "config.json",
+		},
+		{
+			name:     "rejected path",
+			response: textResponse(`[{"file_name": "../main.go", "source_code": "package main\n"}]`, "STOP"),
+		},
+		{
+			name:     "generation failure",
+			response: errorResponse(400, "bad request"),
+			err:      "bad request",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Prompt = "Write a program."
+			cfg.OutputDir = t.TempDir()
+			cfg.NoRaw = true
+			report := runCheckWith(t, cfg, tt.response)
+			if report.Pass != tt.pass {
+				t.Errorf("Pass = %v, want %v in %+v", report.Pass, tt.pass, report)
+			}
+			if tt.err != "" && !strings.Contains(report.Error, tt.err) {
+				t.Errorf("Error = %q, want it to contain %q", report.Error, tt.err)
+			}
+			if tt.issue != "" {
+				found := false
+				for _, file := range report.Files {
+					for _, issue := range file.Issues {
+						found = found || file.Path == tt.issue && issue.Category == "parse"
+					}
+				}
+				if !found {
+					t.Errorf("no parse issue reported for %s in %+v", tt.issue, report.Files)
+				}
+			}
+
+			// Nothing is written, whatever the outcome
+			entries, err := os.ReadDir(cfg.OutputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 0 {
+				t.Errorf("check wrote %d entries to the output directory", len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// outlineRequest asks the model for the list of files before any code is
+// generated in chunked mode.
+func outlineRequest(prompt string) string {
+	return prompt + "\n\nDo not generate any code yet. Only list the files that are needed, with a one-line description of the purpose of each."
+}
+
+// batchRequest asks the model for the source code of one batch of files from
+// the outline.
+func batchRequest(prompt string, outline, batch []OutlineEntry) string {
+	var b strings.Builder
+	b.WriteString(prompt)
+	b.WriteString("\n\nThe project consists of the following files:\n")
+	for _, entry := range outline {
+		fmt.Fprintf(&b, "- %s: %s\n", entry.Name, entry.Description)
+	}
+	b.WriteString("\nGenerate only these files in this response:\n")
+	for _, entry := range batch {
+		fmt.Fprintf(&b, "- %s\n", entry.Name)
+	}
+	return b.String()
+}
+
+// generateChunked generates the files for prompt across several requests. It
+// first asks outlineGen for the list of files and then asks gen for their
+// source code in batches of cfg.ChunkSize, merging the results.
+func generateChunked(ctx context.Context, cfg Config, outlineGen, gen generator, prompt string) ([]File, error) {
+	r, err := outlineGen.Generate(ctx, outlineRequest(prompt))
+	if err != nil {
+		return nil, fmt.Errorf("generating outline: %w", err)
+	}
+	outline, err := parseOutline(r.Text)
+	if err != nil {
+		return nil, err
+	}
+	size := cfg.ChunkSize
+	if size <= 0 {
+		size = len(outline)
+	}
+	batches := (len(outline) + size - 1) / size
+	fmt.Printf("\nOutline has %d file(s), generating in %d batch(es)\n", len(outline), batches)
+
+	var files []File
+	for i := 0; i < len(outline); i += size {
+		batch := outline[i:min(i+size, len(outline))]
+		r, err := gen.Generate(ctx, batchRequest(prompt, outline, batch))
+		if err != nil {
+			return files, fmt.Errorf("generating batch %d: %w", i/size+1, err)
+		}
+		received, err := parseResponse(cfg, r.Text)
+		if err != nil {
+			return files, fmt.Errorf("batch %d: %w", i/size+1, err)
+		}
+		files = mergeFiles(files, received)
+		fmt.Printf("Batch %d/%d: received %d file(s), %d in total\n", i/size+1, batches, len(received), len(files))
+	}
+
+	// Report files from the outline that never arrived
+	generated := make(map[string]bool, len(files))
+	for _, file := range files {
+		generated[file.Name] = true
+	}
+	for _, entry := range outline {
+		if !generated[entry.Name] {
+			fmt.Printf("Warning: %s from the outline was not generated\n", entry.Name)
+		}
+	}
+	return files, nil
+}
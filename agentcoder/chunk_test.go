@@ -0,0 +1,54 @@
+package agentcoder
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateChunked(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ChunkSize = 2
+	outline := &fakeGenerator{replies: []*reply{{Text: `[
+		{"file_name": "a.go", "description": "A"},
+		{"file_name": "b.go", "description": "B"},
+		{"file_name": "c.go", "description": "C"}
+	]`}}}
+	gen := &fakeGenerator{replies: []*reply{
+		filesReply(t, File{Name: "a.go", Code: "package a"}, File{Name: "b.go", Code: "package b"}),
+		// A file repeated in a later batch replaces the earlier version
+		filesReply(t, File{Name: "c.go", Code: "package c"}, File{Name: "a.go", Code: "package a // v2"}),
+	}}
+
+	files, err := generateChunked(context.Background(), cfg, outline, gen, "Build it.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{Name: "a.go", Code: "package a // v2"}, {Name: "b.go", Code: "package b"}, {Name: "c.go", Code: "package c"}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	if len(gen.prompts) != 2 {
+		t.Fatalf("%d batch requests, want 2", len(gen.prompts))
+	}
+	if !strings.HasSuffix(gen.prompts[0], "in this response:\n- a.go\n- b.go\n") || !strings.HasSuffix(gen.prompts[1], "in this response:\n- c.go\n") {
+		t.Errorf("batch prompts = %q", gen.prompts)
+	}
+}
+
+func TestGenerateChunkedMissingFile(t *testing.T) {
+	cfg := DefaultConfig()
+	outline := &fakeGenerator{replies: []*reply{{Text: `[{"file_name": "a.go", "description": "A"}, {"file_name": "b.go", "description": "B"}]`}}}
+	gen := &fakeGenerator{replies: []*reply{filesReply(t, File{Name: "a.go", Code: "package a"})}}
+	var files []File
+	out := captureStdout(t, func() {
+		var err error
+		if files, err = generateChunked(context.Background(), cfg, outline, gen, "Build it."); err != nil {
+			t.Error(err)
+		}
+	})
+	if len(files) != 1 || !strings.Contains(out, "Warning: b.go from the outline was not generated") {
+		t.Errorf("files = %v, output %q; want a.go and a warning for b.go", files, out)
+	}
+}
@@ -0,0 +1,662 @@
+package agentcoder
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// File is a generated file.
+type File struct {
+	Name string `json:"file_name"`   // Name of the file
+	Code string `json:"source_code"` // Source code located in the file
+}
+
+// Main runs the command line tool with the arguments of the process and exits
+// with a non-zero status on failure.
+func Main() {
+	args := os.Args[1:]
+	if len(args) > 0 {
+		switch args[0] {
+		case "plan":
+			if err := runPlan(args[1:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "apply":
+			if err := runApply(args[1:]); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		case "verify":
+			runVerify(args[1:])
+			return
+		case "replay":
+			runReplay(args[1:])
+			return
+		case "validate-response":
+			runValidateResponse(args[1:])
+			return
+		case "compare":
+			runCompare(args[1:])
+			return
+		case "cache":
+			runCache(args[1:])
+			return
+		case "check":
+			runCheck(args[1:])
+			return
+		}
+	}
+
+	cfg, _, err := parseConfig(args, nil)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if err := checkOutputDir(osFS{}, cfg.OutputDir); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	ctx := context.Background()
+	shutdown, err := setupTracing(ctx, cfg)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, span := tracer.Start(ctx, "run")
+	var branch *gitBranch
+	// fail reports the error and exits once the spans are flushed
+	fail := func(err error) {
+		fmt.Printf("Error: %v\n", err)
+		if branch != nil && branch.active {
+			fmt.Printf("Branch %s is left checked out with the files written so far\n", branch.name)
+		}
+		endSpan(span, err)
+		shutdown(context.Background())
+		os.Exit(1)
+	}
+	defer func() {
+		span.End()
+		shutdown(context.Background())
+	}()
+
+	gen, err := newModelGenerator(ctx, cfg)
+	if err != nil {
+		fail(err)
+	}
+	defer gen.Close()
+
+	// Report the expected cost for CI gating without generating anything
+	if cfg.PlanJSON {
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		if err := writePlanJSON(ctx, stdout, cfg, gen); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// Process every prompt file of the batch directory
+	if cfg.BatchDir != "" {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err := runBatch(ctx, cfg, gen)
+		stop()
+		if err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// Serve prompts from a named pipe until interrupted
+	if cfg.FIFO != "" {
+		ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+		err := runFIFO(ctx, cfg, gen, cfg.FIFO)
+		stop()
+		if err != nil && !errors.Is(err, context.Canceled) {
+			fail(err)
+		}
+		return
+	}
+
+	// Only propose the file structure in outline mode
+	if cfg.Outline {
+		text, err := requestText(ctx, cfg, gen)
+		if err != nil {
+			fail(err)
+		}
+		outline, err := parseOutline(text)
+		if err != nil {
+			fail(err)
+		}
+		printOutline(outline)
+		return
+	}
+
+	// Let the model create, delete and rename files through function calls
+	if cfg.FunctionCalling {
+		if err := runFunctionCalls(ctx, cfg, gen); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	// Keep stdout for machine-readable status or files and log everything
+	// else to stderr
+	var reporter *jsonlReporter
+	stdout := os.Stdout
+	switch cfg.Format {
+	case "text":
+	case "jsonl":
+		reporter = newJSONLReporter(stdout)
+	default:
+		fail(fmt.Errorf("unknown format %q", cfg.Format))
+	}
+	if reporter != nil || cfg.Stdout {
+		os.Stdout = os.Stderr
+	}
+
+	// The report shows the prompt and the branch is named after it, so read
+	// it before generating, which is also when the timeout starts
+	if (cfg.Report != "" || cfg.OutputGitBranch || cfg.Timeout > 0) && cfg.Prompt == "" {
+		if cfg.Prompt, err = userPrompt(cfg, bufio.NewScanner(os.Stdin)); err != nil {
+			fail(err)
+		}
+	}
+
+	// Write files as soon as they arrive in the stream, unless they must all
+	// be written together or go elsewhere
+	var streamed *streamWriter
+	if cfg.StreamWrite {
+		if !cfg.Stream || cfg.AllOrNothing || cfg.Inject || cfg.Concat != "" {
+			fmt.Println("Warning: --stream-write requires --stream and cannot be combined with --all-or-nothing, --inject or --concat")
+		} else {
+			if streamed, err = newStreamWriter(cfg); err != nil {
+				fail(err)
+			}
+			gen.onFile = streamed.Write
+		}
+	}
+
+	// Commit the files on a branch of their own, checking the repository
+	// before paying for the generation
+	if cfg.OutputGitBranch {
+		if streamed != nil || cfg.Inject || cfg.Stdout || cfg.OutputZip != "" || cfg.Concat != "" {
+			fmt.Println("Warning: --output-git-branch writes to the output directory and cannot be combined with --stream-write, --inject, --stdout, --output-zip or --concat")
+		} else if branch, err = newGitBranch(cfg, cfg.Prompt); err != nil {
+			fail(err)
+		}
+	}
+
+	// Give the generation a deadline, keeping the files completed in the
+	// stream so that they are written if it expires
+	files, timedOut, err := generateWithTimeout(ctx, cfg, gen)
+	if err != nil {
+		fail(err)
+	}
+
+	// Fill in the markers of an existing skeleton instead of writing files
+	if cfg.Inject {
+		if err := injectFiles(osFS{}, cfg, files); err != nil {
+			fail(err)
+		}
+		return
+	}
+
+	var results []writeResult
+	report := func(file File, result writeResult) {
+		results = append(results, result)
+		if reporter != nil {
+			reporter.Report(file, result)
+		}
+	}
+	_, writeSpan := tracer.Start(ctx, "write", trace.WithAttributes(attribute.Int("files", len(files))))
+	var written map[string]writeResult
+	if streamed != nil {
+		written = streamed.written
+	}
+	if cfg.Concat == "" {
+		if err := checkDestructive(osFS{}, cfg, files, terminalConfirm()); err != nil {
+			fail(err)
+		}
+	}
+	if branch != nil {
+		if err := branch.checkout(); err != nil {
+			fail(err)
+		}
+	}
+	err = writeSinks(newSinks(cfg, stdout, report, written), files)
+	endSpan(writeSpan, err)
+	if err != nil {
+		fail(err)
+	}
+	if reporter != nil {
+		reporter.Summary(timedOut)
+	}
+
+	// The following steps work on the output directory, which --concat
+	// replaces with a single file
+	inDir := cfg.Concat == ""
+
+	// Let the model fix Go code that does not build
+	if inDir && cfg.AutoFix && hasGoFiles(files) {
+		if files, err = autoFix(ctx, cfg, gen, files); err != nil {
+			fmt.Printf("Error: %v\n", err)
+		}
+	}
+
+	// Catch suspicious constructs that still compile
+	if inDir && (cfg.GoVet || cfg.FailOnVet) && hasGoFiles(files) {
+		if err := goVet(cfg); err != nil {
+			fail(err)
+		}
+	}
+
+	// Record the hashes of the written files
+	if inDir && cfg.Manifest {
+		saveManifest(osFS{}, cfg, files, results)
+	}
+
+	if branch != nil {
+		if err := branch.commit(commitMessage(cfg.Prompt)); err != nil {
+			fail(err)
+		}
+	}
+
+	// Summarize the run for sharing
+	if cfg.Report != "" {
+		if err := writeReport(cfg.Report, cfg, files); err != nil {
+			fmt.Printf("Error writing report: %v\n", err)
+		} else {
+			fmt.Printf("Report written to %s\n", cfg.Report)
+		}
+	}
+
+	// Tell callers that the files are incomplete
+	if timedOut {
+		fmt.Printf("\nPartial run: only %d file(s) were completed before the timeout\n", len(files))
+		span.End()
+		shutdown(context.Background())
+		os.Exit(exitPartial)
+	}
+}
+
+// generate reads the prompt from stdin, sends it to the model and returns the
+// files parsed from the response, or the single output file in text mode.
+func generate(ctx context.Context, cfg Config, gen generator) ([]File, error) {
+	text, err := requestText(ctx, cfg, gen)
+	if err != nil {
+		return nil, err
+	}
+	_, span := tracer.Start(ctx, "parse")
+	files, err := filesFromText(cfg, text)
+	span.SetAttributes(attribute.Int("files", len(files)))
+	endSpan(span, err)
+	return files, err
+}
+
+// filesFromText parses the files out of the response text, which in text mode
+// is the content of a single file.
+func filesFromText(cfg Config, text string) ([]File, error) {
+	if cfg.ResponseFormat == "text" {
+		return []File{{Name: cfg.OutputFile, Code: text}}, nil
+	}
+	return parseResponse(cfg, text)
+}
+
+// generateFiles generates the files for the prompt read from stdin, in batches
+// when chunked generation is enabled, and applies the configured rewrites.
+func generateFiles(ctx context.Context, cfg Config, gen *modelGenerator) ([]File, error) {
+	var files []File
+	var err error
+	if cfg.Chunked {
+		var req *promptRequest
+		if req, err = readPrompt(ctx, cfg, gen); err != nil {
+			return nil, err
+		}
+		files, err = generateChunked(ctx, cfg, gen.withSchema(outlineSchema()), gen, req.Text)
+	} else {
+		files, err = generate(ctx, cfg, gen)
+	}
+	if err != nil {
+		return nil, err
+	}
+	files = transformFiles(cfg, files)
+	if files, err = filterFiles(cfg, files); err != nil {
+		return nil, err
+	}
+	return files, checkFileCount(cfg, files)
+}
+
+// requestText reads the prompt from stdin, sends it to the model and returns
+// the raw text of the response.
+func requestText(ctx context.Context, cfg Config, gen generator) (string, error) {
+	req, err := readPrompt(ctx, cfg, gen)
+	if err != nil {
+		return "", err
+	}
+
+	// Send the request to the API
+	r, err := gen.Generate(ctx, req.Text)
+	if isTokenLimitError(err) {
+		r, err = retryWithLessContext(ctx, cfg, gen, req, err)
+	}
+	if err != nil {
+		return "", fmt.Errorf("generating content: %w", err)
+	}
+
+	// Keep a log of every response for auditing
+	if cfg.ResponseLog != "" {
+		if err := appendResponseLog(cfg.ResponseLog, cfg.ResponseLogMaxSize, cfg.apiKeys(), req.Text, r); err != nil {
+			fmt.Printf("Error writing response log: %v\n", err)
+		}
+	}
+
+	// Keep the raw response so the files can be replayed without the API
+	if cfg.DebugDump != "" {
+		if err := saveDump(cfg, r.Text); err != nil {
+			fmt.Printf("Error writing debug dump: %v\n", err)
+		}
+	}
+
+	if err := printRawResponse(cfg, r.Text); err != nil {
+		return "", err
+	}
+	return r.Text, nil
+}
+
+// readPrompt reads the prompt from stdin and assembles the instruction sent to
+// the model. With --summarize-context, large context files are summarized by
+// gen if it is a summarizer.
+func readPrompt(ctx context.Context, cfg Config, gen generator) (*promptRequest, error) {
+	// Create a scanner to read user input
+	scanner := bufio.NewScanner(os.Stdin)
+	prompt, err := userPrompt(cfg, scanner)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rewrite the prompt with the configured transforms
+	chain := newPromptChain(cfg)
+	request, err := chain.Apply(prompt)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.PromptCompress {
+		uncompressed, err := chain.without(transformCompress).Apply(prompt)
+		if err != nil {
+			return nil, err
+		}
+		reportCompression(ctx, gen, uncompressed, request)
+	}
+	prompt = request
+
+	// Point out prompts that are too vague to produce good code
+	if cfg.LintPrompt {
+		if suggestions := lintPrompt(prompt); len(suggestions) > 0 {
+			fmt.Println("The prompt could be more specific:")
+			for _, s := range suggestions {
+				fmt.Printf("  - %s\n", s)
+			}
+			if cfg.Strict {
+				return nil, errors.New("prompt failed the lint check")
+			}
+		}
+	}
+
+	// Read the existing files to include as context
+	contextFiles, err := readContext(cfg)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.Mode {
+	case "generate":
+	case "modify":
+		if len(contextFiles) == 0 {
+			fmt.Println("Warning: modify mode without context files, set --context-dir to the code to modify")
+		}
+	default:
+		return nil, fmt.Errorf("unknown mode %q, use generate or modify", cfg.Mode)
+	}
+
+	// Condense large context files to stay within the prompt budget
+	var summarized map[string]bool
+	if s, ok := gen.(summarizer); ok && cfg.SummarizeContext {
+		if contextFiles, summarized, err = summarizeContext(ctx, s, contextFiles, cfg.SummaryMaxChars); err != nil {
+			return nil, err
+		}
+	}
+
+	// Let the model know the import path of the Go module it generates into
+	module, err := findGoModule(cfg.OutputDir)
+	if err != nil {
+		return nil, err
+	}
+	if module == "" && mentionsGo(prompt) {
+		fmt.Printf("Warning: no go.mod found for %s, generated imports may not match your module\n", cfg.OutputDir)
+	}
+
+	// Create the instruction prompt
+	req := &promptRequest{Prompt: prompt, Context: contextFiles, Summarized: summarized, Module: module}
+	instructionPrompt := buildPrompt(cfg, req)
+
+	// Guard against unexpectedly large prompts
+	instructionPrompt, ok := limitPrompt(instructionPrompt, cfg.MaxPromptChars, isInteractive(os.Stdin), func(question string) bool {
+		return confirm(scanner, question)
+	})
+	if !ok {
+		return nil, errors.New("aborted")
+	}
+
+	// Keep a copy of the exact prompt for reproducibility
+	if cfg.SavePrompt {
+		if err := savePrompt(osFS{}, cfg, instructionPrompt); err != nil {
+			return nil, fmt.Errorf("saving prompt: %w", err)
+		}
+	}
+
+	req.Text = instructionPrompt
+	return req, nil
+}
+
+// userPrompt returns the prompt given in the config, read from the clipboard
+// with --clipboard, composed in the editor with --edit, or entered on stdin.
+func userPrompt(cfg Config, scanner *bufio.Scanner) (string, error) {
+	if cfg.Prompt != "" {
+		return cfg.Prompt, nil
+	}
+	if cfg.Clipboard {
+		prompt, err := clipboardReader()
+		if err != nil {
+			return "", fmt.Errorf("reading clipboard: %w", err)
+		}
+		if strings.TrimSpace(prompt) == "" {
+			return "", errors.New("the clipboard is empty")
+		}
+		return prompt, nil
+	}
+	if cfg.Edit {
+		if editor := promptEditor(); editor != "" {
+			return editPrompt(editor)
+		}
+		fmt.Println("Neither $VISUAL nor $EDITOR is set, reading the prompt from stdin")
+	}
+	return readInteractivePrompt(scanner, cfg.HistoryFile)
+}
+
+// printRawResponse prints the raw response, pretty-printed by default or as
+// compact JSON with --compact. Nothing is printed with --no-raw.
+func printRawResponse(cfg Config, text string) error {
+	if cfg.NoRaw {
+		return nil
+	}
+	fmt.Println("\nAPI Response:")
+	if cfg.Compact {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(text)); err != nil {
+			// Not valid JSON, print it as received
+			fmt.Println(text)
+			return nil
+		}
+		fmt.Println(buf.String())
+		return nil
+	}
+
+	// Marshal the response to JSON for pretty printing
+	prettyJSON, err := json.MarshalIndent(genai.Text(text), "", "  ")
+	if err != nil {
+		return fmt.Errorf("serializing response: %w", err)
+	}
+
+	// Print the serialized response
+	fmt.Println(string(prettyJSON))
+	return nil
+}
+
+// maxStopSequences is the number of stop sequences accepted by the API.
+const maxStopSequences = 5
+
+// newModel creates the model configured to answer with a JSON array of files,
+// or of outline entries in outline mode.
+func newModel(client *genai.Client, cfg Config) (*genai.GenerativeModel, error) {
+	schema := filesSchema()
+	if cfg.Outline {
+		schema = outlineSchema()
+	}
+
+	model := client.GenerativeModel(cfg.Model)
+
+	// Set the generation config with the schema for structured output
+	model.GenerationConfig = genai.GenerationConfig{
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   schema,
+	}
+
+	// Individual flags take precedence over the model config file
+	if cfg.ModelConfig != "" {
+		mc, err := loadModelConfig(cfg.ModelConfig)
+		if err != nil {
+			return nil, fmt.Errorf("reading model config %s: %w", cfg.ModelConfig, err)
+		}
+		mc.apply(&model.GenerationConfig)
+	}
+	if cfg.Temperature != nil {
+		model.Temperature = cfg.Temperature
+	}
+	if len(cfg.StopSequences) > 0 {
+		model.StopSequences = cfg.StopSequences
+	}
+	if len(model.StopSequences) > maxStopSequences {
+		return nil, fmt.Errorf("%d stop sequences given, the API accepts at most %d", len(model.StopSequences), maxStopSequences)
+	}
+
+	// Plain text responses are not constrained by a schema
+	parser, err := lookupParser(cfg.Parser)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.ResponseFormat {
+	case "json":
+		if !parser.Structured && !cfg.Outline {
+			model.ResponseMIMEType = "text/plain"
+			model.ResponseSchema = nil
+		}
+		if len(model.StopSequences) > 0 && model.ResponseMIMEType == "application/json" {
+			fmt.Println("Warning: a stop sequence that occurs in the generated code cuts the JSON response short, so it cannot be parsed")
+		}
+	case "text":
+		model.ResponseMIMEType = "text/plain"
+		model.ResponseSchema = nil
+	default:
+		return nil, fmt.Errorf("unknown response format %q", cfg.ResponseFormat)
+	}
+
+	// Let the model answer only by calling the file operation functions
+	if cfg.FunctionCalling {
+		tool, err := fileTool(cfg.FileFunctions)
+		if err != nil {
+			return nil, err
+		}
+		model.ResponseMIMEType = ""
+		model.ResponseSchema = nil
+		model.Tools = []*genai.Tool{tool}
+		model.ToolConfig = &genai.ToolConfig{FunctionCallingConfig: &genai.FunctionCallingConfig{Mode: genai.FunctionCallingAny}}
+	}
+
+	// Apply the same safety threshold to every harm category
+	if cfg.Safety != "" {
+		threshold, ok := safetyThresholds[cfg.Safety]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety threshold %q", cfg.Safety)
+		}
+		for _, category := range []genai.HarmCategory{
+			genai.HarmCategoryHarassment,
+			genai.HarmCategoryHateSpeech,
+			genai.HarmCategorySexuallyExplicit,
+			genai.HarmCategoryDangerousContent,
+		} {
+			model.SafetySettings = append(model.SafetySettings, &genai.SafetySetting{Category: category, Threshold: threshold})
+		}
+	}
+	return model, nil
+}
+
+// filesSchema describes the JSON array of files the model answers with.
+func filesSchema() *genai.Schema {
+	return &genai.Schema{
+		Type:        genai.TypeArray, // The top-level structure is an ARRAY (using string type)
+		Description: "List of all of the filenames and source code in the files.",
+		Items: &genai.Schema{ // Define the schema for EACH item WITHIN the array
+			Type:        genai.TypeObject, // Each item is an OBJECT
+			Description: "Object representing file.",
+			Properties: map[string]*genai.Schema{
+				"file_name": { // Define the 'name' property
+					Type:        genai.TypeString,
+					Description: "Name of the file: relative_path/file_name.file_extension",
+				},
+				"source_code": { // Define the 'description' property
+					Type:        genai.TypeString,
+					Description: "Source code located in the file.",
+				},
+			},
+			Required: []string{"file_name", "source_code"}, // Correct property names
+		},
+	}
+}
+
+// parseFiles decodes the JSON array of files returned by the model, which is
+// sometimes wrapped in a Markdown code fence.
+func parseFiles(text string) ([]File, error) {
+	var files []File
+	if err := json.Unmarshal([]byte(stripCodeFence(text)), &files); err != nil {
+		return nil, fmt.Errorf("parsing response: %w", err)
+	}
+	fmt.Printf("\nSuccessfully parsed %d file(s)\n", len(files))
+	return files, nil
+}
+
+// stripCodeFence removes the Markdown code fence, with an optional language
+// tag, around text. Text without a fence is returned trimmed.
+func stripCodeFence(text string) string {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, "```") || !strings.HasSuffix(text, "```") {
+		return text
+	}
+	first, rest, ok := strings.Cut(text, "\n")
+	if !ok || strings.ContainsAny(first[3:], " `") {
+		return text
+	}
+	return strings.TrimSpace(strings.TrimSuffix(rest, "```"))
+}
@@ -0,0 +1,92 @@
+package agentcoder
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// captureStdout returns what f prints to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- data
+	}()
+	defer func() {
+		os.Stdout = stdout
+	}()
+	f()
+	w.Close()
+	return string(<-done)
+}
+
+func TestPrintRawResponse(t *testing.T) {
+	text := "[\n  {\n    \"file_name\": \"a.go\",\n    \"source_code\": \"package a\"\n  }\n]"
+	tests := []struct {
+		name string
+		text string
+		cfg  func(*Config)
+		want string
+	}{
+		{name: "compact", text: text, cfg: func(cfg *Config) { cfg.Compact = true }, want: "\nAPI Response:\n[{\"file_name\":\"a.go\",\"source_code\":\"package a\"}]\n"},
+		{name: "no raw", text: text, cfg: func(cfg *Config) { cfg.NoRaw = true }, want: ""},
+		{name: "compact invalid JSON", text: "not json", cfg: func(cfg *Config) { cfg.Compact = true }, want: "\nAPI Response:\nnot json\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.cfg(&cfg)
+			var err error
+			out := captureStdout(t, func() { err = printRawResponse(cfg, tt.text) })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out != tt.want {
+				t.Errorf("printed %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestTextResponseFormat(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResponseFormat = "text"
+	cfg.OutputFile = "notes.md"
+	cfg.Prompt = "Write release notes."
+	cfg.OutputDir = "out"
+	model, err := newModel(&genai.Client{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if model.ResponseMIMEType != "text/plain" || model.ResponseSchema != nil {
+		t.Errorf("MIME type %q, schema %v; want text/plain without a schema", model.ResponseMIMEType, model.ResponseSchema)
+	}
+
+	raw := "# Notes\n\n* [not JSON] {\"a\": 1}  \n"
+	files, err := generate(context.Background(), cfg, &fakeGenerator{replies: []*reply{{Text: raw}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fsys := newMemFS()
+	if err := writeFiles(fsys, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fsys.ReadFile("out/notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != raw {
+		t.Errorf("notes.md = %q, want the raw response %q", data, raw)
+	}
+}
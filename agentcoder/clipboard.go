@@ -0,0 +1,59 @@
+package agentcoder
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// clipboardReader returns the text on the system clipboard. It is a variable
+// so other clipboard sources can be plugged in.
+var clipboardReader = readClipboard
+
+// clipboardCommands returns the commands that print the clipboard on this
+// system, in order of preference.
+func clipboardCommands() ([][]string, error) {
+	switch runtime.GOOS {
+	case "darwin":
+		return [][]string{{"pbpaste"}}, nil
+	case "windows":
+		return [][]string{{"powershell", "-NoProfile", "-Command", "Get-Clipboard -Raw"}}, nil
+	}
+	var cmds [][]string
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-paste", "--no-newline"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		cmds = append(cmds, []string{"xclip", "-selection", "clipboard", "-o"}, []string{"xsel", "--clipboard", "--output"})
+	}
+	if len(cmds) == 0 {
+		return nil, errors.New("no clipboard available: neither DISPLAY nor WAYLAND_DISPLAY is set")
+	}
+	return cmds, nil
+}
+
+// readClipboard reads the system clipboard with the first available command.
+func readClipboard() (string, error) {
+	cmds, err := clipboardCommands()
+	if err != nil {
+		return "", err
+	}
+	for _, args := range cmds {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		out, err := runCommand(5*time.Second, "", args...)
+		if err != nil {
+			return "", err
+		}
+		return string(out), nil
+	}
+	names := make([]string, len(cmds))
+	for i, args := range cmds {
+		names[i] = args[0]
+	}
+	return "", errors.New("no clipboard tool found, install one of " + strings.Join(names, ", "))
+}
@@ -0,0 +1,56 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeClipboard makes the clipboard return text and err for the rest of the
+// test.
+func fakeClipboard(t *testing.T, text string, err error) {
+	t.Helper()
+	prev := clipboardReader
+	clipboardReader = func() (string, error) { return text, err }
+	t.Cleanup(func() { clipboardReader = prev })
+}
+
+func TestClipboardPrompt(t *testing.T) {
+	fakeClipboard(t, "Write a clipboard manager in Go.", nil)
+	cfg := DefaultConfig()
+	cfg.Clipboard = true
+	req, err := readPrompt(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Text, "Write a clipboard manager in Go.") {
+		t.Errorf("prompt %q does not contain the clipboard", req.Text)
+	}
+}
+
+func TestClipboardErrors(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Clipboard = true
+
+	fakeClipboard(t, " \n", nil)
+	if _, err := userPrompt(cfg, nil); err == nil || err.Error() != "the clipboard is empty" {
+		t.Errorf("userPrompt() error = %v, want the clipboard is empty", err)
+	}
+	fakeClipboard(t, "", errors.New("no clipboard available"))
+	if _, err := userPrompt(cfg, nil); err == nil || !strings.Contains(err.Error(), "reading clipboard: no clipboard available") {
+		t.Errorf("userPrompt() error = %v, want the clipboard error", err)
+	}
+}
+
+func TestClipboardHeadless(t *testing.T) {
+	if runtime.GOOS == "darwin" || runtime.GOOS == "windows" {
+		t.Skip("the clipboard does not depend on a display")
+	}
+	t.Setenv("DISPLAY", "")
+	t.Setenv("WAYLAND_DISPLAY", "")
+	if _, err := readClipboard(); err == nil || !strings.Contains(err.Error(), "no clipboard available") {
+		t.Errorf("readClipboard() error = %v, want no clipboard available", err)
+	}
+}
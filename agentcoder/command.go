@@ -0,0 +1,39 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// errCommandTimeout is returned for external commands killed after running
+// longer than the command timeout.
+var errCommandTimeout = errors.New("command timed out")
+
+// runCommand runs the command in args in dir and returns its combined output.
+// A positive timeout kills the command, and anything it started, once it runs
+// longer than that.
+func runCommand(timeout time.Duration, dir string, args ...string) ([]byte, error) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = dir
+	// Do not wait forever for children that inherited the output pipes
+	cmd.WaitDelay = time.Second
+	out, err := cmd.CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return out, fmt.Errorf("%s: %w after %v", args[0], errCommandTimeout, timeout)
+	}
+	return out, err
+}
+
+// commandTimeout returns the configured timeout for external commands.
+func (cfg Config) commandTimeout() time.Duration {
+	return time.Duration(cfg.CommandTimeout) * time.Second
+}
@@ -0,0 +1,74 @@
+package agentcoder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sleepingCommand writes a script that sleeps far longer than any test timeout
+// and returns its path.
+func sleepingCommand(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "hang.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\nexec sleep 30\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunCommandTimeout(t *testing.T) {
+	start := time.Now()
+	_, err := runCommand(100*time.Millisecond, "", sleepingCommand(t))
+	if !errors.Is(err, errCommandTimeout) {
+		t.Fatalf("runCommand() error = %v, want %v", err, errCommandTimeout)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("command killed after %v, want it killed at the timeout", elapsed)
+	}
+}
+
+func TestRunCommandNoTimeout(t *testing.T) {
+	out, err := runCommand(0, "", "echo", "hi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != "hi\n" {
+		t.Errorf("runCommand() = %q, want %q", out, "hi\n")
+	}
+}
+
+func TestFormatterTimeout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.FormatCode = true
+	cfg.CommandTimeout = 1
+	cfg.Formatters = map[string]string{".txt": sleepingCommand(t)}
+	files := []File{{Name: "notes.txt", Code: "hello"}}
+
+	// Reported as a format issue without failing the run
+	var result writeResult
+	out := captureStdout(t, func() {
+		err := writeFilesFunc(osFS{}, cfg, files, func(_ File, r writeResult) { result = r })
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if result.Status != writeWritten {
+		t.Fatalf("result = %+v, want written", result)
+	}
+	if !strings.Contains(out, "Warning: could not format notes.txt") || !strings.Contains(out, errCommandTimeout.Error()) {
+		t.Errorf("output %q does not warn about the timeout", out)
+	}
+
+	// Fails the run in strict mode
+	cfg.Strict = true
+	var err error
+	captureStdout(t, func() { err = writeFiles(osFS{}, cfg, files) })
+	if !errors.Is(err, errCommandTimeout) {
+		t.Errorf("strict writeFiles() error = %v, want %v", err, errCommandTimeout)
+	}
+}
@@ -0,0 +1,329 @@
+package agentcoder
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// commentSyntax describes the comments and string literals of a family of
+// languages.
+type commentSyntax struct {
+	line   string // Start of a line comment
+	block  bool   // Whether /* */ block comments are used
+	quotes string // Characters opening a string literal anywhere
+
+	// wordLine restricts line comments to the start of a word, as in shell
+	// and YAML, where the # of $#, ${#var} or a#b starts no comment
+	wordLine bool
+
+	// wordQuotes are the characters opening a string literal only at the
+	// start of a word, so that an apostrophe inside a word is not taken for
+	// one
+	wordQuotes string
+
+	// rawSingle is set when a backslash between single quotes is an
+	// ordinary character rather than an escape
+	rawSingle bool
+
+	// charLiterals is set for Rust, where a single quote opens a character
+	// literal if a single character and a closing quote follow, and starts
+	// a lifetime or label otherwise
+	charLiterals bool
+}
+
+var (
+	cLikeComments = commentSyntax{line: "//", block: true, quotes: "\"'`"}
+	rustComments  = commentSyntax{line: "//", block: true, quotes: `"`, charLiterals: true}
+	hashComments  = commentSyntax{line: "#", quotes: `"'`}
+	shellComments = commentSyntax{line: "#", quotes: "\"`", wordLine: true, wordQuotes: "'", rawSingle: true}
+	yamlComments  = commentSyntax{line: "#", wordLine: true, wordQuotes: `"'`, rawSingle: true}
+	tomlComments  = commentSyntax{line: "#", quotes: `"'`, rawSingle: true}
+)
+
+// commentSyntaxes maps the extensions of the files --strip-comments handles,
+// other than Go, to their comment syntax.
+var commentSyntaxes = map[string]commentSyntax{
+	".c":     cLikeComments,
+	".h":     cLikeComments,
+	".cc":    cLikeComments,
+	".cpp":   cLikeComments,
+	".hpp":   cLikeComments,
+	".cs":    cLikeComments,
+	".java":  cLikeComments,
+	".kt":    cLikeComments,
+	".scala": cLikeComments,
+	".swift": cLikeComments,
+	".js":    cLikeComments,
+	".jsx":   cLikeComments,
+	".ts":    cLikeComments,
+	".tsx":   cLikeComments,
+	".rs":    rustComments,
+	".css":   {block: true, quotes: `"'`},
+	".py":    hashComments,
+	".rb":    hashComments,
+	".sh":    shellComments,
+	".bash":  shellComments,
+	".yaml":  yamlComments,
+	".yml":   yamlComments,
+	".toml":  tomlComments,
+}
+
+// headerPattern matches comments that are kept as license or provenance
+// headers.
+var headerPattern = regexp.MustCompile(`(?i)copyright|licen[cs]e|spdx-license-identifier|code generated`)
+
+// isKeptGoComment reports whether a Go comment carries meaning for the
+// toolchain, such as a build constraint or directive, and must stay.
+func isKeptGoComment(text string) bool {
+	return strings.HasPrefix(text, "//go:") || strings.HasPrefix(text, "//line ") || strings.HasPrefix(text, "// +build") || strings.HasPrefix(text, "//export ")
+}
+
+// stripGoComments removes the comments of a Go source file, keeping the
+// license or provenance header before the package clause, directives, and the
+// preamble of cgo.
+func stripGoComments(code string) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", code, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return "", err
+	}
+	cgo := make(map[*ast.CommentGroup]bool)
+	for _, spec := range file.Imports {
+		if spec.Path.Value == `"C"` {
+			for _, decl := range file.Decls {
+				if gen, ok := decl.(*ast.GenDecl); ok && gen.Tok == token.IMPORT && gen.Doc != nil {
+					cgo[gen.Doc] = true
+				}
+			}
+			cgo[spec.Doc] = true
+		}
+	}
+	// Not nil even if no comment is kept, as the printer falls back to the
+	// doc comments of the declarations when a file has no comments
+	kept := []*ast.CommentGroup{}
+	for _, group := range file.Comments {
+		header := group.End() < file.Package && headerPattern.MatchString(group.Text())
+		if header || cgo[group] {
+			kept = append(kept, group)
+			continue
+		}
+		var directives []*ast.Comment
+		for _, c := range group.List {
+			if isKeptGoComment(c.Text) {
+				directives = append(directives, c)
+			}
+		}
+		if len(directives) > 0 {
+			kept = append(kept, &ast.CommentGroup{List: directives})
+		}
+	}
+	file.Comments = kept
+	var buf bytes.Buffer
+	if err := format.Node(&buf, fset, file); err != nil {
+		return "", err
+	}
+
+	// The printer keeps the line of a comment that opened a block, which is
+	// dropped unless it is part of a raw string
+	formatted := buf.String()
+	inString := make(map[int]bool)
+	fset = token.NewFileSet()
+	if file, err = parser.ParseFile(fset, "", formatted, parser.SkipObjectResolution); err != nil {
+		return "", err
+	}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+			for line := fset.Position(lit.Pos()).Line; line <= fset.Position(lit.End()).Line; line++ {
+				inString[line] = true
+			}
+		}
+		return true
+	})
+	lines := strings.Split(formatted, "\n")
+	out := lines[:0]
+	for i, line := range lines {
+		if line == "" && i > 0 && strings.HasSuffix(lines[i-1], "{") && !inString[i+1] {
+			continue
+		}
+		out = append(out, line)
+	}
+	return strings.Join(out, "\n"), nil
+}
+
+// stripComments removes the comments of code written with the given comment
+// syntax, leaving string literals alone. The comments at the very top of the
+// file are kept if they look like a license or provenance header, as is a
+// shebang line. Lines left empty by a removed comment are dropped.
+func stripComments(code string, syntax commentSyntax) string {
+	var b strings.Builder
+	header := true
+	var quote byte
+	for i := 0; i < len(code); {
+		c := code[i]
+		switch {
+		case quote != 0:
+			b.WriteByte(c)
+			if c == '\\' && i+1 < len(code) && !(quote == '\'' && syntax.rawSingle) {
+				b.WriteByte(code[i+1])
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+			i++
+			continue
+		case strings.IndexByte(syntax.quotes, c) >= 0 || strings.IndexByte(syntax.wordQuotes, c) >= 0 && afterAny(code, i, wordSeparators+quotePrefixes):
+			quote = c
+			header = false
+		case c == '\'' && syntax.charLiterals:
+			header = false
+			if end := charLiteralEnd(code, i); end > 0 {
+				b.WriteString(code[i:end])
+				i = end
+				continue
+			}
+		case i == 0 && strings.HasPrefix(code, "#!"):
+			end := lineEnd(code, i)
+			b.WriteString(code[i:end])
+			i = end
+			continue
+		case syntax.line != "" && strings.HasPrefix(code[i:], syntax.line) && (!syntax.wordLine || afterAny(code, i, wordSeparators)):
+			end := lineEnd(code, i)
+			if header && headerPattern.MatchString(code[i:end]) {
+				b.WriteString(code[i:end])
+			} else {
+				b.WriteByte(removedComment)
+			}
+			i = end
+			continue
+		case syntax.block && strings.HasPrefix(code[i:], "/*"):
+			end := strings.Index(code[i+2:], "*/")
+			if end < 0 {
+				end = len(code)
+			} else {
+				end += i + 4
+			}
+			if header && headerPattern.MatchString(code[i:end]) {
+				b.WriteString(code[i:end])
+			} else {
+				b.WriteByte(removedComment)
+			}
+			i = end
+			continue
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			header = false
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return dropEmptiedLines(b.String())
+}
+
+// wordSeparators end a word in the languages whose comments or quotes only
+// start a word: whitespace and the metacharacters of the shell.
+const wordSeparators = " \t\r\n;|&()<>"
+
+// quotePrefixes may also come right before the opening quote of a string, as
+// in NAME='value', $'text' or ['a', 'b'].
+const quotePrefixes = "=$[{,:"
+
+// afterAny reports whether offset i of code is at its start or follows one of
+// the characters in chars.
+func afterAny(code string, i int, chars string) bool {
+	return i == 0 || strings.IndexByte(chars, code[i-1]) >= 0
+}
+
+// charLiteralEnd returns the offset after the Rust character literal opened
+// by the single quote at offset i, or -1 if the quote starts a lifetime or
+// label instead.
+func charLiteralEnd(code string, i int) int {
+	rest := code[i+1:]
+	if strings.HasPrefix(rest, "\\") && len(rest) > 2 {
+		// An escape such as '\n', '\'' or '\u{1F600}'
+		if end := strings.IndexByte(rest[2:], '\''); end >= 0 && end <= 8 {
+			return i + 1 + 2 + end + 1
+		}
+		return -1
+	}
+	_, size := utf8.DecodeRuneInString(rest)
+	if size > 0 && size < len(rest) && rest[size] == '\'' {
+		return i + 1 + size + 1
+	}
+	return -1
+}
+
+// lineEnd returns the offset of the end of the line containing offset i.
+func lineEnd(code string, i int) int {
+	if end := strings.IndexByte(code[i:], '\n'); end >= 0 {
+		return i + end
+	}
+	return len(code)
+}
+
+// removedComment marks where stripComments removed a comment, until
+// dropEmptiedLines cleans up the lines.
+const removedComment = '\x00'
+
+// removedCommentPattern matches a removed comment with the whitespace before
+// it.
+var removedCommentPattern = regexp.MustCompile("[ \t]*\x00")
+
+// dropEmptiedLines removes the lines of stripped that held nothing but
+// removed comments, and the whitespace left before removed comments on the
+// other lines. Blank lines of the code stay.
+func dropEmptiedLines(stripped string) string {
+	lines := strings.Split(stripped, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.ContainsRune(line, removedComment) {
+			kept = append(kept, line)
+			continue
+		}
+		line = removedCommentPattern.ReplaceAllString(line, "")
+		cr := strings.HasSuffix(line, "\r")
+		if line = strings.TrimRight(line, " \t\r"); line == "" {
+			continue
+		}
+		if cr {
+			line += "\r"
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// stripFileComments removes the comments of the files in a language it
+// knows, reporting how many files were modified. Go files that do not parse
+// are left as they are.
+func stripFileComments(files []File) []File {
+	modified := 0
+	for i, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name))
+		code := file.Code
+		if ext == ".go" {
+			stripped, err := stripGoComments(code)
+			if err != nil {
+				fmt.Printf("Warning: keeping the comments of %s, which does not parse: %v\n", file.Name, err)
+				continue
+			}
+			code = stripped
+		} else if syntax, ok := commentSyntaxes[ext]; ok {
+			code = stripComments(code, syntax)
+		}
+		if code != file.Code {
+			files[i].Code = code
+			modified++
+		}
+	}
+	if modified > 0 {
+		fmt.Printf("Stripped comments from %d file(s)\n", modified)
+	}
+	return files
+}
@@ -0,0 +1,194 @@
+package agentcoder
+
+import (
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func TestStripGoComments(t *testing.T) {
+	code := `// Copyright 2024 The Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+// Package demo greets.
+package demo
+
+import "fmt"
+
+// Greeting is what Hello says.
+const Greeting = "hello // not a comment" /* trailing */
+
+//go:generate stringer -type=Mode
+
+// Hello prints the greeting.
+func Hello() {
+	// Say it
+	fmt.Println(Greeting) // twice?
+	s := ` + "`" + `
+// kept in a raw string
+` + "`" + `
+	fmt.Println(s)
+}
+`
+	want := `// Copyright 2024 The Authors. All rights reserved.
+// SPDX-License-Identifier: MIT
+
+//go:build linux
+
+package demo
+
+import "fmt"
+
+const Greeting = "hello // not a comment"
+
+//go:generate stringer -type=Mode
+
+func Hello() {
+	fmt.Println(Greeting)
+	s := ` + "`" + `
+// kept in a raw string
+` + "`" + `
+	fmt.Println(s)
+}
+`
+	got, err := stripGoComments(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want {
+		t.Errorf("stripGoComments() =\n%s\nwant\n%s", got, want)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "demo.go", got, 0); err != nil {
+		t.Errorf("stripped code does not parse: %v", err)
+	}
+}
+
+func TestStripGoCommentsKeepsCgoPreamble(t *testing.T) {
+	code := "package demo\n\n// #include <stdio.h>\nimport \"C\"\n\n// Print prints.\nfunc Print() {}\n"
+	got, err := stripGoComments(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got, "// #include <stdio.h>\nimport \"C\"") || strings.Contains(got, "Print prints") {
+		t.Errorf("stripGoComments() =\n%s\nwant the cgo preamble kept and the doc comment removed", got)
+	}
+}
+
+func TestStripComments(t *testing.T) {
+	tests := []struct {
+		name string
+		ext  string
+		code string
+		want string
+	}{
+		{
+			name: "C-like",
+			ext:  ".js",
+			code: "// Licensed under MIT\n\n// helper\nconst url = \"http://example.com\"; // the url\nconst s = 'it\\'s // here';\n/* block\n   comment */\nlet t = `a /* b */`;\n",
+			want: "// Licensed under MIT\n\nconst url = \"http://example.com\";\nconst s = 'it\\'s // here';\nlet t = `a /* b */`;\n",
+		},
+		{
+			name: "header only at the top",
+			ext:  ".ts",
+			code: "let x = 1;\n// Copyright 2024\nlet y = 2;\n",
+			want: "let x = 1;\nlet y = 2;\n",
+		},
+		{
+			name: "Python",
+			ext:  ".py",
+			code: "#!/usr/bin/env python3\n# helper\ns = \"# not a comment\"  # a comment\nt = 'it''s'\n",
+			want: "#!/usr/bin/env python3\ns = \"# not a comment\"\nt = 'it''s'\n",
+		},
+		{
+			name: "shell word comments",
+			ext:  ".sh",
+			code: "#!/bin/sh\n# count\necho $# ${#args[@]} a#b # args\n",
+			want: "#!/bin/sh\necho $# ${#args[@]} a#b\n",
+		},
+		{
+			name: "shell quotes",
+			ext:  ".sh",
+			code: "echo 'a # b' \"c # d\" # e\nNAME='x # y'\necho 'C:\\' # path\necho $'tab\\t# here'\n",
+			want: "echo 'a # b' \"c # d\"\nNAME='x # y'\necho 'C:\\'\necho $'tab\\t# here'\n",
+		},
+		{
+			name: "shell apostrophe in a word",
+			ext:  ".bash",
+			code: "cat <<EOF\nIt's done\nEOF\nrm -rf build # clean\n",
+			want: "cat <<EOF\nIt's done\nEOF\nrm -rf build\n",
+		},
+		{
+			name: "YAML",
+			ext:  ".yaml",
+			code: "# settings\nname: it's mine # owner\nurl: http://example.com/#anchor\nquoted: 'a # b' # c\nlist: ['x # y', \"z # w\"]\ncolor: a#b\n",
+			want: "name: it's mine\nurl: http://example.com/#anchor\nquoted: 'a # b'\nlist: ['x # y', \"z # w\"]\ncolor: a#b\n",
+		},
+		{
+			name: "Rust",
+			ext:  ".rs",
+			code: "// helper\nfn first<'a>(s: &'a str) -> char { // lifetime\n    let q = '\"'; // quote\n    let e = '\\''; // escaped\n    'outer: loop { break 'outer; } // label\n    s.chars().next().unwrap_or('/') // slash\n}\n",
+			want: "fn first<'a>(s: &'a str) -> char {\n    let q = '\"';\n    let e = '\\'';\n    'outer: loop { break 'outer; }\n    s.chars().next().unwrap_or('/')\n}\n",
+		},
+		{
+			name: "TOML",
+			ext:  ".toml",
+			code: "# config\npath = 'C:\\dir\\' # windows\nname = \"a # b\"\n",
+			want: "path = 'C:\\dir\\'\nname = \"a # b\"\n",
+		},
+		{
+			name: "CSS",
+			ext:  ".css",
+			code: "/* theme */\na { content: \"/* no */\"; } /* link */\n",
+			want: "a { content: \"/* no */\"; }\n",
+		},
+	}
+	for _, tt := range tests {
+		if got := stripComments(tt.code, commentSyntaxes[tt.ext]); got != tt.want {
+			t.Errorf("%s: stripComments() =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCharLiteralEnd(t *testing.T) {
+	tests := []struct {
+		code string
+		want int
+	}{
+		{"'a'", 3},
+		{"'é'", 4},
+		{`'\n'`, 4},
+		{`'\''`, 4},
+		{`'\u{1F600}'`, 11},
+		{"'a", -1},
+		{"'static str", -1},
+		{`'\`, -1},
+	}
+	for _, tt := range tests {
+		if got := charLiteralEnd(tt.code, 0); got != tt.want {
+			t.Errorf("charLiteralEnd(%q) = %d, want %d", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestStripFileComments(t *testing.T) {
+	files := []File{
+		{Name: "main.go", Code: "package main\n\n// main runs.\nfunc main() {}\n"},
+		{Name: "broken.go", Code: "package main\n\n// broken\nfunc {\n"},
+		{Name: "notes.txt", Code: "# not a comment\n"},
+		{Name: "run.sh", Code: "echo hi # greet\n"},
+	}
+	var got []File
+	out := captureStdout(t, func() { got = stripFileComments(files) })
+	want := []string{"package main\n\nfunc main() {}\n", "package main\n\n// broken\nfunc {\n", "# not a comment\n", "echo hi\n"}
+	for i, file := range got {
+		if file.Code != want[i] {
+			t.Errorf("%s = %q, want %q", file.Name, file.Code, want[i])
+		}
+	}
+	if !strings.Contains(out, "keeping the comments of broken.go") || !strings.Contains(out, "Stripped comments from 2 file(s)") {
+		t.Errorf("output %q does not report the stripped and kept files", out)
+	}
+}
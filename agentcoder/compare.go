@@ -0,0 +1,185 @@
+package agentcoder
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// compareSummaryFile is the name of the summary written into the output
+// directory by the compare subcommand.
+const compareSummaryFile = "compare.json"
+
+// compareResult is the outcome of generating the prompt with one model.
+type compareResult struct {
+	Model          string  `json:"model"`                     // Model the prompt was sent to
+	Dir            string  `json:"dir"`                       // Directory the files were written to
+	Files          int     `json:"files"`                     // Number of files generated
+	PromptTokens   int32   `json:"prompt_tokens,omitempty"`   // Tokens of the prompt, if reported
+	ResponseTokens int32   `json:"response_tokens,omitempty"` // Tokens of the response, if reported
+	Seconds        float64 `json:"seconds"`                   // Time taken to generate and write the files
+	Error          string  `json:"error,omitempty"`           // Why the generation failed
+}
+
+// usageRecorder is a generator that remembers the token usage of the last
+// reply of the generator it wraps.
+type usageRecorder struct {
+	generator
+	usage *reply
+}
+
+func (u *usageRecorder) Generate(ctx context.Context, prompt string) (*reply, error) {
+	r, err := u.generator.Generate(ctx, prompt)
+	if err == nil {
+		u.usage = r
+	}
+	return r, err
+}
+
+// modelDirName returns a directory name for the output of model.
+func modelDirName(model string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, model)
+}
+
+// modelPath returns the path of a file of model next to path, the file of
+// the whole run, with the name of the model before the extension.
+func modelPath(path, model string) string {
+	if path == "" {
+		return ""
+	}
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + modelDirName(model) + ext
+}
+
+// compareModels sends cfg.Prompt to each model, at most maxConcurrency at a
+// time, and writes the files of each into a subdirectory of the output
+// directory named after the model. The debug dump and response log of each
+// model get a name of their own, while cfg.cost, if set, keeps all models
+// within one cost ceiling. newGen creates the generator for the configuration
+// of a model. The results are returned in the order of models.
+func compareModels(ctx context.Context, cfg Config, models []string, newGen func(context.Context, Config) (generator, error)) []compareResult {
+	results := make([]compareResult, len(models))
+	sem := make(chan struct{}, max(cfg.MaxConcurrency, 1))
+	var wg sync.WaitGroup
+	for i, model := range models {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			mcfg := cfg
+			mcfg.Model = model
+			mcfg.OutputDir = filepath.Join(cfg.OutputDir, modelDirName(model))
+			mcfg.DebugDump = modelPath(cfg.DebugDump, model)
+			mcfg.ResponseLog = modelPath(cfg.ResponseLog, model)
+			start := time.Now()
+			result := compareResult{Model: model, Dir: mcfg.OutputDir}
+			n, r, err := compareModel(ctx, mcfg, newGen)
+			result.Seconds = time.Since(start).Seconds()
+			result.Files = n
+			if r != nil && r.Usage != nil {
+				result.PromptTokens = r.Usage.PromptTokenCount
+				result.ResponseTokens = r.Usage.CandidatesTokenCount
+			}
+			if err != nil {
+				result.Error = err.Error()
+			}
+			results[i] = result
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// compareModel generates and writes the files for cfg with a generator made
+// by newGen, returning the number of files and the reply of the model.
+func compareModel(ctx context.Context, cfg Config, newGen func(context.Context, Config) (generator, error)) (int, *reply, error) {
+	gen, err := newGen(ctx, cfg)
+	if err != nil {
+		return 0, nil, err
+	}
+	if c, ok := gen.(io.Closer); ok {
+		defer c.Close()
+	}
+	rec := &usageRecorder{generator: gen}
+	files, err := generate(ctx, cfg, rec)
+	if err != nil {
+		return 0, rec.usage, err
+	}
+	files = transformFiles(cfg, files)
+	if files, err = filterFiles(cfg, files); err != nil {
+		return 0, rec.usage, err
+	}
+	if err := checkFileCount(cfg, files); err != nil {
+		return len(files), rec.usage, err
+	}
+	return len(files), rec.usage, writeFiles(osFS{}, cfg, files)
+}
+
+// printComparison prints the results as a table.
+func printComparison(results []compareResult) {
+	fmt.Printf("\n%-30s %6s %10s %10s %8s  %s\n", "MODEL", "FILES", "PROMPT", "RESPONSE", "SECONDS", "ERROR")
+	for _, r := range results {
+		fmt.Printf("%-30s %6d %10d %10d %8.1f  %s\n", r.Model, r.Files, r.PromptTokens, r.ResponseTokens, r.Seconds, r.Error)
+	}
+}
+
+// runCompare implements the compare subcommand, which sends the same prompt
+// to several models and writes the output of each into its own subdirectory
+// together with a summary.
+func runCompare(args []string) {
+	cfg, _, err := parseConfig(args, nil)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(cfg.CompareModels) < 2 {
+		fmt.Println("Usage: compare --models <model>,<model>[,...] [flags]")
+		os.Exit(1)
+	}
+	if cfg.Prompt, err = userPrompt(cfg, bufio.NewScanner(os.Stdin)); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Share one cost tracker so that --max-cost limits the whole comparison
+	if cfg.cost, err = newCostTracker(cfg); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	results := compareModels(context.Background(), cfg, cfg.CompareModels, func(ctx context.Context, cfg Config) (generator, error) {
+		return newModelGenerator(ctx, cfg)
+	})
+	printComparison(results)
+
+	data, err := json.MarshalIndent(results, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(cfg.OutputDir, os.FileMode(cfg.DirMode))
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(cfg.OutputDir, compareSummaryFile), data, 0644)
+	}
+	if err != nil {
+		fmt.Printf("Error writing comparison summary: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("\nComparison summary written to %s\n", filepath.Join(cfg.OutputDir, compareSummaryFile))
+	for _, r := range results {
+		if r.Error != "" {
+			os.Exit(1)
+		}
+	}
+}
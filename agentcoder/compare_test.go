@@ -0,0 +1,122 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareModels(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a greeter."
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	cfg.MaxConcurrency = 2
+	cfg.DebugDump = filepath.Join(t.TempDir(), "dump.json")
+	cfg.ResponseLog = filepath.Join(t.TempDir(), "responses.jsonl")
+	replies := map[string]*reply{
+		"model-a":    filesReply(t, File{Name: "hello.txt", Code: "hello"}),
+		"provider/b": filesReply(t, File{Name: "hello.txt", Code: "hi"}, File{Name: "bye.txt", Code: "bye"}),
+	}
+	var results []compareResult
+	captureStdout(t, func() {
+		results = compareModels(context.Background(), cfg, []string{"model-a", "provider/b"}, func(ctx context.Context, mcfg Config) (generator, error) {
+			return &fakeGenerator{replies: []*reply{replies[mcfg.Model]}}, nil
+		})
+	})
+
+	if len(results) != 2 || results[0].Model != "model-a" || results[1].Model != "provider/b" {
+		t.Fatalf("results = %+v, want model-a and provider/b in order", results)
+	}
+	if results[0].Files != 1 || results[1].Files != 2 || results[0].Error != "" || results[1].Error != "" {
+		t.Errorf("results = %+v, want 1 and 2 files without errors", results)
+	}
+	if got := readTree(t, filepath.Join(cfg.OutputDir, "model-a")); !reflect.DeepEqual(got, map[string]string{"hello.txt": "hello"}) {
+		t.Errorf("model-a wrote %v", got)
+	}
+	if got := readTree(t, filepath.Join(cfg.OutputDir, "provider_b")); !reflect.DeepEqual(got, map[string]string{"hello.txt": "hi", "bye.txt": "bye"}) {
+		t.Errorf("provider/b wrote %v", got)
+	}
+
+	// Each model keeps its own debug dump and response log
+	for _, path := range []string{
+		filepath.Join(filepath.Dir(cfg.DebugDump), "dump-model-a.json"),
+		filepath.Join(filepath.Dir(cfg.DebugDump), "dump-provider_b.json"),
+		filepath.Join(filepath.Dir(cfg.ResponseLog), "responses-model-a.jsonl"),
+		filepath.Join(filepath.Dir(cfg.ResponseLog), "responses-provider_b.jsonl"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Error(err)
+		}
+	}
+	if entries := readResponseLog(t, filepath.Join(filepath.Dir(cfg.ResponseLog), "responses-provider_b.jsonl")); len(entries) != 1 {
+		t.Errorf("%d entries in the response log of provider/b, want 1", len(entries))
+	}
+}
+
+func TestModelPath(t *testing.T) {
+	tests := []struct {
+		path, model, want string
+	}{
+		{"dump.json", "gemini-2.5-pro", "dump-gemini-2.5-pro.json"},
+		{"/var/log/responses.jsonl", "models/gemini", "/var/log/responses-models_gemini.jsonl"},
+		{"dump", "a", "dump-a"},
+		{"", "a", ""},
+	}
+	for _, tt := range tests {
+		if got := modelPath(tt.path, tt.model); got != tt.want {
+			t.Errorf("modelPath(%q, %q) = %q, want %q", tt.path, tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestCompareModelsShareCostCeiling(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a greeter."
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	cfg.MaxConcurrency = 1
+	// Each request is estimated at $0.000125 and fits the ceiling alone,
+	// but the reply to either model costs enough that the request to the
+	// other one would exceed it
+	cfg.MaxCost = 0.0002
+	var err error
+	if cfg.cost, err = newCostTracker(cfg); err != nil {
+		t.Fatal(err)
+	}
+	apis := make(map[string]*fakeAPI)
+	var results []compareResult
+	captureStdout(t, func() {
+		results = compareModels(context.Background(), cfg, []string{"gemini-2.5-pro", "gemini-1.5-pro"}, func(ctx context.Context, mcfg Config) (generator, error) {
+			api, client := newFakeAPI(t,
+				tokenCount(100),
+				textResponse(`[{"file_name": "a.txt", "source_code": "a"}]`, "STOP"),
+			)
+			apis[mcfg.Model] = api
+			return newClientGenerator(client, mcfg)
+		})
+	})
+
+	var done, refused int
+	for _, r := range results {
+		switch {
+		case r.Error == "" && r.Files == 1:
+			done++
+		case strings.Contains(r.Error, errCostCeiling.Error()):
+			refused++
+			if n := apis[r.Model].requests(); n != 1 {
+				t.Errorf("%s got %d requests after it was refused, want only the token count", r.Model, n)
+			}
+		}
+	}
+	if done != 1 || refused != 1 {
+		t.Errorf("results = %+v, want one model to generate and the other to be refused", results)
+	}
+	if !errors.Is(cfg.cost.check("gemini-2.5-pro", 0.0001), errCostCeiling) {
+		t.Errorf("shared tracker allows more requests after spending $%.7f", cfg.cost.spent)
+	}
+}
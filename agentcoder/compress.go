@@ -0,0 +1,45 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// compressWhitespace removes trailing whitespace from every line of text,
+// collapses runs of blank lines into one and trims blank lines at the start
+// and end.
+func compressWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := lines[:0]
+	blank := false
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" {
+			if blank || len(out) == 0 {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.TrimRight(strings.Join(out, "\n"), "\n")
+}
+
+// reportCompression prints how much smaller --prompt-compress made the
+// request, in tokens if gen can count them and in characters otherwise.
+func reportCompression(ctx context.Context, gen generator, original, compressed string) {
+	if counter, ok := gen.(tokenCounter); ok {
+		before, err := counter.CountTokens(ctx, original)
+		if err == nil {
+			after, err := counter.CountTokens(ctx, compressed)
+			if err == nil {
+				fmt.Printf("Prompt compression saved %d token(s) (%d to %d)\n", before-after, before, after)
+				return
+			}
+		}
+	}
+	fmt.Printf("Prompt compression saved %d character(s) (%d to %d)\n", len(original)-len(compressed), len(original), len(compressed))
+}
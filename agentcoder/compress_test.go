@@ -0,0 +1,68 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompressWhitespace(t *testing.T) {
+	tests := []struct {
+		text, want string
+	}{
+		{"Write a server.", "Write a server."},
+		{"\n\n  \nWrite a server.   \n\n\n\nUse Go.\t\n\n", "Write a server.\n\nUse Go."},
+		{"Line one.  \r\nLine two.\r\n", "Line one.\nLine two."},
+		{"  Indented line.\n\n\n    Kept indentation.", "  Indented line.\n\n    Kept indentation."},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := compressWhitespace(tt.text); got != tt.want {
+			t.Errorf("compressWhitespace(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestPromptCompressKeepsIncludedFiles(t *testing.T) {
+	dir := t.TempDir()
+	code := "def main():\n    print('hi')   \n\n\n\n    return 0\n"
+	path := filepath.Join(dir, "main.py")
+	if err := os.WriteFile(path, []byte(code), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.PromptCompress = true
+	cfg.PromptInclude = true
+	got, err := newPromptChain(cfg).Apply("Port this to Go:   \n\n\n\n@" + path + "\n\n\nKeep it short.  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Port this to Go:\n\n" + code + "\n\nKeep it short."; got != want {
+		t.Errorf("compressed prompt = %q, want %q", got, want)
+	}
+}
+
+func TestPromptCompressKeepsContextFiles(t *testing.T) {
+	code := "package main\n\n\n\nfunc main() {}   \n"
+	cfg := contextConfig(t, map[string]string{"main.go": code})
+	cfg.PromptCompress = true
+	cfg.Prompt = "Refactor   \n\n\n\nthe code."
+	gen := &countingGenerator{}
+	var req *promptRequest
+	var err error
+	out := captureStdout(t, func() { req, err = readPrompt(context.Background(), cfg, gen) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Text, ":\n\nRefactor\n\nthe code.") {
+		t.Errorf("prompt %q does not contain the compressed request", req.Text)
+	}
+	if !strings.Contains(req.Text, "--- main.go ---\n"+code) {
+		t.Errorf("prompt %q does not contain main.go unchanged", req.Text)
+	}
+	if !strings.Contains(out, "Prompt compression saved 2 token(s) (6 to 4)") {
+		t.Errorf("output %q does not report the tokens saved", out)
+	}
+}
@@ -0,0 +1,549 @@
+package agentcoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// Config holds the settings for a generation run. Values are read from the
+// optional JSON config file first and then overridden by command-line flags.
+type Config struct {
+	APIKey        string `json:"-"`              // API key for the generative AI service
+	Prompt        string `json:"-"`              // Prompt to use instead of reading it from stdin
+	OutputDir     string `json:"output"`         // Directory the generated files are written to
+	Model         string `json:"model"`          // Name of the generative model
+	Safety        string `json:"safety"`         // Safety block threshold: none, low, medium or high
+	FailOnSafety  string `json:"fail_on_safety"` // Fail when a safety rating reaches this probability
+	PromptPrefix  string `json:"prompt_prefix"`  // Text placed before every user prompt
+	PromptSuffix  string `json:"prompt_suffix"`  // Text appended after every user prompt
+	Stream        bool   `json:"stream"`         // Use the streaming API and report progress
+	FormatCode    bool   `json:"format_code"`    // Run formatters on the written files
+	Outline       bool   `json:"outline"`        // Only propose the file structure without code
+	Compact       bool   `json:"compact"`        // Print the raw response as compact JSON
+	NoRaw         bool   `json:"no_raw"`         // Do not print the raw response
+	SavePrompt    bool   `json:"save_prompt"`    // Save the assembled prompt in the output directory
+	Manifest      bool   `json:"manifest"`       // Write a manifest of file hashes to the output directory
+	SkipIdentical bool   `json:"skip_identical"` // Do not rewrite files whose content is unchanged
+	Redact        bool   `json:"redact"`         // Replace secrets in generated files with placeholders
+	ContextDir    string `json:"context_dir"`    // Directory of existing files included as context
+	ContextSince  string `json:"context_since"`  // Only include context files modified within this window
+
+	MaxPromptChars    int  `json:"max_prompt_chars"`    // Maximum assembled prompt length, 0 for no limit
+	AutoFix           bool `json:"auto_fix"`            // Feed Go build errors back to the model
+	AutoFixIterations int  `json:"auto_fix_iterations"` // Maximum number of fix attempts
+	Chunked           bool `json:"chunked"`             // Generate an outline first and then the files in batches
+	ChunkSize         int  `json:"chunk_size"`          // Number of files generated per batch
+
+	ResponseFormat string `json:"response_format"` // Format of the response: json or text
+	OutputFile     string `json:"output_file"`     // Name of the file written in text mode
+
+	AutoTrim bool `json:"auto_trim"` // Retry with less context when the prompt is too large
+
+	OutputEncoding string `json:"output_encoding"` // Encoding of the written files: utf-8, utf-16le, utf-16be or latin1
+
+	DebugDump string `json:"debug_dump"` // File the raw response is saved to for replay
+
+	TrimTrailingWS     bool     `json:"trim_trailing_ws"`     // Remove trailing whitespace from generated lines
+	TrimSkipExtensions []string `json:"trim_skip_extensions"` // Extensions where trailing whitespace is kept
+
+	MaxContinuations int `json:"max_continuations"` // Follow-up requests made to complete a truncated response
+
+	HistoryFile string `json:"history_file"` // File entered prompts are saved to and recalled from
+
+	MaxDirDepth int `json:"max_dir_depth"` // Maximum directory nesting of generated paths, 0 for no limit
+
+	Format string `json:"format"` // Format of the per-file status output: text or jsonl
+
+	AllowedExtensions []string `json:"allowed_extensions"` // Only write files with these extensions, all if empty
+	DeniedExtensions  []string `json:"denied_extensions"`  // Never write files with these extensions
+	Strict            bool     `json:"strict"`             // Fail instead of warning when a check does not pass
+
+	ForbidPatterns []string `json:"forbid_patterns"` // Regular expressions generated files must not match
+	ForbidAction   string   `json:"forbid_action"`   // What to do on a match: abort or skip
+
+	CommandTimeout int `json:"command_timeout"` // Seconds after which formatters and builds are killed, 0 for no limit
+
+	Inject bool `json:"inject"` // Insert generated files at AGENTCODER:INSERT markers instead of writing them
+
+	PlanJSON bool `json:"plan_json"` // Print the resolved config, prompt tokens and estimated cost as JSON without generating
+
+	Proxy  string `json:"proxy"`   // URL of the HTTP proxy for API requests, HTTPS_PROXY if empty
+	CACert string `json:"ca_cert"` // PEM file with additional CA certificates to trust
+
+	MaxFilesPerDir int `json:"max_files_per_dir"` // Warn when more files target one directory, 0 for no limit
+
+	Parser string `json:"parser"` // Parser for the files in the response: json or fenced-files
+
+	NormalizePaths bool `json:"normalize_paths"` // Convert backslashes in generated file names to the OS separator
+
+	JSONSchemaStrict bool `json:"json_schema_strict"` // Fail on files missing a name or source code instead of warning
+
+	APIKeys []string `json:"-"` // Additional API keys to rotate to when a key hits a rate limit
+
+	Report string `json:"report"` // File to write a self-contained HTML report of the run to
+
+	Attachments []string `json:"attachments"` // Images or PDFs sent alongside the prompt
+
+	LintPrompt bool `json:"lint_prompt"` // Suggest improvements to vague prompts before generating
+
+	FallbackModel string `json:"fallback_model"` // Model to retry with once when the model is overloaded or unavailable
+
+	AllOrNothing bool `json:"all_or_nothing"` // Stage the files and only move them into the output directory if all succeed
+
+	NoExpand bool `json:"no_expand"` // Take paths literally instead of expanding environment variables in them
+
+	ShowDiffStat bool `json:"show_diff_stat"` // Print a diffstat of the lines changed in each file
+
+	BatchDir string `json:"batch_dir"` // Directory of prompt files to generate one after another
+	Resume   bool   `json:"resume"`    // Skip the prompts of the batch completed by an earlier run
+
+	ModelConfig string `json:"model_config"` // JSON file with generation settings such as top_p and max_output_tokens
+
+	StopSequences []string `json:"stop_sequences"` // Sequences that end the response, overriding the model config
+
+	Trace         bool   `json:"trace"`          // Export OpenTelemetry spans of the run over OTLP/HTTP
+	TraceEndpoint string `json:"trace_endpoint"` // OTLP/HTTP endpoint URL, OTEL_EXPORTER_OTLP_ENDPOINT if empty
+
+	Clipboard bool `json:"clipboard"` // Read the prompt from the system clipboard instead of stdin
+
+	Cache    bool   `json:"cache"`     // Reuse cached responses for repeated requests and skip identical files
+	CacheDir string `json:"cache_dir"` // Directory the responses are cached in
+
+	MaxLineLength int `json:"max_line_length"` // Warn about longer lines in generated code, 0 for no limit
+
+	FIFO string `json:"fifo"` // Named pipe to serve prompts from, one per line
+
+	FailIfEmpty bool `json:"fail_if_empty"` // Fail when the response contains no files
+
+	Language string `json:"language"` // Language whose formatter is enabled: auto to detect it, none, or a language such as go
+
+	Owner string `json:"owner"` // Owner of the written files as uid:gid or user:group, on Unix with privileges
+
+	SummarizeContext bool `json:"summarize_context"` // Replace large context files with a summary made by the model
+	SummaryMaxChars  int  `json:"summary_max_chars"` // Context files longer than this are summarized into at most as many characters
+
+	KeepTemp bool `json:"keep_temp"` // Keep the staging directory of --all-or-nothing for debugging
+
+	ResponseLog        string `json:"response_log"`          // File every raw response is appended to
+	ResponseLogMaxSize int64  `json:"response_log_max_size"` // Size in bytes past which the response log is rotated, 0 for no limit
+
+	OutputZip string `json:"output_zip"` // Zip archive the files are also written to
+	Stdout    bool   `json:"stdout"`     // Also emit the files to stdout as JSON
+
+	MinFiles int `json:"min_files"` // Fail when fewer files are generated, 0 for no minimum
+
+	Retries        int    `json:"retries"`          // Retries of requests that fail because the model is overloaded or unavailable
+	RetryStrategy  string `json:"retry_strategy"`   // Growth of the delay between retries: exponential, linear or constant
+	RetryBaseDelay int    `json:"retry_base_delay"` // Milliseconds before the first retry
+	RetryMaxDelay  int    `json:"retry_max_delay"`  // Maximum milliseconds between retries, 0 for the default of 30 seconds
+
+	GoVet     bool `json:"go_vet"`      // Run go vet on the generated Go code
+	FailOnVet bool `json:"fail_on_vet"` // Fail when go vet reports problems, implies GoVet
+
+	Edit bool `json:"edit"` // Compose the prompt in $VISUAL or $EDITOR
+
+	StreamWrite bool `json:"stream_write"` // Write each file as soon as it has been streamed completely
+
+	DedupeContent bool   `json:"dedupe_content"` // Detect generated files with identical content
+	DedupeAction  string `json:"dedupe_action"`  // What to do with duplicates: warn, skip or symlink
+
+	Mode string `json:"mode"` // generate for new code, or modify to edit the context files and return only changed ones
+
+	DirMode fileMode `json:"dir_mode"` // Permissions of created directories, in octal, before the umask
+
+	Concat string `json:"concat"` // Single file all generated files are written to instead of the output directory
+
+	ContextGitDiff bool   `json:"context_git_diff"` // Only include context files changed since ContextGitBase
+	ContextGitBase string `json:"context_git_base"` // Git ref the changes of ContextGitDiff are relative to
+
+	CompareModels  []string `json:"compare_models"`  // Models the compare subcommand sends the prompt to
+	MaxConcurrency int      `json:"max_concurrency"` // Maximum number of models the compare subcommand queries at once
+
+	SortFiles     bool `json:"sort_files"`     // Write files and the manifest in order of their paths
+	PreserveOrder bool `json:"preserve_order"` // Keep the order of the response, overriding SortFiles
+
+	PromptInclude         bool `json:"prompt_include"`           // Replace @path references in the prompt with the content of the file
+	PromptIncludeMaxChars int  `json:"prompt_include_max_chars"` // Maximum characters included through @path references, 0 for no limit
+
+	MaxCost float64 `json:"max_cost"` // Ceiling in US dollars on the estimated cost of the run, 0 for no limit
+
+	Validate      bool `json:"validate"`        // Check that generated JSON and YAML files are well-formed
+	FailOnInvalid bool `json:"fail_on_invalid"` // Fail when a generated file does not parse, implies Validate
+
+	Plugin string `json:"plugin"` // WebAssembly module every generated file is passed through
+
+	PromptCompress bool `json:"prompt_compress"` // Remove redundant whitespace from the request, but not from context or included files
+
+	ValidateSchema string `json:"validate_schema"` // JSON Schema (draft-07) file the response is validated against
+
+	OutputGitBranch bool `json:"output_git_branch"` // Commit the files on a new branch named after the prompt and check out the current branch again
+
+	CacheTTL int `json:"cache_ttl"` // Seconds after which cached responses expire, 0 to keep them
+
+	AbortOnBinaryOutput bool `json:"abort_on_binary_output"` // Fail instead of skipping files whose content looks binary
+
+	Verbose bool `json:"verbose"` // Print and report the time taken to process each file
+
+	ConfirmDestructive bool `json:"confirm_destructive"` // Refuse dangerous output directories and confirm overwriting files outside git
+	Yes                bool `json:"yes"`                 // Answer yes to confirmations
+
+	FunctionCalling bool     `json:"function_calling"` // Let the model call file operation functions instead of answering with files
+	FileFunctions   []string `json:"file_functions"`   // File operation functions offered to the model with --function-calling
+
+	DailyBudget    float64 `json:"daily_budget"`    // Cost in US dollars all runs of a day may add up to, 0 for no limit
+	BudgetFile     string  `json:"budget_file"`     // File the spend of the day is kept in, by default in the user configuration directory
+	BudgetTimezone string  `json:"budget_timezone"` // Time zone whose midnight starts a new budget day
+
+	ContextPriority []string `json:"context_priority"` // Globs of the context files dropped last by --auto-trim, most important first
+
+	Timeout int `json:"timeout"` // Seconds the generation may take, after which the files completed in the stream are written, 0 for no limit
+
+	StripComments bool `json:"strip_comments"` // Remove comments from generated code, keeping license headers and directives
+
+	SemanticDiff bool `json:"semantic_diff"` // Report the top-level declarations of existing Go files that are added, removed or modified
+
+	Readonly bool `json:"readonly"` // Make the written files read-only to discourage manual edits
+
+	PromptExpandEnv bool `json:"prompt_expand_env"` // Replace ${VAR} references in the prompt with the value of the environment variable
+
+	// Temperature controls the randomness of the output. The model's default
+	// is used when it is nil.
+	Temperature *float32 `json:"temperature"`
+
+	// Formatters maps file extensions to external formatter commands. Entries
+	// in the config file are merged over the defaults; an empty command
+	// disables formatting for that extension.
+	Formatters map[string]string `json:"formatters"`
+
+	// Profiles holds named sets of settings in the config file, selected with
+	// --profile and applied over the rest of the file.
+	Profiles map[string]json.RawMessage `json:"profiles"`
+
+	// existingDir, if not empty, holds the existing files that generated
+	// files are compared with instead of OutputDir. It is set when writing
+	// into a staging directory.
+	existingDir string
+
+	// cost, if not nil, is the cost tracker of the run that generators use
+	// instead of one of their own. It is set by compare so that all models
+	// stay within the same --max-cost and --daily-budget.
+	cost *costTracker
+}
+
+// configSource identifies where the configuration is loaded from.
+type configSource struct {
+	Path    string // Path of the config file
+	Profile string // Name of the profile to apply from the config file
+	Spec    string // Path of the spec file with settings and the prompt
+}
+
+// DefaultConfig returns the configuration used when neither a config file nor
+// flags override a setting.
+func DefaultConfig() Config {
+	return Config{
+		OutputDir:  "output",
+		Model:      "gemini-2.0-flash",
+		Formatters: defaultFormatters(),
+
+		AutoFixIterations: 3,
+		ChunkSize:         5,
+
+		ResponseFormat: "json",
+		OutputFile:     "response.txt",
+
+		OutputEncoding: "utf-8",
+
+		TrimSkipExtensions: []string{".md", ".markdown", ".txt"},
+
+		MaxContinuations: 3,
+
+		MaxDirDepth: 10,
+
+		Format: "text",
+
+		ForbidAction: "abort",
+
+		CommandTimeout: 60,
+
+		MaxFilesPerDir: 100,
+
+		Parser: "json",
+
+		NormalizePaths: true,
+
+		CacheDir: ".agent_coder_cache",
+
+		Language: "auto",
+
+		SummaryMaxChars: 2000,
+
+		ResponseLogMaxSize: 10 << 20,
+
+		RetryStrategy:  "exponential",
+		RetryBaseDelay: 1000,
+		RetryMaxDelay:  30000,
+
+		DedupeAction: "warn",
+
+		Mode: "generate",
+
+		DirMode: 0755,
+
+		ContextGitBase: "HEAD",
+
+		MaxConcurrency: 4,
+
+		PromptIncludeMaxChars: 100000,
+
+		FileFunctions: []string{fnCreateFile},
+
+		BudgetTimezone: "Local",
+	}
+}
+
+// registerFlags binds the command-line flags to the fields of cfg, using the
+// current field values as defaults.
+func registerFlags(fs *flag.FlagSet, cfg *Config, src *configSource) {
+	fs.StringVar(&src.Path, "config", src.Path, "Path to a JSON config file")
+	fs.StringVar(&src.Profile, "profile", src.Profile, "Name of the config file profile to use")
+	fs.StringVar(&src.Spec, "spec", src.Spec, "Path to a spec file with front-matter settings followed by the prompt")
+	fs.StringVar(&cfg.APIKey, "key", cfg.APIKey, "API key for the generative AI service")
+	fs.StringVar(&cfg.OutputDir, "output", cfg.OutputDir, "Output directory for generated files")
+	fs.StringVar(&cfg.Model, "model", cfg.Model, "Name of the generative model")
+	fs.StringVar(&cfg.Safety, "safety", cfg.Safety, "Safety block threshold: none, low, medium or high (model default if empty)")
+	fs.StringVar(&cfg.FailOnSafety, "fail-on-safety", cfg.FailOnSafety, "Fail when any safety rating reaches this probability: negligible, low, medium or high")
+	fs.Func("temperature", "Sampling temperature (model default if unset)", func(value string) error {
+		t, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			return err
+		}
+		temperature := float32(t)
+		cfg.Temperature = &temperature
+		return nil
+	})
+	fs.StringVar(&cfg.PromptPrefix, "prompt-prefix", cfg.PromptPrefix, "Text placed before every prompt")
+	fs.StringVar(&cfg.PromptSuffix, "prompt-suffix", cfg.PromptSuffix, "Text appended to every prompt")
+	fs.BoolVar(&cfg.Stream, "stream", cfg.Stream, "Stream the response and report files as they arrive")
+	fs.StringVar(&cfg.ContextDir, "context-dir", cfg.ContextDir, "Directory of existing files to include in the prompt as context")
+	fs.StringVar(&cfg.ContextSince, "context-since", cfg.ContextSince, "Only include context files modified within a duration (e.g. 24h) or since a timestamp")
+	fs.BoolVar(&cfg.Outline, "outline", cfg.Outline, "Only propose file names and descriptions without generating code")
+	fs.BoolVar(&cfg.Compact, "compact", cfg.Compact, "Print the raw API response as compact JSON")
+	fs.BoolVar(&cfg.NoRaw, "no-raw", cfg.NoRaw, "Do not print the raw API response")
+	fs.BoolVar(&cfg.SavePrompt, "save-prompt", cfg.SavePrompt, "Save the assembled prompt to "+promptFileName+" in the output directory")
+	fs.BoolVar(&cfg.Manifest, "manifest", cfg.Manifest, "Write "+manifestFileName+" with the hashes of the written files")
+	fs.BoolVar(&cfg.SkipIdentical, "skip-identical", cfg.SkipIdentical, "Leave existing files with identical content untouched, preserving their modification times")
+	fs.BoolVar(&cfg.Redact, "redact", cfg.Redact, "Replace API keys and other secrets in generated files with placeholders")
+	fs.StringVar(&cfg.ResponseFormat, "response-format", cfg.ResponseFormat, "Format of the response: json for a set of files, text for a single file")
+	fs.StringVar(&cfg.OutputFile, "output-file", cfg.OutputFile, "Name of the file the response is written to in text mode")
+	fs.BoolVar(&cfg.FormatCode, "format-code", cfg.FormatCode, "Format written files with gofmt or the configured formatters")
+	fs.IntVar(&cfg.MaxPromptChars, "max-prompt-chars", cfg.MaxPromptChars, "Confirm or truncate prompts longer than this many characters (0 disables)")
+	fs.BoolVar(&cfg.AutoFix, "auto-fix", cfg.AutoFix, "Send Go build errors back to the model and rewrite the fixed files")
+	fs.IntVar(&cfg.AutoFixIterations, "auto-fix-iterations", cfg.AutoFixIterations, "Maximum number of auto-fix attempts")
+	fs.BoolVar(&cfg.Chunked, "chunked", cfg.Chunked, "Request an outline first and then generate the files in batches")
+	fs.IntVar(&cfg.ChunkSize, "chunk-size", cfg.ChunkSize, "Number of files generated per batch in chunked mode")
+	fs.BoolVar(&cfg.AutoTrim, "auto-trim", cfg.AutoTrim, "Drop the largest context files and retry once when the prompt exceeds the token limit")
+	fs.StringVar(&cfg.OutputEncoding, "output-encoding", cfg.OutputEncoding, "Encoding of the written files: utf-8, utf-16le, utf-16be or latin1")
+	fs.StringVar(&cfg.DebugDump, "debug-dump", cfg.DebugDump, "Save the raw response to this file so it can be written again with the replay subcommand")
+	fs.BoolVar(&cfg.TrimTrailingWS, "trim-trailing-ws", cfg.TrimTrailingWS, "Remove trailing spaces and tabs from every line of the generated files")
+	fs.Var(&listFlag{values: &cfg.TrimSkipExtensions, split: true}, "trim-skip-ext", "Comma-separated extensions where trailing whitespace is kept")
+	fs.IntVar(&cfg.MaxContinuations, "max-continuations", cfg.MaxContinuations, "Maximum follow-up requests made to complete a response cut off at the token limit (0 disables)")
+	fs.StringVar(&cfg.HistoryFile, "history-file", cfg.HistoryFile, "File to save entered prompts to; enter /history to list them and !<number> to reuse one")
+	fs.IntVar(&cfg.MaxDirDepth, "max-dir-depth", cfg.MaxDirDepth, "Reject generated paths nested deeper than this many directories (0 disables)")
+	fs.StringVar(&cfg.Format, "format", cfg.Format, "Status output format: text, or jsonl for one JSON object per file on stdout with logs on stderr")
+	fs.Var(&listFlag{values: &cfg.AllowedExtensions, split: true}, "allowed-ext", "Comma-separated extensions of the only files that may be written")
+	fs.Var(&listFlag{values: &cfg.DeniedExtensions, split: true}, "denied-ext", "Comma-separated extensions of files that are never written")
+	fs.BoolVar(&cfg.Strict, "strict", cfg.Strict, "Fail the run instead of warning when a check does not pass, such as disallowed files, hung formatters or a vague prompt")
+	fs.Var(&listFlag{values: &cfg.ForbidPatterns}, "forbid-pattern", "Regular expression generated files must not match (repeatable)")
+	fs.StringVar(&cfg.ForbidAction, "forbid-action", cfg.ForbidAction, "What to do when a file matches a forbidden pattern: abort or skip")
+	fs.IntVar(&cfg.CommandTimeout, "command-timeout", cfg.CommandTimeout, "Seconds after which external formatters and builds are killed (0 disables)")
+	fs.BoolVar(&cfg.Inject, "inject", cfg.Inject, "Insert each generated file at the \"AGENTCODER:INSERT <name>\" marker in the existing files of the output directory")
+	fs.BoolVar(&cfg.PlanJSON, "plan-json", cfg.PlanJSON, "Print the resolved config, prompt token count and estimated cost as JSON, then exit without generating or writing files")
+	fs.StringVar(&cfg.Proxy, "proxy", cfg.Proxy, "URL of the HTTP proxy for API requests (defaults to HTTPS_PROXY)")
+	fs.StringVar(&cfg.CACert, "ca-cert", cfg.CACert, "PEM file with additional CA certificates to trust for API requests")
+	fs.IntVar(&cfg.MaxFilesPerDir, "max-output-files-per-dir", cfg.MaxFilesPerDir, "Warn, or fail with --strict, when more files target one directory (0 disables)")
+	fs.StringVar(&cfg.Parser, "parser", cfg.Parser, "Parser for the files in the response: json, or fenced-files for Markdown code blocks with file names")
+	fs.BoolVar(&cfg.NormalizePaths, "normalize-paths", cfg.NormalizePaths, "Convert backslash separators in generated file names to the OS separator")
+	fs.BoolVar(&cfg.JSONSchemaStrict, "json-schema-strict", cfg.JSONSchemaStrict, "Fail when a generated file is missing its name or source code instead of warning")
+	fs.Var(&listFlag{values: &cfg.APIKeys, split: true}, "keys", "Comma-separated API keys to rotate through when one hits a rate limit")
+	fs.StringVar(&cfg.Report, "report", cfg.Report, "Write a self-contained HTML report of the prompt, model and generated files to this file")
+	fs.Var(&listFlag{values: &cfg.Attachments}, "attach", "Image or PDF to send alongside the prompt (repeatable)")
+	fs.BoolVar(&cfg.LintPrompt, "lint-prompt", cfg.LintPrompt, "Suggest improvements to vague prompts before generating; fails the run with --strict")
+	fs.StringVar(&cfg.FallbackModel, "fallback-model", cfg.FallbackModel, "Model to retry with once when the model is overloaded or unavailable")
+	fs.BoolVar(&cfg.AllOrNothing, "all-or-nothing", cfg.AllOrNothing, "Write the files to a staging directory and move them into the output directory only if every file succeeds")
+	fs.BoolVar(&cfg.NoExpand, "no-expand", cfg.NoExpand, "Take paths literally instead of expanding environment variables such as $HOME in them")
+	fs.BoolVar(&cfg.ShowDiffStat, "show-diff-stat", cfg.ShowDiffStat, "Print a git-style diffstat of the lines added and removed in each file")
+	fs.StringVar(&cfg.BatchDir, "batch-dir", cfg.BatchDir, "Directory of prompt files (.txt, .md, .prompt), each generated into a subdirectory of the output directory")
+	fs.BoolVar(&cfg.Resume, "resume", cfg.Resume, "Skip the prompts of the batch completed by an earlier run")
+	fs.StringVar(&cfg.ModelConfig, "model-config", cfg.ModelConfig, "JSON file with generation settings: temperature, top_p, top_k, max_output_tokens, stop_sequences, candidate_count")
+	fs.Var(&listFlag{values: &cfg.StopSequences}, "stop", "Sequence that ends the response (repeatable, at most 5)")
+	fs.BoolVar(&cfg.Trace, "trace", cfg.Trace, "Export OpenTelemetry spans of the generation, parsing and writing over OTLP/HTTP")
+	fs.StringVar(&cfg.TraceEndpoint, "trace-endpoint", cfg.TraceEndpoint, "OTLP/HTTP endpoint URL for --trace, such as http://localhost:4318 (defaults to OTEL_EXPORTER_OTLP_ENDPOINT)")
+	fs.BoolVar(&cfg.Clipboard, "clipboard", cfg.Clipboard, "Read the prompt from the system clipboard instead of stdin")
+	fs.BoolVar(&cfg.Cache, "cache", cfg.Cache, "Reuse cached responses for repeated requests and leave identical files untouched, so unchanged reruns change nothing")
+	fs.StringVar(&cfg.CacheDir, "cache-dir", cfg.CacheDir, "Directory the responses are cached in")
+	fs.IntVar(&cfg.MaxLineLength, "max-line-length", cfg.MaxLineLength, "Warn, or fail with --strict, about lines of generated code longer than this (0 disables)")
+	fs.StringVar(&cfg.FIFO, "fifo", cfg.FIFO, "Serve prompts written to this named pipe, one per line, each into a numbered subdirectory of the output directory")
+	fs.BoolVar(&cfg.FailIfEmpty, "fail-if-empty", cfg.FailIfEmpty, "Exit with an error when the response contains no files")
+	fs.StringVar(&cfg.Language, "language", cfg.Language, "Language whose formatter is enabled even without --format-code: auto to detect it from the file extensions, none, or go, python, javascript, typescript, rust or css")
+	fs.StringVar(&cfg.Owner, "owner", cfg.Owner, "Change the owner of the written files to uid:gid or user:group (Unix, requires privileges)")
+	fs.BoolVar(&cfg.SummarizeContext, "summarize-context", cfg.SummarizeContext, "Summarize large context files with a preliminary request and include the summaries instead")
+	fs.IntVar(&cfg.SummaryMaxChars, "summary-max-chars", cfg.SummaryMaxChars, "Maximum size in characters of a context summary; larger context files are summarized")
+	fs.BoolVar(&cfg.KeepTemp, "keep-temp", cfg.KeepTemp, "Keep the staging directory of --all-or-nothing and print its location")
+	fs.StringVar(&cfg.ResponseLog, "response-log", cfg.ResponseLog, "Append every raw response with its metadata to this file")
+	fs.Int64Var(&cfg.ResponseLogMaxSize, "response-log-max-size", cfg.ResponseLogMaxSize, "Rotate the response log to <file>.1 once it would exceed this many bytes (0 disables)")
+	fs.StringVar(&cfg.OutputZip, "output-zip", cfg.OutputZip, "Also write the generated files into this zip archive")
+	fs.BoolVar(&cfg.Stdout, "stdout", cfg.Stdout, "Also emit the generated files to stdout as JSON, logging everything else to stderr")
+	fs.IntVar(&cfg.MinFiles, "min-files", cfg.MinFiles, "Exit with an error when fewer than this many files are generated (0 disables)")
+	fs.IntVar(&cfg.Retries, "retries", cfg.Retries, "Retry requests this many times when the model is overloaded or unavailable")
+	fs.StringVar(&cfg.RetryStrategy, "retry-strategy", cfg.RetryStrategy, "Growth of the delay between retries: exponential, linear or constant")
+	fs.IntVar(&cfg.RetryBaseDelay, "retry-base-delay", cfg.RetryBaseDelay, "Milliseconds to wait before the first retry")
+	fs.IntVar(&cfg.RetryMaxDelay, "retry-max-delay", cfg.RetryMaxDelay, "Maximum milliseconds to wait between retries (0 uses the default of 30 seconds)")
+	fs.BoolVar(&cfg.GoVet, "go-vet", cfg.GoVet, "Run go vet on the generated Go code in the output directory and report its findings")
+	fs.BoolVar(&cfg.FailOnVet, "fail-on-vet", cfg.FailOnVet, "Exit with an error when go vet reports problems")
+	fs.BoolVar(&cfg.Edit, "edit", cfg.Edit, "Compose the prompt in $VISUAL or $EDITOR instead of typing it on stdin")
+	fs.BoolVar(&cfg.StreamWrite, "stream-write", cfg.StreamWrite, "With --stream, write each file as soon as it has been received completely")
+	fs.BoolVar(&cfg.DedupeContent, "dedupe-identical-content", cfg.DedupeContent, "Report generated files with the same content as an earlier file")
+	fs.StringVar(&cfg.DedupeAction, "dedupe-action", cfg.DedupeAction, "What to do with files of duplicate content: warn, skip, or symlink to the first file")
+	fs.StringVar(&cfg.Mode, "mode", cfg.Mode, "generate new code, or modify the files of --context-dir and return only the changed ones")
+	fs.Var(&cfg.DirMode, "dir-mode", "Permissions of created output directories in octal, such as 0750; the umask still applies")
+	fs.StringVar(&cfg.Concat, "concat", cfg.Concat, "Write all generated files into this single file, separated by path headers, instead of the output directory")
+	fs.BoolVar(&cfg.ContextGitDiff, "context-git-diff", cfg.ContextGitDiff, "Only include the context files changed since --context-git-base, or the current directory without --context-dir")
+	fs.StringVar(&cfg.ContextGitBase, "context-git-base", cfg.ContextGitBase, "Git ref the changes of --context-git-diff are relative to")
+	fs.Var(&listFlag{values: &cfg.CompareModels, split: true}, "models", "Comma-separated models the compare subcommand sends the prompt to")
+	fs.IntVar(&cfg.MaxConcurrency, "max-concurrency", cfg.MaxConcurrency, "Maximum number of models the compare subcommand queries at once")
+	fs.BoolVar(&cfg.SortFiles, "sort-files", cfg.SortFiles, "Write the files and the manifest in order of their paths for stable output")
+	fs.BoolVar(&cfg.PreserveOrder, "preserve-order", cfg.PreserveOrder, "Keep the order of the files in the response, overriding --sort-files")
+	fs.BoolVar(&cfg.PromptInclude, "prompt-include", cfg.PromptInclude, "Replace @path references in the prompt with the content of the file; write \\@ for a literal @")
+	fs.IntVar(&cfg.PromptIncludeMaxChars, "prompt-include-max-chars", cfg.PromptIncludeMaxChars, "Maximum characters included through @path references (0 disables)")
+	fs.Float64Var(&cfg.MaxCost, "max-cost", cfg.MaxCost, "Abort before a request whose estimated cost would bring the run over this many US dollars (0 disables)")
+	fs.BoolVar(&cfg.Validate, "validate", cfg.Validate, "Check that generated .json, .yaml and .yml files are well-formed")
+	fs.BoolVar(&cfg.FailOnInvalid, "fail-on-invalid", cfg.FailOnInvalid, "Exit with an error when a generated file does not parse, including Go files; implies --validate")
+	fs.StringVar(&cfg.Plugin, "plugin", cfg.Plugin, "WebAssembly plugin that renames, rewrites or rejects each generated file")
+	fs.BoolVar(&cfg.PromptCompress, "prompt-compress", cfg.PromptCompress, "Collapse blank lines and trailing whitespace in the request to save tokens; context and @file included files are kept as they are")
+	fs.StringVar(&cfg.ValidateSchema, "validate-schema", cfg.ValidateSchema, "Validate the response against this JSON Schema (draft-07) file before writing and report every violation")
+	fs.BoolVar(&cfg.OutputGitBranch, "output-git-branch", cfg.OutputGitBranch, "Write the files on a new git branch named after the prompt, commit them there and check out the current branch again")
+	fs.IntVar(&cfg.CacheTTL, "cache-ttl", cfg.CacheTTL, "Seconds after which cached responses expire (0 keeps them until cleared with the cache clear subcommand)")
+	fs.BoolVar(&cfg.AbortOnBinaryOutput, "abort-on-binary-output", cfg.AbortOnBinaryOutput, "Abort the write when a generated file looks like binary data instead of skipping it with a warning")
+	fs.BoolVar(&cfg.Verbose, "verbose", cfg.Verbose, "Print the time taken to write, validate and format each file and include it in the jsonl output")
+	fs.BoolVar(&cfg.ConfirmDestructive, "confirm-destructive", cfg.ConfirmDestructive, "Refuse to write to / or $HOME and ask before overwriting files in a non-empty directory outside a git repository")
+	fs.BoolVar(&cfg.Yes, "yes", cfg.Yes, "Confirm overwriting files with --confirm-destructive without asking")
+	fs.BoolVar(&cfg.FunctionCalling, "function-calling", cfg.FunctionCalling, "Let the model create, delete and rename files by calling functions; deletions and renames are listed for confirmation")
+	fs.Var(&listFlag{values: &cfg.FileFunctions, split: true}, "file-functions", "Comma-separated file functions offered with --function-calling: createFile, deleteFile, renameFile")
+	fs.Float64Var(&cfg.DailyBudget, "daily-budget", cfg.DailyBudget, "Refuse requests that would bring the cost of all runs of the day over this many US dollars (0 disables)")
+	fs.StringVar(&cfg.BudgetFile, "budget-file", cfg.BudgetFile, "File the spend of the day is kept in for --daily-budget (defaults to the user configuration directory)")
+	fs.StringVar(&cfg.BudgetTimezone, "budget-timezone", cfg.BudgetTimezone, "Time zone whose midnight resets the daily budget, such as UTC or Europe/Berlin")
+	fs.Var(&listFlag{values: &cfg.ContextPriority, split: true}, "context-priority", "Comma-separated globs of the context files --auto-trim drops last, most important first")
+	fs.IntVar(&cfg.Timeout, "timeout", cfg.Timeout, "Seconds the generation may take, requires --stream; on timeout the files completed so far are written and the run exits with status 3 (0 disables)")
+	fs.BoolVar(&cfg.StripComments, "strip-comments", cfg.StripComments, "Remove comments from generated Go, C-like and script files, keeping license and provenance headers and Go directives")
+	fs.BoolVar(&cfg.SemanticDiff, "semantic-diff", cfg.SemanticDiff, "Report the top-level declarations added, removed or modified in existing Go files")
+	fs.BoolVar(&cfg.Readonly, "readonly", cfg.Readonly, "Make the written files read-only (0444) to discourage manual edits; they are made writable again when regenerated")
+	fs.BoolVar(&cfg.PromptExpandEnv, "prompt-expand-env", cfg.PromptExpandEnv, "Replace ${VAR} references in the prompt with the value of the environment variable; unset variables are left as they are")
+}
+
+// decodeConfig decodes JSON settings into cfg. Settings that are missing from
+// data keep their current values, and unknown settings are rejected.
+func decodeConfig(data []byte, cfg *Config) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(cfg)
+}
+
+// loadConfigFile decodes the JSON config file at path into cfg and then applies
+// the named profile from it, if any.
+func loadConfigFile(path, profile string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := decodeConfig(data, cfg); err != nil {
+		return err
+	}
+	if profile == "" {
+		return nil
+	}
+	settings, ok := cfg.Profiles[profile]
+	if !ok {
+		return fmt.Errorf("profile %q not found", profile)
+	}
+	if err := decodeConfig(settings, cfg); err != nil {
+		return fmt.Errorf("profile %q: %w", profile, err)
+	}
+	return nil
+}
+
+// parseConfig resolves the configuration for a run from args. The flags are
+// parsed twice: once to find the config file, profile and spec file, and
+// again on top of the values loaded from them so that explicit flags take
+// precedence. The optional extra function registers subcommand-specific
+// flags. The remaining positional arguments are returned alongside the
+// configuration.
+func parseConfig(args []string, extra func(fs *flag.FlagSet)) (Config, []string, error) {
+	var src configSource
+	probe := DefaultConfig()
+	pre := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	registerFlags(pre, &probe, &src)
+	if extra != nil {
+		extra(pre)
+	}
+	pre.Parse(args)
+	if !probe.NoExpand {
+		src.Path = os.ExpandEnv(src.Path)
+		src.Spec = os.ExpandEnv(src.Spec)
+	}
+
+	cfg := DefaultConfig()
+	if src.Path != "" {
+		if err := loadConfigFile(src.Path, src.Profile, &cfg); err != nil {
+			return cfg, nil, fmt.Errorf("reading config file %s: %w", src.Path, err)
+		}
+	} else if src.Profile != "" {
+		return cfg, nil, fmt.Errorf("profile %q requires a config file", src.Profile)
+	}
+	if src.Spec != "" {
+		if err := loadSpecFile(src.Spec, &cfg); err != nil {
+			return cfg, nil, fmt.Errorf("reading spec file %s: %w", src.Spec, err)
+		}
+	}
+
+	fs := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	registerFlags(fs, &cfg, &src)
+	if extra != nil {
+		extra(fs)
+	}
+	fs.Parse(args)
+	if !cfg.NoExpand {
+		cfg.expandPaths()
+	}
+	// Without streaming no file is complete before the whole response is, so
+	// a timeout would only ever fail the run
+	if cfg.Timeout > 0 && !cfg.Stream {
+		return cfg, nil, errors.New("--timeout requires --stream")
+	}
+	// Repeated cached runs should leave the output untouched
+	if cfg.Cache {
+		cfg.SkipIdentical = true
+	}
+	return cfg, fs.Args(), nil
+}
+
+// sortFiles reports whether the files are written in order of their paths
+// rather than in the order of the response.
+func (cfg Config) sortFiles() bool {
+	return cfg.SortFiles && !cfg.PreserveOrder
+}
+
+// expandPaths replaces references to environment variables, such as $HOME or
+// ${PROJECT}, in the settings that hold paths. The prompt is never expanded.
+func (cfg *Config) expandPaths() {
+	for _, path := range []*string{&cfg.OutputDir, &cfg.ContextDir, &cfg.BatchDir, &cfg.FIFO, &cfg.CacheDir, &cfg.DebugDump, &cfg.HistoryFile, &cfg.ResponseLog, &cfg.OutputZip, &cfg.Concat, &cfg.Report, &cfg.CACert, &cfg.ModelConfig, &cfg.Plugin, &cfg.ValidateSchema, &cfg.BudgetFile} {
+		*path = os.ExpandEnv(*path)
+	}
+	for i, path := range cfg.Attachments {
+		cfg.Attachments[i] = os.ExpandEnv(path)
+	}
+}
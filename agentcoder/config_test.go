@@ -0,0 +1,128 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeConfig writes a config file with the given JSON content to a temporary
+// directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseConfigPromptAffixes(t *testing.T) {
+	path := writeConfig(t, `{"prompt_prefix": "Use Go.", "prompt_suffix": "Add tests."}`)
+	cfg, _, err := parseConfig([]string{"-config", path, "-prompt-suffix", "Add benchmarks."}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.PromptPrefix != "Use Go." {
+		t.Errorf("PromptPrefix = %q, want the value of the config file", cfg.PromptPrefix)
+	}
+	if cfg.PromptSuffix != "Add benchmarks." {
+		t.Errorf("PromptSuffix = %q, want the value of the flag", cfg.PromptSuffix)
+	}
+}
+
+func TestParseConfigProfile(t *testing.T) {
+	path := writeConfig(t, `{
+		"model": "base-model",
+		"output": "base",
+		"profiles": {
+			"strict": {"model": "strict-model", "safety": "low", "temperature": 0.1, "output": "strict", "prompt_prefix": "Be careful."}
+		}
+	}`)
+	tests := []struct {
+		name       string
+		args       []string
+		wantModel  string
+		wantOutput string
+	}{
+		{name: "no profile", args: []string{"-config", path}, wantModel: "base-model", wantOutput: "base"},
+		{name: "profile", args: []string{"-config", path, "-profile", "strict"}, wantModel: "strict-model", wantOutput: "strict"},
+		{name: "flag overrides profile", args: []string{"-config", path, "-profile", "strict", "-model", "flag-model"}, wantModel: "flag-model", wantOutput: "strict"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _, err := parseConfig(tt.args, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Model != tt.wantModel || cfg.OutputDir != tt.wantOutput {
+				t.Errorf("model %q, output %q; want %q, %q", cfg.Model, cfg.OutputDir, tt.wantModel, tt.wantOutput)
+			}
+		})
+	}
+
+	cfg, _, err := parseConfig([]string{"-config", path, "-profile", "strict"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Safety != "low" || cfg.Temperature == nil || *cfg.Temperature != 0.1 || cfg.PromptPrefix != "Be careful." {
+		t.Errorf("profile settings not applied: safety %q, temperature %v, prefix %q", cfg.Safety, cfg.Temperature, cfg.PromptPrefix)
+	}
+}
+
+func TestParseConfigUnknownProfile(t *testing.T) {
+	path := writeConfig(t, `{"profiles": {"work": {}}}`)
+	if _, _, err := parseConfig([]string{"-config", path, "-profile", "home"}, nil); err == nil {
+		t.Error("unknown profile accepted")
+	}
+	if _, _, err := parseConfig([]string{"-profile", "work"}, nil); err == nil {
+		t.Error("profile without a config file accepted")
+	}
+}
+
+func TestParseConfigTimeoutRequiresStream(t *testing.T) {
+	if _, _, err := parseConfig([]string{"-timeout", "5"}, nil); err == nil || err.Error() != "--timeout requires --stream" {
+		t.Errorf("parseConfig() error = %v, want --timeout without --stream rejected", err)
+	}
+	if _, _, err := parseConfig([]string{"-config", writeConfig(t, `{"timeout": 5}`)}, nil); err == nil {
+		t.Error("timeout without stream in the config file accepted")
+	}
+	for _, args := range [][]string{
+		{"-timeout", "5", "-stream"},
+		{"-config", writeConfig(t, `{"timeout": 5, "stream": true}`)},
+		{"-timeout", "0"},
+	} {
+		if _, _, err := parseConfig(args, nil); err != nil {
+			t.Errorf("parseConfig(%v) error = %v", args, err)
+		}
+	}
+}
+
+func TestParseConfigExpandsEnv(t *testing.T) {
+	t.Setenv("AGENT_CODER_TEST_ROOT", "/tmp/project")
+	path := writeConfig(t, `{"context_dir": "${AGENT_CODER_TEST_ROOT}/src"}`)
+	t.Setenv("AGENT_CODER_TEST_CONFIG", path)
+	args := []string{"-config", "$AGENT_CODER_TEST_CONFIG", "-output", "$AGENT_CODER_TEST_ROOT/gen", "-prompt-prefix", "Print $AGENT_CODER_TEST_ROOT"}
+
+	cfg, _, err := parseConfig(args, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OutputDir != "/tmp/project/gen" {
+		t.Errorf("OutputDir = %q, want /tmp/project/gen", cfg.OutputDir)
+	}
+	if cfg.ContextDir != "/tmp/project/src" {
+		t.Errorf("ContextDir = %q, want /tmp/project/src", cfg.ContextDir)
+	}
+	if cfg.PromptPrefix != "Print $AGENT_CODER_TEST_ROOT" {
+		t.Errorf("PromptPrefix = %q, want it unexpanded", cfg.PromptPrefix)
+	}
+
+	cfg, _, err = parseConfig([]string{"-no-expand", "-output", "$AGENT_CODER_TEST_ROOT/gen"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.OutputDir != "$AGENT_CODER_TEST_ROOT/gen" {
+		t.Errorf("OutputDir with -no-expand = %q, want it unexpanded", cfg.OutputDir)
+	}
+}
@@ -0,0 +1,144 @@
+package agentcoder
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// parseSince interprets the --context-since value, which is either a duration
+// relative to now (e.g. "24h") or an RFC 3339 timestamp or date.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid duration or timestamp %q", value)
+}
+
+// loadContext reads the text files under dir to include in the prompt. Hidden
+// files and directories are ignored, and when since is not zero, so are files
+// last modified before it. If include is not nil, only the files whose slash
+// separated path relative to dir it contains are read. The number of files
+// skipped because of their modification time is returned alongside the files.
+func loadContext(dir string, since time.Time, include map[string]bool) ([]File, int, error) {
+	var files []File
+	skipped := 0
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name = filepath.ToSlash(name)
+		if include != nil && !include[name] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if !since.IsZero() && info.ModTime().Before(since) {
+			skipped++
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if !utf8.Valid(data) {
+			return nil
+		}
+		files = append(files, File{Name: name, Code: string(data)})
+		return nil
+	})
+	return files, skipped, err
+}
+
+// gitChangedFiles returns the files under dir that differ from the git ref
+// base, including untracked files, by slash separated path relative to dir.
+func gitChangedFiles(cfg Config, dir, base string) (map[string]bool, error) {
+	if _, err := runCommand(cfg.commandTimeout(), dir, "git", "rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository", dir)
+	}
+	diff, err := runCommand(cfg.commandTimeout(), dir, "git", "diff", "--name-only", "--relative", base, "--")
+	if err != nil {
+		return nil, fmt.Errorf("git diff: %w: %s", err, strings.TrimSpace(string(diff)))
+	}
+	untracked, err := runCommand(cfg.commandTimeout(), dir, "git", "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("git ls-files: %w: %s", err, strings.TrimSpace(string(untracked)))
+	}
+	changed := make(map[string]bool)
+	for _, line := range strings.Split(string(diff)+"\n"+string(untracked), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			changed[line] = true
+		}
+	}
+	return changed, nil
+}
+
+// readContext loads the context files configured in cfg and reports how many
+// were included. With --context-git-diff only the files changed since the
+// base ref are included, from the current directory if no context directory
+// is set; outside a git repository it falls back to the whole context
+// directory.
+func readContext(cfg Config) ([]File, error) {
+	var include map[string]bool
+	if cfg.ContextGitDiff {
+		hasDir := cfg.ContextDir != ""
+		if !hasDir {
+			cfg.ContextDir = "."
+		}
+		changed, err := gitChangedFiles(cfg, cfg.ContextDir, cfg.ContextGitBase)
+		switch {
+		case err == nil:
+			include = changed
+			fmt.Printf("Including only the %d file(s) changed since %s as context\n", len(changed), cfg.ContextGitBase)
+		case !hasDir:
+			fmt.Printf("Warning: no changed files for context: %v\n", err)
+			return nil, nil
+		default:
+			fmt.Printf("Warning: including every file of %s as context: %v\n", cfg.ContextDir, err)
+		}
+	}
+	if cfg.ContextDir == "" {
+		return nil, nil
+	}
+	var since time.Time
+	if cfg.ContextSince != "" {
+		var err error
+		if since, err = parseSince(cfg.ContextSince, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	files, skipped, err := loadContext(cfg.ContextDir, since, include)
+	if err != nil {
+		return nil, fmt.Errorf("reading context: %w", err)
+	}
+	if since.IsZero() {
+		fmt.Printf("Included %d context file(s)\n", len(files))
+	} else {
+		fmt.Printf("Included %d context file(s), skipped %d not modified since %s\n", len(files), skipped, since.Format(time.RFC3339))
+	}
+	return files, nil
+}
@@ -0,0 +1,171 @@
+package agentcoder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"24h", now.Add(-24 * time.Hour)},
+		{"2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{"2024-05-01T08:30:00Z", time.Date(2024, 5, 1, 8, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := parseSince(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := parseSince("yesterday", now); err == nil {
+		t.Error("parseSince accepted an invalid value")
+	}
+}
+
+func TestLoadContextSince(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	for name, age := range map[string]time.Duration{"old.go": 48 * time.Hour, "sub/recent.go": time.Hour, "new.go": 0} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, skipped, err := loadContext(dir, now.Add(-24*time.Hour), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	if len(names) != 2 || names[0] != "new.go" || names[1] != "sub/recent.go" || skipped != 1 {
+		t.Errorf("included %v, skipped %d; want new.go and sub/recent.go, 1 skipped", names, skipped)
+	}
+
+	files, skipped, err = loadContext(dir, time.Time{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 || skipped != 0 {
+		t.Errorf("without since included %d, skipped %d; want 3, 0", len(files), skipped)
+	}
+}
+
+// gitRepo returns a new git repository with the files committed. Tests using
+// it are skipped without git.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(dir))
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	writeTree(t, dir, files)
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=Test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "Initial commit"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %s: %v: %s", strings.Join(args, " "), err, out)
+		}
+	}
+	return dir
+}
+
+// writeTree writes the files, by slash separated path, into dir.
+func writeTree(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestContextGitDiff(t *testing.T) {
+	dir := gitRepo(t, map[string]string{
+		"main.go":     "package main\n",
+		"pkg/a.go":    "package pkg\n",
+		"pkg/b.go":    "package pkg\n",
+		"README.md":   "# Project\n",
+		"pkg/old.txt": "old\n",
+	})
+	writeTree(t, dir, map[string]string{
+		"pkg/b.go":   "package pkg\n\nfunc B() {}\n",
+		"pkg/new.go": "package pkg\n\nfunc New() {}\n",
+	})
+	if err := os.Remove(filepath.Join(dir, "pkg", "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		contextDir string
+		want       []string
+	}{
+		{dir, []string{"pkg/b.go", "pkg/new.go"}},
+		{filepath.Join(dir, "pkg"), []string{"b.go", "new.go"}},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.ContextDir = tt.contextDir
+		cfg.ContextGitDiff = true
+		var files []File
+		var err error
+		out := captureStdout(t, func() { files, err = readContext(cfg) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fileNames(files); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("context of %s = %v, want %v", tt.contextDir, got, tt.want)
+		}
+		if !strings.Contains(out, "changed since HEAD") {
+			t.Errorf("output %q does not report the changed files", out)
+		}
+	}
+}
+
+func TestContextGitDiffOutsideRepository(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	cfg := DefaultConfig()
+	cfg.ContextDir = t.TempDir()
+	cfg.ContextGitDiff = true
+	t.Setenv("GIT_CEILING_DIRECTORIES", filepath.Dir(cfg.ContextDir))
+	writeTree(t, cfg.ContextDir, map[string]string{"a.go": "package a\n", "b.go": "package b\n"})
+	var files []File
+	var err error
+	out := captureStdout(t, func() { files, err = readContext(cfg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fileNames(files); !reflect.DeepEqual(got, []string{"a.go", "b.go"}) {
+		t.Errorf("context = %v, want every file", got)
+	}
+	if !strings.Contains(out, "is not in a git repository") {
+		t.Errorf("output %q does not warn about the missing repository", out)
+	}
+}
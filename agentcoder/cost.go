@@ -0,0 +1,109 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// errCostCeiling is returned for requests refused because of --max-cost.
+var errCostCeiling = errors.New("cost ceiling reached")
+
+// costTracker keeps the cost of the requests of a run below a ceiling, and the
+// cost of all runs of the day within the daily budget. It is shared by all
+// generators of the run and safe for concurrent use.
+type costTracker struct {
+	mu    sync.Mutex
+	max   float64 // Ceiling in US dollars, 0 for none
+	spent float64 // Cost of the replies received so far
+
+	// daily, if not nil, is the budget of the day
+	daily *dailyBudget
+}
+
+// newCostTracker returns the cost tracker enforcing the --max-cost and
+// --daily-budget of cfg, or nil if neither is set. It refuses to start once the
+// budget of the day is used up.
+func newCostTracker(cfg Config) (*costTracker, error) {
+	if cfg.MaxCost <= 0 && cfg.DailyBudget <= 0 {
+		return nil, nil
+	}
+	c := &costTracker{max: cfg.MaxCost}
+	if cfg.DailyBudget > 0 {
+		var err error
+		if c.daily, err = newDailyBudget(cfg); err != nil {
+			return nil, err
+		}
+		if err := c.daily.start(); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// reserve refuses a request of parts to model, which has the given name, if
+// its estimated cost would bring the run over the ceiling. The estimate is the
+// cost of the prompt tokens and, if the output is limited, of the maximum
+// number of output tokens.
+func (c *costTracker) reserve(ctx context.Context, model *genai.GenerativeModel, name string, parts []genai.Part) error {
+	price, ok := lookupPrice(name)
+	if !ok {
+		return fmt.Errorf("no price known for model %s to enforce --max-cost or --daily-budget", name)
+	}
+	resp, err := model.CountTokens(ctx, parts...)
+	if err != nil {
+		return fmt.Errorf("counting tokens for the cost limits: %w", err)
+	}
+	estimate := float64(resp.TotalTokens) * price.Input / 1e6
+	if model.MaxOutputTokens != nil {
+		estimate += float64(*model.MaxOutputTokens) * price.Output / 1e6
+	}
+	return c.check(name, estimate)
+}
+
+// check refuses a request to the named model estimated to cost estimate if it
+// would bring the run over the ceiling or the day over the daily budget.
+func (c *costTracker) check(name string, estimate float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.max > 0 && c.spent+estimate > c.max {
+		return fmt.Errorf("%w: the next request to %s would cost about $%.4f, bringing the run from $%.4f over the limit of $%.4f", errCostCeiling, name, estimate, c.spent, c.max)
+	}
+	if c.daily != nil {
+		spent, err := c.daily.spent()
+		if err != nil {
+			return err
+		}
+		if spent+estimate > c.daily.limit {
+			return fmt.Errorf("%w: the next request to %s would cost about $%.4f, bringing the day from $%.4f over the budget of $%.2f", errDailyBudget, name, estimate, spent, c.daily.limit)
+		}
+	}
+	return nil
+}
+
+// add records the cost of a reply of the named model and reports the running
+// total.
+func (c *costTracker) add(name string, usage *genai.UsageMetadata) {
+	price, ok := lookupPrice(name)
+	if !ok || usage == nil {
+		return
+	}
+	cost := (float64(usage.PromptTokenCount)*price.Input + float64(usage.CandidatesTokenCount)*price.Output) / 1e6
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spent += cost
+	if c.max > 0 {
+		fmt.Printf("Run cost so far: $%.4f of $%.4f\n", c.spent, c.max)
+	}
+	if c.daily != nil {
+		spent, err := c.daily.record(cost)
+		if err != nil {
+			fmt.Printf("Error recording the daily spend: %v\n", err)
+			return
+		}
+		fmt.Printf("Spent today: $%.4f of $%.2f, $%.4f remaining\n", spent, c.daily.limit, max(c.daily.limit-spent, 0))
+	}
+}
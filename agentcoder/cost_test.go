@@ -0,0 +1,62 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// tokenCount is the answer of the fake API to a token count.
+func tokenCount(tokens int) apiResponse {
+	return apiResponse{body: fmt.Sprintf(`{"totalTokens": %d}`, tokens)}
+}
+
+func TestMaxCostStopsChunkedRun(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Model = "gemini-2.5-pro"
+	cfg.Prompt = "Write three files."
+	cfg.NoRaw = true
+	cfg.Chunked = true
+	cfg.ChunkSize = 1
+	// Each request is estimated at $0.000125 and each reply costs
+	// $0.0002125, so the third request would bring the run to $0.00055
+	cfg.MaxCost = 0.0005
+	gen, api := newTestGenerator(t, cfg,
+		tokenCount(100),
+		textResponse(`[{"file_name": "a.go", "description": "A"}, {"file_name": "b.go", "description": "B"}, {"file_name": "c.go", "description": "C"}]`, "STOP"),
+		tokenCount(100),
+		textResponse(`[{"file_name": "a.go", "source_code": "package a"}]`, "STOP"),
+		tokenCount(100),
+	)
+	var err error
+	out := captureStdout(t, func() { _, err = generateFiles(context.Background(), cfg, gen) })
+	if !errors.Is(err, errCostCeiling) {
+		t.Fatalf("generateFiles() error = %v, want the cost ceiling", err)
+	}
+	if !strings.Contains(err.Error(), "generating batch 2") || !strings.Contains(err.Error(), "bringing the run from $0.0004 over the limit of $0.0005") {
+		t.Errorf("error %q does not explain which request was refused and why", err)
+	}
+	for _, want := range []string{"Run cost so far: $0.0002 of $0.0005", "Run cost so far: $0.0004 of $0.0005"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not report %q", out, want)
+		}
+	}
+	if api.requests() != 5 {
+		t.Errorf("%d requests, want no request after the refused token count", api.requests())
+	}
+}
+
+func TestMaxCostUnknownPrice(t *testing.T) {
+	api, client := newFakeAPI(t)
+	cfg := DefaultConfig()
+	cfg.Model = "unpriced-model"
+	cfg.MaxCost = 1
+	if _, err := newClientGenerator(client, cfg); err == nil || !strings.Contains(err.Error(), "no price known for model unpriced-model") {
+		t.Errorf("newClientGenerator() error = %v, want no price known", err)
+	}
+	if api.requests() != 0 {
+		t.Errorf("%d requests, want none", api.requests())
+	}
+}
@@ -0,0 +1,43 @@
+package agentcoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// validateData checks that a generated data file is well-formed for its
+// extension: .json files must hold a single JSON value and .yaml and .yml
+// files valid YAML documents. Files of other types are not checked.
+func validateData(name, content string) error {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		dec := json.NewDecoder(strings.NewReader(content))
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("%s: invalid JSON: %w", name, err)
+		}
+		if _, err := dec.Token(); err != io.EOF {
+			return fmt.Errorf("%s: invalid JSON: unexpected data after the top-level value", name)
+		}
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader([]byte(content)))
+		for {
+			var v any
+			err := dec.Decode(&v)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("%s: invalid YAML: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateData(t *testing.T) {
+	tests := []struct {
+		name, content string
+		want          string // Part of the error, empty if valid
+	}{
+		{"config.json", `{"port": 8080, "hosts": ["a", "b"]}`, ""},
+		{"config.json", `{"port": 8080,}`, "config.json: invalid JSON"},
+		{"config.json", `{"port": 8080} {"port": 8081}`, "unexpected data after the top-level value"},
+		{"config.json", ``, "config.json: invalid JSON"},
+		{"deploy.yaml", "name: app\nports:\n  - 80\n---\nname: db\n", ""},
+		{"deploy.yml", "name: app\n  ports: [80\n", "deploy.yml: invalid YAML"},
+		{"deploy.YAML", "key: [unclosed\n", "invalid YAML"},
+		{"empty.yaml", "", ""},
+		{"main.go", "not { valid json", ""},
+	}
+	for _, tt := range tests {
+		err := validateData(tt.name, tt.content)
+		if tt.want == "" && err != nil {
+			t.Errorf("validateData(%s, %q) = %v, want valid", tt.name, tt.content, err)
+		}
+		if tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)) {
+			t.Errorf("validateData(%s, %q) = %v, want %q", tt.name, tt.content, err, tt.want)
+		}
+	}
+}
+
+func TestValidateGeneratedDataFiles(t *testing.T) {
+	files := []File{
+		{Name: "ok.json", Code: `{"a": 1}`},
+		{Name: "bad.json", Code: `{"a": }`},
+		{Name: "bad.yaml", Code: "a: [1\n"},
+	}
+	tests := []struct {
+		name     string
+		validate bool
+		fail     bool
+		wantErr  bool
+		warnings int
+	}{
+		{"off", false, false, false, 0},
+		{"validate", true, false, false, 2},
+		{"fail-on-invalid", false, true, true, 2},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.OutputDir = "out"
+		cfg.Validate = tt.validate
+		cfg.FailOnInvalid = tt.fail
+		var err error
+		out := captureStdout(t, func() { err = writeFiles(newMemFS(), cfg, files) })
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: writeFiles() error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		if tt.wantErr && (err == nil || err.Error() != "2 file(s) are not well-formed") {
+			t.Errorf("%s: error = %v, want 2 file(s) are not well-formed", tt.name, err)
+		}
+		if got := strings.Count(out, "Warning: bad."); got != tt.warnings {
+			t.Errorf("%s: %d warnings in %q, want %d", tt.name, got, out, tt.warnings)
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Outcomes of handling a generated file whose content duplicates an earlier
+// one.
+const (
+	writeSkipped = "skipped"
+	writeLinked  = "linked"
+)
+
+// dedupeActions are the ways of handling files with duplicate content: warn
+// writes them anyway, skip leaves them out and symlink links them to the
+// first file with the same content.
+var dedupeActions = map[string]bool{"warn": true, "skip": true, "symlink": true}
+
+// findDuplicates returns, by name, the files whose content is identical to an
+// earlier file, mapped to the name of that file, and reports them. Files that
+// are empty or only hold whitespace, such as __init__.py or .gitkeep, are
+// meant to be alike and never count as duplicates.
+func findDuplicates(files []File) map[string]string {
+	first := make(map[string]string, len(files))
+	duplicates := make(map[string]string)
+	for _, file := range files {
+		if strings.TrimSpace(file.Code) == "" {
+			continue
+		}
+		hash := hashContent([]byte(file.Code))
+		if original, ok := first[hash]; ok {
+			fmt.Printf("Warning: %s has the same content as %s\n", file.Name, original)
+			duplicates[file.Name] = original
+			continue
+		}
+		first[hash] = file.Name
+	}
+	return duplicates
+}
+
+// writeDuplicate handles the i-th file, whose content is identical to the
+// already written file original, as configured by --dedupe-action. It reports
+// false if the file has to be written normally instead, which is the case
+// for symlinks on filesystems other than the OS one.
+func writeDuplicate(fsys FS, cfg Config, i int, file File, original string) (writeResult, bool) {
+	result := writeResult{Path: file.Name, Size: len(file.Code)}
+	switch cfg.DedupeAction {
+	case "skip":
+		fmt.Printf("\nFile %d: %s skipped, same content as %s\n", i+1, file.Name, original)
+		result.Status = writeSkipped
+		return result, true
+	case "symlink":
+		if _, ok := fsys.(osFS); !ok {
+			return result, false
+		}
+		if err := checkPath(file.Name, cfg.MaxDirDepth); err != nil {
+			return result, false
+		}
+		fullPath := filepath.Join(cfg.OutputDir, file.Name)
+		target, err := filepath.Rel(filepath.Dir(fullPath), filepath.Join(cfg.OutputDir, original))
+		if err == nil {
+			err = os.MkdirAll(filepath.Dir(fullPath), os.FileMode(cfg.DirMode))
+		}
+		if err == nil {
+			if err = os.Remove(fullPath); errors.Is(err, fs.ErrNotExist) {
+				err = nil
+			}
+		}
+		if err == nil {
+			err = os.Symlink(target, fullPath)
+		}
+		if err != nil {
+			fmt.Printf("Warning: could not link %s to %s, writing a copy: %v\n", file.Name, original, err)
+			return result, false
+		}
+		fmt.Printf("\nFile %d: %s linked to %s\n", i+1, file.Name, original)
+		result.Status = writeLinked
+		return result, true
+	}
+	return result, false
+}
@@ -0,0 +1,91 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindDuplicates(t *testing.T) {
+	files := []File{
+		{Name: "a/util.go", Code: "package util\n"},
+		{Name: "b/util.go", Code: "package util\n"},
+		{Name: "c/util.go", Code: "package util\n"},
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "a/__init__.py", Code: ""},
+		{Name: "b/__init__.py", Code: ""},
+		{Name: "a/.gitkeep", Code: "\n"},
+		{Name: "b/.gitkeep", Code: " \n\t"},
+	}
+	var got map[string]string
+	captureStdout(t, func() { got = findDuplicates(files) })
+	want := map[string]string{"b/util.go": "a/util.go", "c/util.go": "a/util.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findDuplicates() = %v, want %v", got, want)
+	}
+}
+
+func TestDedupeActions(t *testing.T) {
+	files := []File{
+		{Name: "a/util.go", Code: "package util\n"},
+		{Name: "b/util.go", Code: "package util\n"},
+		{Name: "a/__init__.py", Code: ""},
+		{Name: "b/__init__.py", Code: ""},
+	}
+	tests := []struct {
+		action string
+		status string
+	}{
+		{"warn", writeWritten},
+		{"skip", writeSkipped},
+		{"symlink", writeLinked},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.OutputDir = t.TempDir()
+		cfg.DedupeContent = true
+		cfg.DedupeAction = tt.action
+		var statuses map[string]string
+		captureStdout(t, func() { statuses = writeStatuses(t, osFS{}, cfg, files) })
+		want := map[string]string{
+			"a/util.go":     writeWritten,
+			"b/util.go":     tt.status,
+			"a/__init__.py": writeWritten,
+			"b/__init__.py": writeWritten,
+		}
+		if !reflect.DeepEqual(statuses, want) {
+			t.Errorf("%s: statuses = %v, want %v", tt.action, statuses, want)
+		}
+
+		dup := filepath.Join(cfg.OutputDir, "b", "util.go")
+		target, linkErr := os.Readlink(dup)
+		_, statErr := os.Stat(dup)
+		switch tt.action {
+		case "warn":
+			if linkErr == nil || statErr != nil {
+				t.Errorf("warn: b/util.go is not a regular file")
+			}
+		case "skip":
+			if statErr == nil {
+				t.Errorf("skip: b/util.go was written")
+			}
+		case "symlink":
+			if want := filepath.Join("..", "a", "util.go"); target != want {
+				t.Errorf("symlink: b/util.go links to %q, want %q", target, want)
+			}
+		}
+	}
+}
+
+func TestUnknownDedupeAction(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.DedupeContent = true
+	cfg.DedupeAction = "merge"
+	var err error
+	captureStdout(t, func() { err = writeFiles(osFS{}, cfg, []File{{Name: "a.go", Code: "package a\n"}}) })
+	if err == nil {
+		t.Error("writeFiles() succeeded, want an error for an unknown dedupe action")
+	}
+}
@@ -0,0 +1,77 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// dangerousDir reports why writing into dir is refused outright with
+// --confirm-destructive, or an empty string if it is not a dangerous target.
+func dangerousDir(dir string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return ""
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+	if abs == filepath.VolumeName(abs)+string(filepath.Separator) {
+		return "it is the root of the file system"
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		if resolved, err := filepath.EvalSymlinks(home); err == nil {
+			home = resolved
+		}
+		if abs == filepath.Clean(home) {
+			return "it is the home directory"
+		}
+	}
+	return ""
+}
+
+// checkDestructive guards the output directory with --confirm-destructive
+// before files are written. Dangerous targets such as / and $HOME are refused.
+// Otherwise, when the generated files would overwrite existing files in a
+// directory outside a git repository, which could not restore them, confirm
+// is asked with the directory and file count unless --yes is set. A nil
+// confirm, for input that is not a terminal, refuses.
+func checkDestructive(fsys FS, cfg Config, files []File, confirm func(question string) bool) error {
+	if !cfg.ConfirmDestructive {
+		return nil
+	}
+	if reason := dangerousDir(cfg.OutputDir); reason != "" {
+		return fmt.Errorf("refusing to write to %s: %s", cfg.OutputDir, reason)
+	}
+	if cfg.Yes {
+		return nil
+	}
+	entries, err := os.ReadDir(cfg.OutputDir)
+	if err != nil || len(entries) == 0 {
+		return nil
+	}
+	if _, err := cfg.git("rev-parse", "--is-inside-work-tree"); err == nil {
+		return nil
+	}
+	overwritten := 0
+	for _, file := range files {
+		if checkPath(file.Name, cfg.MaxDirDepth) != nil {
+			continue
+		}
+		if _, err := fsys.Stat(filepath.Join(cfg.OutputDir, file.Name)); err == nil {
+			overwritten++
+		}
+	}
+	if overwritten == 0 {
+		return nil
+	}
+	question := fmt.Sprintf("Overwrite %d existing file(s) in %s, which holds %d entries and is not in a git repository?", overwritten, cfg.OutputDir, len(entries))
+	if confirm == nil {
+		return fmt.Errorf("the files would overwrite %d existing file(s) in %s, which is not in a git repository; pass --yes to confirm", overwritten, cfg.OutputDir)
+	}
+	if !confirm(question) {
+		return errors.New("aborted")
+	}
+	return nil
+}
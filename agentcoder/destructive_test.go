@@ -0,0 +1,118 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckDestructiveDangerousTargets(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	link := filepath.Join(t.TempDir(), "home")
+	if err := os.Symlink(home, link); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		want string
+	}{
+		{"/", "it is the root of the file system"},
+		{home, "it is the home directory"},
+		{home + "/.", "it is the home directory"},
+		{link, "it is the home directory"},
+	}
+	for _, tt := range tests {
+		for _, yes := range []bool{false, true} {
+			cfg := DefaultConfig()
+			cfg.OutputDir = tt.dir
+			cfg.ConfirmDestructive = true
+			cfg.Yes = yes
+			confirm := func(string) bool {
+				t.Errorf("asked to confirm writing to %s", tt.dir)
+				return true
+			}
+			err := checkDestructive(osFS{}, cfg, []File{{Name: "main.go"}}, confirm)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("checkDestructive(%s) with yes %v = %v, want an error containing %q", tt.dir, yes, err, tt.want)
+			}
+		}
+	}
+
+	// Without --confirm-destructive nothing is checked
+	cfg := DefaultConfig()
+	cfg.OutputDir = "/"
+	if err := checkDestructive(osFS{}, cfg, []File{{Name: "main.go"}}, nil); err != nil {
+		t.Errorf("checkDestructive() without --confirm-destructive = %v", err)
+	}
+}
+
+func TestCheckDestructiveConfirmation(t *testing.T) {
+	t.Setenv("GIT_CEILING_DIRECTORIES", os.TempDir())
+	existing := map[string]string{"main.go": "package main\n", "README.md": "# Old\n", "notes.txt": "notes\n"}
+	files := []File{{Name: "main.go"}, {Name: "README.md"}, {Name: "new.go"}, {Name: "../escape.go"}}
+
+	tests := []struct {
+		name     string
+		existing map[string]string
+		yes      bool
+		answer   bool
+		noTTY    bool
+		asked    bool
+		wantErr  string
+	}{
+		{name: "empty directory", asked: false},
+		{name: "nothing overwritten", existing: map[string]string{"notes.txt": "notes\n"}, asked: false},
+		{name: "confirmed", existing: existing, answer: true, asked: true},
+		{name: "declined", existing: existing, asked: true, wantErr: "aborted"},
+		{name: "no terminal", existing: existing, noTTY: true, wantErr: "pass --yes to confirm"},
+		{name: "yes", existing: existing, yes: true, asked: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputDir = t.TempDir()
+			cfg.ConfirmDestructive = true
+			cfg.Yes = tt.yes
+			writeTree(t, cfg.OutputDir, tt.existing)
+
+			var questions []string
+			confirm := func(question string) bool {
+				questions = append(questions, question)
+				return tt.answer
+			}
+			if tt.noTTY {
+				confirm = nil
+			}
+			err := checkDestructive(osFS{}, cfg, files, confirm)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("checkDestructive() = %v, want error %q", err, tt.wantErr)
+			}
+			if asked := len(questions) > 0; asked != tt.asked {
+				t.Fatalf("asked %v, want asked %v", questions, tt.asked)
+			}
+			if tt.asked {
+				want := "Overwrite 2 existing file(s) in " + cfg.OutputDir + ", which holds 3 entries"
+				if !strings.HasPrefix(questions[0], want) {
+					t.Errorf("question = %q, want it to start with %q", questions[0], want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckDestructiveGitRepository(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	cfg := DefaultConfig()
+	cfg.OutputDir = dir
+	cfg.ConfirmDestructive = true
+	confirm := func(string) bool {
+		t.Error("asked to confirm overwriting files that git can restore")
+		return false
+	}
+	if err := checkDestructive(osFS{}, cfg, []File{{Name: "main.go"}}, confirm); err != nil {
+		t.Errorf("checkDestructive() in a git repository = %v", err)
+	}
+}
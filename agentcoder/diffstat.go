@@ -0,0 +1,96 @@
+package agentcoder
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table used to align two files. Larger
+// files are compared as multisets of lines instead, which can only
+// underestimate the changes of reordered lines.
+const maxDiffCells = 4 << 20
+
+// diffStat is the number of lines added to and removed from a file.
+type diffStat struct {
+	Name    string
+	Added   int
+	Removed int
+}
+
+// splitLines splits text into lines, without an empty last line for text
+// ending in a newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// countChanges returns the number of lines added and removed to turn old into
+// new, based on their longest common subsequence of lines.
+func countChanges(old, new string) (added, removed int) {
+	a, b := splitLines(old), splitLines(new)
+
+	// Lines shared at the start and end are unchanged
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	var common int
+	if len(a)*len(b) > maxDiffCells {
+		counts := make(map[string]int, len(a))
+		for _, line := range a {
+			counts[line]++
+		}
+		for _, line := range b {
+			if counts[line] > 0 {
+				counts[line]--
+				common++
+			}
+		}
+	} else {
+		prev := make([]int, len(b)+1)
+		cur := make([]int, len(b)+1)
+		for i := range a {
+			for j := range b {
+				if a[i] == b[j] {
+					cur[j+1] = prev[j] + 1
+				} else {
+					cur[j+1] = max(prev[j+1], cur[j])
+				}
+			}
+			prev, cur = cur, prev
+		}
+		common = prev[len(b)]
+	}
+	return len(b) - common, len(a) - common
+}
+
+// printDiffStat prints a git-style line per file with the number of changed
+// lines and a bar of + and -, followed by the totals.
+func printDiffStat(stats []diffStat) {
+	if len(stats) == 0 {
+		return
+	}
+	const maxBar = 50
+	width, most := 0, 0
+	for _, s := range stats {
+		width = max(width, len(s.Name))
+		most = max(most, s.Added+s.Removed)
+	}
+	added, removed := 0, 0
+	for _, s := range stats {
+		plus, minus := s.Added, s.Removed
+		if most > maxBar {
+			plus = (s.Added*maxBar + most - 1) / most
+			minus = (s.Removed*maxBar + most - 1) / most
+		}
+		fmt.Printf(" %-*s | %4d %s%s\n", width, s.Name, s.Added+s.Removed, strings.Repeat("+", plus), strings.Repeat("-", minus))
+		added += s.Added
+		removed += s.Removed
+	}
+	fmt.Printf(" %d file(s) changed, %d insertion(s)(+), %d deletion(s)(-)\n", len(stats), added, removed)
+}
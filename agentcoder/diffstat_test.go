@@ -0,0 +1,55 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCountChanges(t *testing.T) {
+	tests := []struct {
+		old, new       string
+		added, removed int
+	}{
+		{old: "", new: "a\nb\n", added: 2},
+		{old: "a\nb\n", new: "", removed: 2},
+		{old: "a\nb\nc\n", new: "a\nb\nc\n"},
+		{old: "a\nb\nc\n", new: "a\nB\nc\n", added: 1, removed: 1},
+		{old: "a\nb\nc\n", new: "a\nx\nb\nc\ny\n", added: 2},
+		{old: "a\nb\nc\nd\n", new: "b\nd\n", removed: 2},
+	}
+	for _, tt := range tests {
+		added, removed := countChanges(tt.old, tt.new)
+		if added != tt.added || removed != tt.removed {
+			t.Errorf("countChanges(%q, %q) = %d, %d, want %d, %d", tt.old, tt.new, added, removed, tt.added, tt.removed)
+		}
+	}
+}
+
+func TestShowDiffStat(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	cfg.ShowDiffStat = true
+	if err := writeFiles(fsys, cfg, []File{{Name: "main.go", Code: "package main\n\nfunc main() {\n}\n"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []File{
+		{Name: "main.go", Code: "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println()\n}\n"},
+		{Name: "util.go", Code: "package main\n"},
+	}
+	out := captureStdout(t, func() {
+		if err := writeFiles(fsys, cfg, files); err != nil {
+			t.Error(err)
+		}
+	})
+	for _, want := range []string{
+		" main.go |    3 +++\n",
+		" util.go |    1 +\n",
+		" 2 file(s) changed, 4 insertion(s)(+), 0 deletion(s)(-)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not contain %q", out, want)
+		}
+	}
+}
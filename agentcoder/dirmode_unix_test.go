@@ -0,0 +1,33 @@
+//go:build unix
+
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+func TestDirMode(t *testing.T) {
+	// The umask would clear bits of the configured mode
+	defer syscall.Umask(syscall.Umask(0))
+
+	cfg := DefaultConfig()
+	cfg.OutputDir = filepath.Join(t.TempDir(), "out")
+	cfg.DirMode = 0750
+	captureStdout(t, func() {
+		if err := writeFiles(osFS{}, cfg, []File{{Name: "cmd/tool/main.txt", Code: "hello"}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, dir := range []string{"", "cmd", "cmd/tool"} {
+		info, err := os.Stat(filepath.Join(cfg.OutputDir, dir))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mode := info.Mode().Perm(); mode != 0750 {
+			t.Errorf("mode of %q = %04o, want 0750", dir, mode)
+		}
+	}
+}
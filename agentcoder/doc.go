@@ -0,0 +1,4 @@
+// Package agentcoder generates source files from a prompt with a generative
+// model and writes them to a directory. It implements the agent_coder command
+// and can be embedded in other programs through Generate.
+package agentcoder
@@ -0,0 +1,48 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// promptEditor returns the command line of the editor prompts are composed
+// in, or an empty string if none is set. It is a variable so the editor can
+// be replaced.
+var promptEditor = func() string {
+	if editor := os.Getenv("VISUAL"); editor != "" {
+		return editor
+	}
+	return os.Getenv("EDITOR")
+}
+
+// editPrompt opens editor, a command line that may include arguments, on an
+// empty temporary file and returns what was saved in it. The run is cancelled
+// when the editor fails or the file is left empty.
+func editPrompt(editor string) (string, error) {
+	f, err := os.CreateTemp("", "agent_coder-prompt-*.txt")
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	f.Close()
+	defer os.Remove(path)
+
+	args := append(strings.Fields(editor), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor %s failed, cancelling: %w", args[0], err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", errors.New("the prompt is empty, cancelling")
+	}
+	return prompt, nil
+}
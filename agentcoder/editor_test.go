@@ -0,0 +1,89 @@
+package agentcoder
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEditor makes the editor a script running body with the file to edit
+// as $1 for the rest of the test.
+func fakeEditor(t *testing.T, body string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "editor.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	prev := promptEditor
+	promptEditor = func() string { return path }
+	t.Cleanup(func() { promptEditor = prev })
+}
+
+func TestEditPrompt(t *testing.T) {
+	fakeEditor(t, `printf 'Write a text editor in Go.\n\n' > "$1"`)
+	cfg := DefaultConfig()
+	cfg.Edit = true
+	prompt, err := userPrompt(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != "Write a text editor in Go." {
+		t.Errorf("userPrompt() = %q, want the saved file", prompt)
+	}
+}
+
+func TestEditPromptWithArguments(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "editor.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho \"$1\" > \"$2\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	prompt, err := editPrompt(path + " --wait")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != "--wait" {
+		t.Errorf("editPrompt() = %q, want the argument the editor was given", prompt)
+	}
+}
+
+func TestEditPromptCancelled(t *testing.T) {
+	tests := []struct {
+		name, body, want string
+	}{
+		{"editor fails", `echo 'Write a text editor.' > "$1"; exit 1`, "failed, cancelling"},
+		{"empty file", `printf '  \n' > "$1"`, "the prompt is empty, cancelling"},
+	}
+	for _, tt := range tests {
+		fakeEditor(t, tt.body)
+		cfg := DefaultConfig()
+		cfg.Edit = true
+		if _, err := userPrompt(cfg, nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: userPrompt() error = %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestEditPromptWithoutEditor(t *testing.T) {
+	prev := promptEditor
+	promptEditor = func() string { return "" }
+	t.Cleanup(func() { promptEditor = prev })
+
+	cfg := DefaultConfig()
+	cfg.Edit = true
+	scanner := bufio.NewScanner(strings.NewReader("Write a pager in Go.\n"))
+	var prompt string
+	var err error
+	out := captureStdout(t, func() { prompt, err = userPrompt(cfg, scanner) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt != "Write a pager in Go." {
+		t.Errorf("userPrompt() = %q, want the prompt from stdin", prompt)
+	}
+	if !strings.Contains(out, "reading the prompt from stdin") {
+		t.Errorf("output %q does not say the prompt is read from stdin", out)
+	}
+}
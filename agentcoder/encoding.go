@@ -0,0 +1,44 @@
+package agentcoder
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// outputEncodings maps the names accepted by --output-encoding to encodings.
+var outputEncodings = map[string]encoding.Encoding{
+	"utf-16le": unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM),
+	"utf-16be": unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM),
+	"latin1":   charmap.ISO8859_1,
+}
+
+// lookupEncoding returns the encoding named by --output-encoding, or nil for
+// UTF-8, which needs no transcoding.
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	name = strings.ToLower(name)
+	if name == "" || name == "utf-8" || name == "utf8" {
+		return nil, nil
+	}
+	enc, ok := outputEncodings[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported output encoding %q", name)
+	}
+	return enc, nil
+}
+
+// transcodeFile rewrites the UTF-8 file at path in fsys using enc.
+func transcodeFile(fsys FS, path string, enc encoding.Encoding) error {
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	encoded, err := enc.NewEncoder().Bytes(data)
+	if err != nil {
+		return err
+	}
+	return fsys.WriteFile(path, encoded, 0644)
+}
@@ -0,0 +1,45 @@
+package agentcoder
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutputEncoding(t *testing.T) {
+	tests := []struct {
+		encoding string
+		want     []byte
+	}{
+		{encoding: "utf-8", want: []byte("hé\n")},
+		{encoding: "utf-16le", want: []byte{'h', 0, 0xe9, 0, '\n', 0}},
+		{encoding: "UTF-16BE", want: []byte{0, 'h', 0, 0xe9, 0, '\n'}},
+		{encoding: "latin1", want: []byte{'h', 0xe9, '\n'}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			fsys := newMemFS()
+			cfg := DefaultConfig()
+			cfg.OutputDir = "out"
+			cfg.OutputEncoding = tt.encoding
+			if err := writeFiles(fsys, cfg, []File{{Name: "a.txt", Code: "hé\n"}}); err != nil {
+				t.Fatal(err)
+			}
+			got, err := fsys.ReadFile("out/a.txt")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("bytes = % x, want % x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnsupportedOutputEncoding(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	cfg.OutputEncoding = "ebcdic"
+	if err := writeFiles(newMemFS(), cfg, []File{{Name: "a.txt", Code: "a"}}); err == nil {
+		t.Error("unsupported encoding accepted")
+	}
+}
@@ -0,0 +1,76 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// modelPrice is the price of a model in US dollars per million tokens.
+type modelPrice struct {
+	Input  float64 `json:"input_per_million"`  // Price of a million prompt tokens
+	Output float64 `json:"output_per_million"` // Price of a million response tokens
+}
+
+// modelPrices are the published prices of the supported models. Models are
+// matched by prefix so dated versions share the price of their family.
+var modelPrices = map[string]modelPrice{
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.0-flash-lite": {Input: 0.075, Output: 0.30},
+	"gemini-2.0-flash":      {Input: 0.10, Output: 0.40},
+	"gemini-1.5-pro":        {Input: 1.25, Output: 5},
+	"gemini-1.5-flash":      {Input: 0.075, Output: 0.30},
+}
+
+// lookupPrice returns the price of the model, preferring the longest matching
+// prefix.
+func lookupPrice(model string) (modelPrice, bool) {
+	model = strings.TrimPrefix(model, "models/")
+	var best string
+	for name := range modelPrices {
+		if strings.HasPrefix(model, name) && len(name) > len(best) {
+			best = name
+		}
+	}
+	price, ok := modelPrices[best]
+	return price, ok
+}
+
+// planReport is the output of --plan-json.
+type planReport struct {
+	Config        Config      `json:"config"`                       // Resolved configuration, without the API key
+	PromptChars   int         `json:"prompt_chars"`                 // Length of the assembled prompt
+	PromptTokens  int32       `json:"prompt_tokens"`                // Tokens of the prompt as counted by the model
+	Price         *modelPrice `json:"price,omitempty"`              // Price of the model, if known
+	EstimatedCost *float64    `json:"estimated_cost_usd,omitempty"` // Cost of the prompt tokens in US dollars, if the price is known
+}
+
+// writePlanJSON assembles the prompt, counts its tokens and writes a report of
+// the expected cost to w. Nothing is generated or written to disk; counting
+// the tokens is the only API call, so context files are never summarized.
+func writePlanJSON(ctx context.Context, w io.Writer, cfg Config, counter tokenCounter) error {
+	// Keep the prompt and history files untouched
+	cfg.SavePrompt = false
+	cfg.HistoryFile = ""
+	req, err := readPrompt(ctx, cfg, nil)
+	if err != nil {
+		return err
+	}
+	tokens, err := counter.CountTokens(ctx, req.Text)
+	if err != nil {
+		return fmt.Errorf("counting tokens: %w", err)
+	}
+
+	report := planReport{Config: cfg, PromptChars: len(req.Text), PromptTokens: tokens}
+	if price, ok := lookupPrice(cfg.Model); ok {
+		cost := float64(tokens) * price.Input / 1e6
+		report.Price = &price
+		report.EstimatedCost = &cost
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
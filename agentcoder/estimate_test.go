@@ -0,0 +1,81 @@
+package agentcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLookupPrice(t *testing.T) {
+	tests := []struct {
+		model string
+		want  modelPrice
+		ok    bool
+	}{
+		{model: "gemini-2.0-flash", want: modelPrice{Input: 0.10, Output: 0.40}, ok: true},
+		{model: "models/gemini-2.0-flash-001", want: modelPrice{Input: 0.10, Output: 0.40}, ok: true},
+		{model: "gemini-2.0-flash-lite-001", want: modelPrice{Input: 0.075, Output: 0.30}, ok: true},
+		{model: "unknown-model"},
+	}
+	for _, tt := range tests {
+		got, ok := lookupPrice(tt.model)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("lookupPrice(%q) = %v, %v, want %v, %v", tt.model, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPlanJSON(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIKey = "secret-api-key"
+	cfg.Model = "gemini-2.0-flash"
+	cfg.Prompt = strings.Repeat("a", 4000)
+	cfg.OutputDir = t.TempDir()
+
+	var buf bytes.Buffer
+	if err := writePlanJSON(context.Background(), &buf, cfg, &countingGenerator{}); err != nil {
+		t.Fatal(err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("plan is not JSON: %v\n%s", err, buf.String())
+	}
+	tokens, ok := report["prompt_tokens"].(float64)
+	if !ok || tokens < 1000 {
+		t.Errorf("prompt_tokens = %v, want at least 1000", report["prompt_tokens"])
+	}
+	if cost, ok := report["estimated_cost_usd"].(float64); !ok || cost != tokens*0.10/1e6 {
+		t.Errorf("estimated_cost_usd = %v, want %v", report["estimated_cost_usd"], tokens*0.10/1e6)
+	}
+	if _, ok := report["config"]; !ok {
+		t.Error("plan has no config")
+	}
+	if strings.Contains(buf.String(), cfg.APIKey) {
+		t.Error("plan contains the API key")
+	}
+	if entries, _ := os.ReadDir(cfg.OutputDir); len(entries) != 0 {
+		t.Errorf("plan wrote %d file(s)", len(entries))
+	}
+}
+
+func TestPlanJSONUnknownPrice(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Model = "unknown-model"
+	cfg.Prompt = "Write a tool."
+	cfg.OutputDir = t.TempDir()
+
+	var buf bytes.Buffer
+	if err := writePlanJSON(context.Background(), &buf, cfg, &countingGenerator{}); err != nil {
+		t.Fatal(err)
+	}
+	var report map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := report["estimated_cost_usd"]; ok {
+		t.Error("plan estimates the cost of a model without a price")
+	}
+}
@@ -0,0 +1,52 @@
+package agentcoder
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// normalizeExt returns ext lower-cased and with a leading dot, so ".SH", "sh"
+// and ".sh" all match the same files.
+func normalizeExt(ext string) string {
+	ext = strings.ToLower(strings.TrimSpace(ext))
+	if ext != "" && !strings.HasPrefix(ext, ".") {
+		ext = "." + ext
+	}
+	return ext
+}
+
+// extAllowed reports whether a file with extension ext may be written. With an
+// allow list only the listed extensions are permitted; the deny list is
+// checked afterwards. Files without an extension match the empty string.
+func extAllowed(ext string, allowed, denied []string) bool {
+	has := func(list []string) bool {
+		return slices.ContainsFunc(list, func(e string) bool { return normalizeExt(e) == ext })
+	}
+	if len(allowed) > 0 && !has(allowed) {
+		return false
+	}
+	return !has(denied)
+}
+
+// filterExtensions drops the files whose extension is not permitted by the
+// allowed and denied lists, warning about each one. In strict mode a single
+// disallowed file fails the whole run instead.
+func filterExtensions(files []File, allowed, denied []string, strict bool) ([]File, error) {
+	if len(allowed) == 0 && len(denied) == 0 {
+		return files, nil
+	}
+	kept := files[:0:0]
+	for _, file := range files {
+		if extAllowed(strings.ToLower(filepath.Ext(file.Name)), allowed, denied) {
+			kept = append(kept, file)
+			continue
+		}
+		if strict {
+			return nil, fmt.Errorf("file %s has a disallowed extension", file.Name)
+		}
+		fmt.Printf("Warning: skipping %s, its extension is not allowed\n", file.Name)
+	}
+	return kept, nil
+}
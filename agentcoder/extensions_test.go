@@ -0,0 +1,49 @@
+package agentcoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+// fileNames returns the names of the files.
+func fileNames(files []File) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names
+}
+
+func TestFilterExtensions(t *testing.T) {
+	files := []File{{Name: "main.go"}, {Name: "run.SH"}, {Name: "Makefile"}, {Name: "tool.exe"}}
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		want    []string
+	}{
+		{name: "default", want: []string{"main.go", "run.SH", "Makefile", "tool.exe"}},
+		{name: "allow only", allowed: []string{"go", ".sh"}, want: []string{"main.go", "run.SH"}},
+		{name: "allow files without extension", allowed: []string{".go", ""}, want: []string{"main.go", "Makefile"}},
+		{name: "deny only", denied: []string{".sh", "EXE"}, want: []string{"main.go", "Makefile"}},
+		{name: "allow and deny", allowed: []string{".go", ".sh"}, denied: []string{".sh"}, want: []string{"main.go"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kept, err := filterExtensions(files, tt.allowed, tt.denied, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fileNames(kept); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("kept %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterExtensionsStrict(t *testing.T) {
+	files := []File{{Name: "main.go"}, {Name: "run.sh"}}
+	if _, err := filterExtensions(files, nil, []string{".sh"}, true); err == nil {
+		t.Error("denied extension accepted in strict mode")
+	}
+}
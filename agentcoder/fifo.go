@@ -0,0 +1,63 @@
+package agentcoder
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// runFIFO serves prompts written to the named pipe at path, one per line,
+// until ctx is cancelled. The files of each prompt are written into their own
+// numbered subdirectory of the output directory. The pipe is created if it
+// does not exist and reopened whenever its writer disconnects, so editors and
+// other tools can send requests to a single long-lived process.
+func runFIFO(ctx context.Context, cfg Config, gen *modelGenerator, path string) error {
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := mkfifo(path); err != nil {
+			return fmt.Errorf("creating FIFO: %w", err)
+		}
+	}
+	fmt.Printf("Waiting for prompts on %s\n", path)
+
+	// Connect as a writer once cancelled to unblock a pending open
+	go func() {
+		<-ctx.Done()
+		if w, err := os.OpenFile(path, os.O_WRONLY, 0); err == nil {
+			w.Close()
+		}
+	}()
+
+	n := 0
+	for ctx.Err() == nil {
+		// Opening blocks until a writer connects
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 64*1024), 4<<20)
+		for scanner.Scan() && ctx.Err() == nil {
+			prompt := strings.TrimSpace(scanner.Text())
+			if prompt == "" {
+				continue
+			}
+			n++
+			dir := filepath.Join(cfg.OutputDir, fmt.Sprintf("request-%03d", n))
+			fmt.Printf("\n=== Request %d ===\n", n)
+			if err := generateInto(ctx, cfg, gen, prompt, dir); err != nil {
+				fmt.Printf("Error: request %d: %v\n", n, err)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("reading FIFO: %w", err)
+		}
+	}
+	return ctx.Err()
+}
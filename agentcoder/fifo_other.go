@@ -0,0 +1,10 @@
+//go:build !unix
+
+package agentcoder
+
+import "errors"
+
+// mkfifo creates a named pipe at path, which is only supported on Unix.
+func mkfifo(path string) error {
+	return errors.New("named pipes are only supported on Unix")
+}
@@ -0,0 +1,69 @@
+//go:build unix
+
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFIFOServesPrompts(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	path := filepath.Join(t.TempDir(), "prompts")
+	if err := mkfifo(path); err != nil {
+		t.Fatal(err)
+	}
+	gen, api := newTestGenerator(t, cfg,
+		textResponse(`[{"file_name": "one.go", "source_code": "package one\n"}]`, "STOP"),
+		textResponse(`[{"file_name": "two.go", "source_code": "package two\n"}]`, "STOP"),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- runFIFO(ctx, cfg, gen, path) }()
+
+	// Each prompt comes from its own writer, so the pipe is reopened
+	for _, prompt := range []string{"Write package one.\n", "\nWrite package two.\n"} {
+		w, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.WriteString(prompt); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+	}
+
+	second := filepath.Join(cfg.OutputDir, "request-002", "two.go")
+	for deadline := time.Now().Add(10 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(second); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s not written", second)
+		}
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("runFIFO() = %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("runFIFO did not stop when cancelled")
+	}
+
+	if api.requests() != 2 {
+		t.Errorf("%d requests, want 2", api.requests())
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "request-001", "one.go")); err != nil {
+		t.Error(err)
+	}
+}
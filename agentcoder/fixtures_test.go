@@ -0,0 +1,124 @@
+package agentcoder
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// TestResponseFixtures runs every recorded response in testdata/responses
+// through parsing, filtering and writing, as the replay subcommand does.
+func TestResponseFixtures(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    map[string]string // Substring expected in each written file
+		wantErr string
+	}{
+		{
+			fixture: "clean.json",
+			want: map[string]string{
+				"main.go":   `fmt.Println("Hello, world!")`,
+				"README.md": "Prints a greeting.",
+			},
+		},
+		{
+			fixture: "fenced.json",
+			want: map[string]string{
+				"main.go":   `fmt.Println("Hello, world!")`,
+				"README.md": "Prints a greeting.",
+			},
+		},
+		{
+			fixture: "fenced-files.json",
+			want: map[string]string{
+				"main.go":           "greet()",
+				"internal/greet.go": `fmt.Println("hi")`,
+				"notes/TODO.md":     "- more greetings",
+			},
+		},
+		{
+			fixture: "multi-part.json",
+			want: map[string]string{
+				"cmd/server/main.go":          "handler.Serve()",
+				"internal/handler/handler.go": "package handler",
+				"go.mod":                      "module example.com/app",
+			},
+		},
+		{
+			fixture: "text.json",
+			want:    map[string]string{"main.go": "func main() {}"},
+		},
+		{
+			fixture: "truncated.json",
+			wantErr: "unexpected end of JSON input",
+		},
+	}
+
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "responses", "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var covered []string
+	for _, tt := range tests {
+		covered = append(covered, filepath.Join("testdata", "responses", tt.fixture))
+	}
+	sort.Strings(fixtures)
+	sort.Strings(covered)
+	if !reflect.DeepEqual(fixtures, covered) {
+		t.Fatalf("fixtures = %v, test table covers %v", fixtures, covered)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			dump, err := loadDump(filepath.Join("testdata", "responses", tt.fixture))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := DefaultConfig()
+			cfg.OutputDir = "out"
+			dump.apply(&cfg)
+
+			files, err := filesFromText(cfg, dump.Text)
+			if err == nil {
+				files = transformFiles(cfg, files)
+				files, err = filterFiles(cfg, files)
+			}
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			fsys := newMemFS()
+			if err := writeFiles(fsys, cfg, files); err != nil {
+				t.Fatal(err)
+			}
+			var want []string
+			for name := range tt.want {
+				want = append(want, filepath.Join("out", name))
+			}
+			sort.Strings(want)
+			if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+				t.Fatalf("written paths = %v, want %v", got, want)
+			}
+			for name, substr := range tt.want {
+				data, err := fsys.ReadFile(filepath.Join("out", name))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.Contains(string(data), substr) {
+					t.Errorf("%s = %q, want it to contain %q", name, data, substr)
+				}
+				if strings.Contains(string(data), "```") {
+					t.Errorf("%s still contains a code fence: %q", name, data)
+				}
+			}
+		})
+	}
+}
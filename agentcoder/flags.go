@@ -0,0 +1,78 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listFlag is a repeatable flag collecting its values into a slice. When split
+// is set, each value may also hold several comma-separated entries. Values
+// given on the command line replace the ones from the config file rather than
+// adding to them.
+type listFlag struct {
+	values *[]string
+	split  bool
+	set    bool
+}
+
+func (f *listFlag) String() string {
+	if f.values == nil {
+		return ""
+	}
+	return strings.Join(*f.values, ",")
+}
+
+func (f *listFlag) Set(value string) error {
+	if !f.set {
+		*f.values = nil
+		f.set = true
+	}
+	if !f.split {
+		*f.values = append(*f.values, value)
+		return nil
+	}
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			*f.values = append(*f.values, entry)
+		}
+	}
+	return nil
+}
+
+// fileMode is a permission mode given in octal, such as 0750, on the command
+// line and in config files.
+type fileMode os.FileMode
+
+// parseFileMode parses an octal permission mode, rejecting anything beyond
+// the permission bits.
+func parseFileMode(value string) (fileMode, error) {
+	mode, err := strconv.ParseUint(value, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid mode %q, expected octal permissions such as 0755", value)
+	}
+	return fileMode(mode), nil
+}
+
+func (m fileMode) String() string { return fmt.Sprintf("%04o", uint32(m)) }
+
+func (m *fileMode) Set(value string) error {
+	mode, err := parseFileMode(value)
+	if err != nil {
+		return err
+	}
+	*m = mode
+	return nil
+}
+
+func (m fileMode) MarshalJSON() ([]byte, error) { return json.Marshal(m.String()) }
+
+func (m *fileMode) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	return m.Set(value)
+}
@@ -0,0 +1,53 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFileMode(t *testing.T) {
+	tests := []struct {
+		value string
+		want  fileMode
+		ok    bool
+	}{
+		{"0755", 0755, true},
+		{"750", 0750, true},
+		{"0700", 0700, true},
+		{"0", 0, true},
+		{"0777", 0777, true},
+		{"1777", 0, false},
+		{"0855", 0, false},
+		{"rwxr-x---", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, err := parseFileMode(tt.value)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("parseFileMode(%q) = %v, %v, want %v and ok %v", tt.value, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDirModeConfig(t *testing.T) {
+	cfg, _, err := parseConfig([]string{"-dir-mode", "0750"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DirMode != 0750 {
+		t.Errorf("DirMode = %v, want 0750", cfg.DirMode)
+	}
+
+	path := writeConfig(t, `{"dir_mode": "0700"}`)
+	if cfg, _, err = parseConfig([]string{"-config", path}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DirMode != 0700 {
+		t.Errorf("DirMode from the config file = %v, want 0700", cfg.DirMode)
+	}
+
+	path = writeConfig(t, `{"dir_mode": "999"}`)
+	if _, _, err := parseConfig([]string{"-config", path}, nil); err == nil || !strings.Contains(err.Error(), "invalid mode") {
+		t.Errorf("parseConfig() error = %v, want an invalid mode", err)
+	}
+}
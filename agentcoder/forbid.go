@@ -0,0 +1,73 @@
+package agentcoder
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// forbiddenMatch describes where a forbidden pattern matched a generated file.
+type forbiddenMatch struct {
+	File    string // Name of the file
+	Line    int    // Line of the match, starting at 1
+	Pattern string // Pattern that matched
+}
+
+func (m forbiddenMatch) String() string {
+	return fmt.Sprintf("%s:%d matches forbidden pattern %q", m.File, m.Line, m.Pattern)
+}
+
+// findForbidden returns the first match of any of the patterns in the file.
+func findForbidden(file File, patterns []*regexp.Regexp) (forbiddenMatch, bool) {
+	for i, line := range strings.Split(file.Code, "\n") {
+		for _, re := range patterns {
+			if re.MatchString(line) {
+				return forbiddenMatch{File: file.Name, Line: i + 1, Pattern: re.String()}, true
+			}
+		}
+	}
+	return forbiddenMatch{}, false
+}
+
+// compileForbidden compiles the forbidden patterns.
+func compileForbidden(patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid forbidden pattern: %w", err)
+		}
+		res[i] = re
+	}
+	return res, nil
+}
+
+// filterForbidden checks the generated files against the forbidden patterns.
+// With action "abort" the first match fails the whole write; with "skip" the
+// matching files are dropped with a warning.
+func filterForbidden(files []File, patterns []string, action string) ([]File, error) {
+	if len(patterns) == 0 {
+		return files, nil
+	}
+	if action != "abort" && action != "skip" {
+		return nil, fmt.Errorf("unknown forbid action %q", action)
+	}
+	res, err := compileForbidden(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := files[:0:0]
+	for _, file := range files {
+		m, found := findForbidden(file, res)
+		if !found {
+			kept = append(kept, file)
+			continue
+		}
+		if action == "abort" {
+			return nil, fmt.Errorf("aborting write: %v", m)
+		}
+		fmt.Printf("Warning: skipping %v\n", m)
+	}
+	return kept, nil
+}
@@ -0,0 +1,46 @@
+package agentcoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilterForbiddenAbort(t *testing.T) {
+	files := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "config.go", Code: "package main\n\nconst host = \"db.corp.internal\"\n"},
+	}
+	_, err := filterForbidden(files, []string{`\.corp\.internal`}, "abort")
+	if err == nil {
+		t.Fatal("forbidden pattern did not abort the write")
+	}
+	if want := `config.go:3 matches forbidden pattern "\\.corp\\.internal"`; !strings.Contains(err.Error(), want) {
+		t.Errorf("error = %q, want it to contain %q", err, want)
+	}
+}
+
+func TestFilterForbiddenSkip(t *testing.T) {
+	files := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "config.go", Code: "const host = \"db.corp.internal\"\n"},
+		{Name: "notes.md", Code: "TODO: darn\n"},
+	}
+	kept, err := filterForbidden(files, []string{`corp\.internal`, `(?i)darn`}, "skip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fileNames(kept), []string{"main.go"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+}
+
+func TestFilterForbiddenInvalid(t *testing.T) {
+	files := []File{{Name: "main.go", Code: "package main\n"}}
+	if _, err := filterForbidden(files, []string{"("}, "abort"); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+	if _, err := filterForbidden(files, []string{"x"}, "ignore"); err == nil {
+		t.Error("unknown action accepted")
+	}
+}
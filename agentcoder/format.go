@@ -0,0 +1,97 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// defaultFormatters maps file extensions to the external formatter command run
+// on files with that extension. The path of the file is appended as the last
+// argument. Go files are formatted in-process unless ".go" is mapped here.
+func defaultFormatters() map[string]string {
+	return map[string]string{
+		".js":  "prettier --write",
+		".ts":  "prettier --write",
+		".css": "prettier --write",
+		".py":  "black -q",
+		".rs":  "rustfmt",
+	}
+}
+
+// formatFile formats the file at path in fsys according to its extension.
+// Files without a configured formatter are left untouched. External formatters
+// can only be run on the OS filesystem and are killed after timeout.
+func formatFile(fsys FS, formatters map[string]string, path string, timeout time.Duration) error {
+	ext := strings.ToLower(filepath.Ext(path))
+	command := formatters[ext]
+	if command == "" {
+		if ext == ".go" {
+			return gofmtFile(fsys, path)
+		}
+		return nil
+	}
+	if _, ok := fsys.(osFS); !ok {
+		return errors.New("external formatters require the OS filesystem")
+	}
+	args := strings.Fields(command)
+	out, err := runCommand(timeout, "", append(args, path)...)
+	if errors.Is(err, errCommandTimeout) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// gofmtFile rewrites the Go source file at path in canonical gofmt style.
+func gofmtFile(fsys FS, path string) error {
+	src, err := fsys.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	formatted, err := format.Source(src)
+	if err != nil {
+		return err
+	}
+	return fsys.WriteFile(path, formatted, 0644)
+}
+
+// formatContent returns code as the formatter for path would leave it, without
+// touching the file at path. External formatters are run on a temporary copy
+// next to path, so that they pick up the same project settings.
+func formatContent(fsys FS, formatters map[string]string, path, code string, timeout time.Duration) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	if formatters[ext] == "" {
+		if ext != ".go" {
+			return code, nil
+		}
+		formatted, err := format.Source([]byte(code))
+		return string(formatted), err
+	}
+	if _, ok := fsys.(osFS); !ok {
+		return "", errors.New("external formatters require the OS filesystem")
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".agent_coder_format_*"+ext)
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.WriteString(code)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := formatFile(fsys, formatters, tmp.Name(), timeout); err != nil {
+		return "", err
+	}
+	formatted, err := os.ReadFile(tmp.Name())
+	return string(formatted), err
+}
@@ -0,0 +1,63 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeFormatter writes a formatter script that uppercases the file it is given
+// and returns its path.
+func fakeFormatter(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "upper.sh")
+	script := "#!/bin/sh\ntr a-z A-Z < \"$1\" > \"$1.tmp\" && mv \"$1.tmp\" \"$1\"\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFormatFile(t *testing.T) {
+	formatters := map[string]string{".txt": fakeFormatter(t), ".md": "false"}
+	tests := []struct {
+		name    string
+		code    string
+		want    string
+		wantErr bool
+	}{
+		{name: "notes.txt", code: "hello", want: "HELLO"},
+		{name: "data.csv", code: "a,b", want: "a,b"},
+		{name: "main.go", code: "package main\nfunc  main( ) {}\n", want: "package main\n\nfunc main() {}\n"},
+		{name: "README.md", code: "# Title", want: "# Title", wantErr: true},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name)
+		if err := os.WriteFile(path, []byte(tt.code), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := formatFile(osFS{}, formatters, path, time.Minute); (err != nil) != tt.wantErr {
+			t.Errorf("formatFile(%s) error = %v, want error %v", tt.name, err, tt.wantErr)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("%s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFormattersFromConfigFile(t *testing.T) {
+	path := writeConfig(t, `{"formatters": {".txt": "upper"}}`)
+	cfg, _, err := parseConfig([]string{"-config", path}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Formatters[".txt"] != "upper" {
+		t.Errorf("Formatters = %v, want the .txt formatter of the config file", cfg.Formatters)
+	}
+}
@@ -0,0 +1,69 @@
+package agentcoder
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWriteFilesToMemFS(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	files := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "internal/util/util.go", Code: "package util\n"},
+		{Name: "README.md", Code: "# Readme\n"},
+	}
+	if err := writeFiles(fsys, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join("out", "README.md"),
+		filepath.Join("out", "internal", "util", "util.go"),
+		filepath.Join("out", "main.go"),
+	}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths() = %v, want %v", got, want)
+	}
+	for _, file := range files {
+		data, err := fsys.ReadFile(filepath.Join("out", file.Name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != file.Code {
+			t.Errorf("%s = %q, want %q", file.Name, data, file.Code)
+		}
+	}
+	info, err := fsys.Stat(filepath.Join("out", "internal"))
+	if err != nil || !info.IsDir() {
+		t.Errorf("Stat(out/internal) = %v, %v; want a directory", info, err)
+	}
+}
+
+func TestMemFSErrors(t *testing.T) {
+	fsys := newMemFS()
+	if err := fsys.WriteFile("missing/a.txt", nil, 0644); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("writing into a missing directory: %v, want ErrNotExist", err)
+	}
+	if err := fsys.MkdirAll("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("dir/a.txt", []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.MkdirAll("dir/a.txt/b", 0755); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("creating a directory below a file: %v, want ErrExist", err)
+	}
+	if err := fsys.WriteFile("dir", nil, 0644); err == nil {
+		t.Error("writing over a directory succeeded")
+	}
+	if _, err := fsys.ReadFile("dir/b.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("reading a missing file: %v, want ErrNotExist", err)
+	}
+	if got := fsys.Paths(); !reflect.DeepEqual(got, []string{filepath.Join("dir", "a.txt")}) {
+		t.Errorf("Paths() = %v, want only dir/a.txt", got)
+	}
+}
@@ -0,0 +1,296 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// Names of the file operation functions the model can call with
+// --function-calling.
+const (
+	fnCreateFile = "createFile"
+	fnDeleteFile = "deleteFile"
+	fnRenameFile = "renameFile"
+)
+
+// fileFunctions declares the file operation functions, in the order they are
+// offered to the model.
+var fileFunctions = []*genai.FunctionDeclaration{
+	{
+		Name:        fnCreateFile,
+		Description: "Create a file, or replace it if it exists, with the given content",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"path":    {Type: genai.TypeString, Description: "Path of the file relative to the output directory"},
+				"content": {Type: genai.TypeString, Description: "Complete content of the file"},
+			},
+			Required: []string{"path", "content"},
+		},
+	},
+	{
+		Name:        fnDeleteFile,
+		Description: "Delete an existing file",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"path": {Type: genai.TypeString, Description: "Path of the file relative to the output directory"},
+			},
+			Required: []string{"path"},
+		},
+	},
+	{
+		Name:        fnRenameFile,
+		Description: "Rename or move an existing file",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"from": {Type: genai.TypeString, Description: "Current path of the file relative to the output directory"},
+				"to":   {Type: genai.TypeString, Description: "New path of the file relative to the output directory"},
+			},
+			Required: []string{"from", "to"},
+		},
+	},
+}
+
+// fileFunctionArgs are the arguments of each file operation function, in the
+// order parseFunctionCalls reads them.
+var fileFunctionArgs = map[string][]string{
+	fnCreateFile: {"path", "content"},
+	fnDeleteFile: {"path"},
+	fnRenameFile: {"from", "to"},
+}
+
+// fileTool returns the tool declaring the allowed file operation functions.
+func fileTool(allowed []string) (*genai.Tool, error) {
+	tool := &genai.Tool{}
+	for _, name := range allowed {
+		i := slices.IndexFunc(fileFunctions, func(f *genai.FunctionDeclaration) bool { return f.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown file function %q, expected %s, %s or %s", name, fnCreateFile, fnDeleteFile, fnRenameFile)
+		}
+		tool.FunctionDeclarations = append(tool.FunctionDeclarations, fileFunctions[i])
+	}
+	if len(tool.FunctionDeclarations) == 0 {
+		return nil, errors.New("--function-calling needs at least one file function")
+	}
+	return tool, nil
+}
+
+// functionCall is a function call of the model as recorded in the text of a
+// reply.
+type functionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+// encodeFunctionCalls returns the text of a reply consisting of function
+// calls: a JSON array of the calls, so that it can be logged, cached and
+// dumped like any other reply.
+func encodeFunctionCalls(calls []genai.FunctionCall) string {
+	encoded := make([]functionCall, len(calls))
+	for i, call := range calls {
+		encoded[i] = functionCall{Name: call.Name, Args: call.Args}
+	}
+	data, _ := json.Marshal(encoded)
+	return string(data)
+}
+
+// fileOperation is a deletion or rename requested by the model.
+type fileOperation struct {
+	Function string // fnDeleteFile or fnRenameFile
+	Path     string // File deleted or renamed
+	To       string // New name of a renamed file
+}
+
+func (op fileOperation) String() string {
+	if op.Function == fnRenameFile {
+		return fmt.Sprintf("rename %s to %s", op.Path, op.To)
+	}
+	return "delete " + op.Path
+}
+
+// parseFunctionCalls reads the function calls from the text of a reply and
+// returns the files to create and the other operations. Calls of functions
+// that are not allowed are rejected.
+func parseFunctionCalls(text string, allowed []string) ([]File, []fileOperation, error) {
+	var calls []functionCall
+	if err := json.Unmarshal([]byte(text), &calls); err != nil {
+		return nil, nil, fmt.Errorf("the response contains no function calls: %w", err)
+	}
+	var files []File
+	var ops []fileOperation
+	for i, call := range calls {
+		if !slices.Contains(allowed, call.Name) {
+			return nil, nil, fmt.Errorf("call %d: function %q is not allowed", i+1, call.Name)
+		}
+		args := make([]string, 2)
+		for j, name := range fileFunctionArgs[call.Name] {
+			value, ok := call.Args[name].(string)
+			if !ok {
+				return nil, nil, fmt.Errorf("call %d: %s needs a string argument %q", i+1, call.Name, name)
+			}
+			args[j] = value
+		}
+		if call.Name == fnCreateFile {
+			files = append(files, File{Name: args[0], Code: args[1]})
+			continue
+		}
+		op := fileOperation{Function: call.Name, Path: args[0]}
+		if call.Name == fnRenameFile {
+			op.To = args[1]
+		}
+		ops = append(ops, op)
+	}
+	return files, ops, nil
+}
+
+// applyFileOperations deletes and renames files in the output directory.
+// Every path must stay inside the directory, a deleted or renamed file must
+// exist and a rename may not replace another file. Each operation is checked
+// against the directory as it was before the batch, so a batch may touch
+// every path only once. The source and target of
+// each operation must pass the filters of generated files: the ignore file,
+// the allowed and denied extensions and, with the content of the file, the
+// forbidden patterns. The operations are listed and confirm is asked unless
+// --yes is set; a nil confirm, for input that is not a terminal, refuses.
+func applyFileOperations(cfg Config, ops []fileOperation, confirm func(question string) bool) error {
+	if len(ops) == 0 {
+		return nil
+	}
+	rules, err := loadIgnore(cfg.OutputDir)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", ignoreFileName, err)
+	}
+	forbidden, err := compileForbidden(cfg.ForbidPatterns)
+	if err != nil {
+		return err
+	}
+	touched := make(map[string]bool)
+	for _, op := range ops {
+		for _, name := range []string{op.Path, op.To} {
+			if name == "" {
+				continue
+			}
+			if err := checkPath(name, cfg.MaxDirDepth); err != nil {
+				return fmt.Errorf("refusing to %v: %w", op, err)
+			}
+			clean := filepath.Clean(name)
+			if touched[clean] {
+				return fmt.Errorf("refusing to %v: %s is already changed by an earlier operation", op, name)
+			}
+			touched[clean] = true
+		}
+		info, err := os.Lstat(filepath.Join(cfg.OutputDir, op.Path))
+		if err != nil {
+			return fmt.Errorf("cannot %v: %w", op, err)
+		}
+		if !info.Mode().IsRegular() {
+			return fmt.Errorf("refusing to %v: not a regular file", op)
+		}
+		if op.To != "" {
+			if _, err := os.Lstat(filepath.Join(cfg.OutputDir, op.To)); err == nil {
+				return fmt.Errorf("refusing to %v: %s exists", op, op.To)
+			}
+		}
+		code, err := os.ReadFile(filepath.Join(cfg.OutputDir, op.Path))
+		if err != nil {
+			return fmt.Errorf("cannot %v: %w", op, err)
+		}
+		for _, name := range []string{op.Path, op.To} {
+			if name == "" {
+				continue
+			}
+			if err := checkOperationFile(cfg, rules, forbidden, File{Name: name, Code: string(code)}); err != nil {
+				return fmt.Errorf("refusing to %v: %w", op, err)
+			}
+		}
+	}
+
+	fmt.Printf("\nThe model requested %d file operation(s) in %s:\n", len(ops), cfg.OutputDir)
+	for _, op := range ops {
+		fmt.Printf("  %v\n", op)
+	}
+	if !cfg.Yes {
+		if confirm == nil {
+			return errors.New("file operations not confirmed; pass --yes to allow them")
+		}
+		if !confirm("Apply these operations?") {
+			return errors.New("aborted")
+		}
+	}
+
+	for _, op := range ops {
+		path := filepath.Join(cfg.OutputDir, op.Path)
+		var err error
+		if op.Function == fnRenameFile {
+			to := filepath.Join(cfg.OutputDir, op.To)
+			if err = os.MkdirAll(filepath.Dir(to), os.FileMode(cfg.DirMode)); err == nil {
+				err = os.Rename(path, to)
+			}
+		} else {
+			err = os.Remove(path)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot %v: %w", op, err)
+		}
+		fmt.Printf("Done: %v\n", op)
+	}
+	return nil
+}
+
+// checkOperationFile applies the filters of generated files to a file deleted
+// or renamed by the model, as it is before the operation or, for the target of
+// a rename, would be after.
+func checkOperationFile(cfg Config, rules []ignoreRule, forbidden []*regexp.Regexp, file File) error {
+	if !extAllowed(strings.ToLower(filepath.Ext(file.Name)), cfg.AllowedExtensions, cfg.DeniedExtensions) {
+		return fmt.Errorf("%s has a disallowed extension", file.Name)
+	}
+	if isIgnored(rules, filepath.ToSlash(filepath.Clean(file.Name))) {
+		return fmt.Errorf("%s matches %s", file.Name, ignoreFileName)
+	}
+	if m, found := findForbidden(file, forbidden); found {
+		return errors.New(m.String())
+	}
+	return nil
+}
+
+// runFunctionCalls sends the prompt read from stdin to the model, which
+// answers by calling the file operation functions, and carries out the calls:
+// deletions and renames first, then the created files are checked and written
+// like generated files.
+func runFunctionCalls(ctx context.Context, cfg Config, gen generator) error {
+	text, err := requestText(ctx, cfg, gen)
+	if err != nil {
+		return err
+	}
+	files, ops, err := parseFunctionCalls(text, cfg.FileFunctions)
+	if err != nil {
+		return err
+	}
+	files = transformFiles(cfg, files)
+	if files, err = filterFiles(cfg, files); err != nil {
+		return err
+	}
+	if err := applyFileOperations(cfg, ops, terminalConfirm()); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return writeFiles(osFS{}, cfg, files)
+}
+
+// functionCallTask is the instruction of the model with --function-calling.
+func functionCallTask(allowed []string) string {
+	return "carry it out by calling " + strings.Join(allowed, ", ") + " for the files in the output directory, giving every file its complete content"
+}
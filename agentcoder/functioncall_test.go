@@ -0,0 +1,291 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// functionCallResponse returns the body of a response whose candidate calls
+// the functions given as JSON objects with a name and args.
+func functionCallResponse(calls ...string) apiResponse {
+	parts := make([]string, len(calls))
+	for i, call := range calls {
+		parts[i] = `{"functionCall": ` + call + `}`
+	}
+	return apiResponse{body: `{"candidates": [{"content": {"role": "model", "parts": [` + strings.Join(parts, ", ") + `]}, "finishReason": "STOP"}], "usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 20, "totalTokenCount": 30}}`}
+}
+
+// functionCallConfig returns a configuration offering the functions and
+// writing to a temporary directory holding the files.
+func functionCallConfig(t *testing.T, functions []string, files map[string]string) Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Prompt = "Reorganize the package."
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	cfg.FunctionCalling = true
+	cfg.FileFunctions = functions
+	writeTree(t, cfg.OutputDir, files)
+	return cfg
+}
+
+func TestFunctionCallCreateFile(t *testing.T) {
+	cfg := functionCallConfig(t, []string{fnCreateFile}, nil)
+	gen, api := newTestGenerator(t, cfg, functionCallResponse(
+		`{"name": "createFile", "args": {"path": "cmd/main.go", "content": "package main\n\nfunc main() {}\n"}}`,
+	))
+	var err error
+	captureStdout(t, func() { err = runFunctionCalls(context.Background(), cfg, gen) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.OutputDir, "cmd", "main.go"))
+	if err != nil || string(data) != "package main\n\nfunc main() {}\n" {
+		t.Errorf("cmd/main.go = %q, %v, want the content of the call", data, err)
+	}
+	body := api.bodies[0]
+	if !strings.Contains(body, `"functionDeclarations"`) || !strings.Contains(body, `"createFile"`) || strings.Contains(body, `"deleteFile"`) {
+		t.Errorf("request %s does not declare only createFile", body)
+	}
+}
+
+func TestFunctionCallDeleteAndRename(t *testing.T) {
+	cfg := functionCallConfig(t, []string{fnCreateFile, fnDeleteFile, fnRenameFile}, map[string]string{
+		"old.go":  "package p\n",
+		"util.go": "package p\n\nfunc Util() {}\n",
+	})
+	cfg.Yes = true
+	gen, _ := newTestGenerator(t, cfg, functionCallResponse(
+		`{"name": "deleteFile", "args": {"path": "old.go"}}`,
+		`{"name": "renameFile", "args": {"from": "util.go", "to": "internal/util.go"}}`,
+		`{"name": "createFile", "args": {"path": "new.go", "content": "package p\n"}}`,
+	))
+	var err error
+	out := captureStdout(t, func() { err = runFunctionCalls(context.Background(), cfg, gen) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "old.go")); !os.IsNotExist(err) {
+		t.Errorf("old.go not deleted: %v", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(cfg.OutputDir, "internal", "util.go")); err != nil || string(data) != "package p\n\nfunc Util() {}\n" {
+		t.Errorf("internal/util.go = %q, %v, want util.go moved there", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.OutputDir, "new.go")); err != nil {
+		t.Errorf("new.go not created: %v", err)
+	}
+	for _, want := range []string{"requested 2 file operation(s)", "Done: delete old.go", "Done: rename util.go to internal/util.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not contain %q", out, want)
+		}
+	}
+}
+
+func TestParseFunctionCalls(t *testing.T) {
+	all := []string{fnCreateFile, fnDeleteFile, fnRenameFile}
+	files, ops, err := parseFunctionCalls(`[
+		{"name": "createFile", "args": {"path": "a.go", "content": "package a"}},
+		{"name": "deleteFile", "args": {"path": "b.go"}},
+		{"name": "renameFile", "args": {"from": "c.go", "to": "d.go"}}
+	]`, all)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []File{{Name: "a.go", Code: "package a"}}; !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	want := []fileOperation{{Function: fnDeleteFile, Path: "b.go"}, {Function: fnRenameFile, Path: "c.go", To: "d.go"}}
+	if !reflect.DeepEqual(ops, want) {
+		t.Errorf("operations = %v, want %v", ops, want)
+	}
+
+	errs := []struct {
+		text    string
+		allowed []string
+		want    string
+	}{
+		{`[{"name": "deleteFile", "args": {"path": "b.go"}}]`, []string{fnCreateFile}, `call 1: function "deleteFile" is not allowed`},
+		{`[{"name": "createFile", "args": {"path": "a.go"}}]`, all, `createFile needs a string argument "content"`},
+		{`[{"name": "renameFile", "args": {"from": "c.go", "to": 1}}]`, all, `renameFile needs a string argument "to"`},
+		{`Here are the files`, all, "the response contains no function calls"},
+	}
+	for _, tt := range errs {
+		if _, _, err := parseFunctionCalls(tt.text, tt.allowed); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseFunctionCalls(%s) = %v, want an error containing %q", tt.text, err, tt.want)
+		}
+	}
+}
+
+func TestApplyFileOperationsRefused(t *testing.T) {
+	existing := map[string]string{
+		"main.go":            "package main\n",
+		"notes.txt":          "notes\n",
+		"secret.go":          "package main\n\nconst key = \"AKIA0000\"\n",
+		"vendor/lib/lib.go":  "package lib\n",
+		ignoreFileName:       "vendor/\n*.lock\n",
+		"sub/keep/.gitkeep":  "",
+		"sub/keep/README.md": "# Keep\n",
+	}
+	tests := []struct {
+		name   string
+		op     fileOperation
+		modify func(*Config)
+		want   string
+	}{
+		{name: "escaping path", op: fileOperation{Function: fnDeleteFile, Path: "../main.go"}, want: "refusing to delete ../main.go"},
+		{name: "escaping target", op: fileOperation{Function: fnRenameFile, Path: "main.go", To: "../main.go"}, want: "refusing to rename main.go to ../main.go"},
+		{name: "missing file", op: fileOperation{Function: fnDeleteFile, Path: "gone.go"}, want: "cannot delete gone.go"},
+		{name: "directory", op: fileOperation{Function: fnDeleteFile, Path: "sub"}, want: "not a regular file"},
+		{name: "existing target", op: fileOperation{Function: fnRenameFile, Path: "main.go", To: "notes.txt"}, want: "notes.txt exists"},
+		{name: "ignored source", op: fileOperation{Function: fnDeleteFile, Path: "vendor/lib/lib.go"}, want: "vendor/lib/lib.go matches " + ignoreFileName},
+		{name: "ignored target", op: fileOperation{Function: fnRenameFile, Path: "main.go", To: "go.lock"}, want: "go.lock matches " + ignoreFileName},
+		{
+			name:   "source extension not allowed",
+			op:     fileOperation{Function: fnDeleteFile, Path: "notes.txt"},
+			modify: func(cfg *Config) { cfg.AllowedExtensions = []string{"go"} },
+			want:   "notes.txt has a disallowed extension",
+		},
+		{
+			name:   "target extension denied",
+			op:     fileOperation{Function: fnRenameFile, Path: "main.go", To: "main.sh"},
+			modify: func(cfg *Config) { cfg.DeniedExtensions = []string{".sh"} },
+			want:   "main.sh has a disallowed extension",
+		},
+		{
+			name:   "forbidden content",
+			op:     fileOperation{Function: fnRenameFile, Path: "secret.go", To: "public.go"},
+			modify: func(cfg *Config) { cfg.ForbidPatterns = []string{`AKIA[0-9]+`} },
+			want:   `secret.go:3 matches forbidden pattern "AKIA[0-9]+"`,
+		},
+		{
+			name:   "invalid forbidden pattern",
+			op:     fileOperation{Function: fnDeleteFile, Path: "main.go"},
+			modify: func(cfg *Config) { cfg.ForbidPatterns = []string{`(`} },
+			want:   "invalid forbidden pattern",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := functionCallConfig(t, nil, existing)
+			cfg.Yes = true
+			if tt.modify != nil {
+				tt.modify(&cfg)
+			}
+			var err error
+			out := captureStdout(t, func() { err = applyFileOperations(cfg, []fileOperation{tt.op}, nil) })
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("applyFileOperations(%v) = %v, want an error containing %q", tt.op, err, tt.want)
+			}
+			if strings.Contains(out, "Done:") {
+				t.Errorf("operation applied although refused:\n%s", out)
+			}
+			for name := range existing {
+				if _, err := os.Stat(filepath.Join(cfg.OutputDir, name)); err != nil {
+					t.Errorf("%s changed: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFileOperationsSamePathTwice(t *testing.T) {
+	existing := map[string]string{"a.go": "package a\n", "c.go": "package c\n"}
+	tests := []struct {
+		name string
+		ops  []fileOperation
+		want string
+	}{
+		{
+			name: "delete then rename",
+			ops:  []fileOperation{{Function: fnDeleteFile, Path: "a.go"}, {Function: fnRenameFile, Path: "a.go", To: "b.go"}},
+			want: "refusing to rename a.go to b.go: a.go is already changed",
+		},
+		{
+			name: "same target",
+			ops:  []fileOperation{{Function: fnRenameFile, Path: "a.go", To: "b.go"}, {Function: fnRenameFile, Path: "c.go", To: "./b.go"}},
+			want: "refusing to rename c.go to ./b.go: ./b.go is already changed",
+		},
+		{
+			name: "rename then delete",
+			ops:  []fileOperation{{Function: fnRenameFile, Path: "a.go", To: "b.go"}, {Function: fnDeleteFile, Path: "b.go"}},
+			want: "refusing to delete b.go: b.go is already changed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := functionCallConfig(t, nil, existing)
+			cfg.Yes = true
+			var err error
+			captureStdout(t, func() { err = applyFileOperations(cfg, tt.ops, nil) })
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("applyFileOperations() = %v, want an error containing %q", err, tt.want)
+			}
+			// The batch is refused before any operation is applied
+			for name := range existing {
+				if _, err := os.Stat(filepath.Join(cfg.OutputDir, name)); err != nil {
+					t.Errorf("%s changed: %v", name, err)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFileOperationsConfirmation(t *testing.T) {
+	ops := []fileOperation{{Function: fnDeleteFile, Path: "a.go"}}
+	tests := []struct {
+		name    string
+		yes     bool
+		confirm func(string) bool
+		want    string
+	}{
+		{name: "not a terminal", want: "pass --yes"},
+		{name: "declined", confirm: func(string) bool { return false }, want: "aborted"},
+		{name: "confirmed", confirm: func(string) bool { return true }},
+		{name: "yes", yes: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := functionCallConfig(t, nil, map[string]string{"a.go": "package a\n"})
+			cfg.Yes = tt.yes
+			var err error
+			captureStdout(t, func() { err = applyFileOperations(cfg, ops, tt.confirm) })
+			_, statErr := os.Stat(filepath.Join(cfg.OutputDir, "a.go"))
+			if tt.want == "" {
+				if err != nil || !os.IsNotExist(statErr) {
+					t.Errorf("applyFileOperations() = %v, a.go deleted = %v, want it deleted", err, os.IsNotExist(statErr))
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("applyFileOperations() = %v, want an error containing %q", err, tt.want)
+			}
+			if statErr != nil {
+				t.Errorf("a.go deleted without confirmation: %v", statErr)
+			}
+		})
+	}
+}
+
+func TestFileTool(t *testing.T) {
+	tool, err := fileTool([]string{fnDeleteFile, fnCreateFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, decl := range tool.FunctionDeclarations {
+		names = append(names, decl.Name)
+	}
+	if want := []string{fnDeleteFile, fnCreateFile}; !reflect.DeepEqual(names, want) {
+		t.Errorf("declared %v, want %v", names, want)
+	}
+	if _, err := fileTool([]string{"chmod"}); err == nil || !strings.Contains(err.Error(), `unknown file function "chmod"`) {
+		t.Errorf("fileTool(chmod) = %v, want an unknown function error", err)
+	}
+	if _, err := fileTool(nil); err == nil {
+		t.Error("fileTool(nil) succeeded without functions")
+	}
+}
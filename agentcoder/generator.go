@@ -0,0 +1,418 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+// generator sends a prompt to the model and returns its reply.
+type generator interface {
+	Generate(ctx context.Context, prompt string) (*reply, error)
+}
+
+// tokenCounter is implemented by generators that can count the tokens of a
+// prompt without generating a reply.
+type tokenCounter interface {
+	CountTokens(ctx context.Context, prompt string) (int32, error)
+}
+
+// reply is the text of a model response together with its metadata.
+type reply struct {
+	Model         string                // Name of the model that served the request
+	Text          string                // Text of the first candidate
+	FinishReason  genai.FinishReason    // Why the model stopped generating
+	SafetyRatings []*genai.SafetyRating // Safety ratings of the first candidate
+	Usage         *genai.UsageMetadata  // Token counts, if reported
+}
+
+// newReply extracts the reply from a response of the API. The text of a reply
+// with function calls is the calls encoded by encodeFunctionCalls.
+func newReply(resp *genai.GenerateContentResponse) (*reply, error) {
+	// Check if there's a response
+	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil || len(resp.Candidates[0].Content.Parts) == 0 {
+		return nil, errors.New("no response received")
+	}
+	candidate := resp.Candidates[0]
+	text, ok := candidate.Content.Parts[0].(genai.Text)
+	if calls := functionCalls(candidate.Content.Parts); len(calls) > 0 {
+		text = genai.Text(encodeFunctionCalls(calls))
+	} else if !ok {
+		return nil, errors.New("response is not text")
+	}
+	return &reply{
+		Text:          string(text),
+		FinishReason:  candidate.FinishReason,
+		SafetyRatings: candidate.SafetyRatings,
+		Usage:         resp.UsageMetadata,
+	}, nil
+}
+
+// functionCalls returns the function calls among parts.
+func functionCalls(parts []genai.Part) []genai.FunctionCall {
+	var calls []genai.FunctionCall
+	for _, part := range parts {
+		if call, ok := part.(genai.FunctionCall); ok {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// modelGenerator is the generator backed by a Gemini model.
+type modelGenerator struct {
+	client *genai.Client
+	model  *genai.GenerativeModel
+	stream bool
+
+	// fallback, if not nil, is tried once when model fails with an error
+	// that suggests it is overloaded or unavailable.
+	fallback *genai.GenerativeModel
+
+	// modelName and fallbackName are the names of model and fallback.
+	modelName, fallbackName string
+
+	// failOnSafety fails replies with a safety rating at or above it.
+	failOnSafety genai.HarmProbability
+
+	// maxContinuations is the number of follow-up requests made to complete
+	// a reply that was cut off at the output token limit.
+	maxContinuations int
+
+	// attachments are sent after the prompt of every request.
+	attachments []genai.Part
+
+	// cache, if not nil, answers repeated requests without the API.
+	cache *responseCache
+
+	// retry decides how requests to model that fail with an error that
+	// suggests it is overloaded or unavailable are retried.
+	retry retryPolicy
+
+	// onFile, if not nil, is called with each file of a streamed JSON reply
+	// as soon as it has been received completely.
+	onFile func(File)
+
+	// cost, if not nil, refuses requests that would make the run exceed
+	// --max-cost.
+	cost *costTracker
+}
+
+// newModelGenerator creates a client for the generative AI service and the
+// model used to generate files.
+func newModelGenerator(ctx context.Context, cfg Config) (*modelGenerator, error) {
+	keys := cfg.apiKeys()
+	if len(keys) == 0 {
+		return nil, errors.New("API key is required")
+	}
+	opts := []option.ClientOption{option.WithAPIKey(keys[0])}
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		opts = append(opts, option.WithHTTPClient(httpClient))
+	}
+	client, err := genai.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("creating client: %w", err)
+	}
+	g, err := newClientGenerator(client, cfg)
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return g, nil
+}
+
+// newClientGenerator creates the generator configured by cfg on top of client,
+// which is closed with the generator.
+func newClientGenerator(client *genai.Client, cfg Config) (*modelGenerator, error) {
+	attachments, err := loadAttachments(cfg.Attachments)
+	if err != nil {
+		return nil, err
+	}
+	model, err := newModel(client, cfg)
+	if err != nil {
+		return nil, err
+	}
+	g := &modelGenerator{
+		client:           client,
+		model:            model,
+		stream:           cfg.Stream,
+		modelName:        cfg.Model,
+		fallbackName:     cfg.FallbackModel,
+		maxContinuations: cfg.MaxContinuations,
+		attachments:      attachments,
+	}
+	if cfg.Cache {
+		g.cache = &responseCache{dir: cfg.CacheDir, ttl: time.Duration(cfg.CacheTTL) * time.Second}
+	}
+	if g.retry, err = newRetryPolicy(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.MaxCost > 0 || cfg.DailyBudget > 0 {
+		for _, name := range []string{cfg.Model, cfg.FallbackModel} {
+			if _, ok := lookupPrice(name); name != "" && !ok {
+				return nil, fmt.Errorf("no price known for model %s to enforce --max-cost or --daily-budget", name)
+			}
+		}
+	}
+	g.cost = cfg.cost
+	if g.cost == nil {
+		if g.cost, err = newCostTracker(cfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.FallbackModel != "" {
+		fallbackCfg := cfg
+		fallbackCfg.Model = cfg.FallbackModel
+		if g.fallback, err = newModel(client, fallbackCfg); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.FailOnSafety != "" {
+		threshold, ok := safetyProbabilities[cfg.FailOnSafety]
+		if !ok {
+			return nil, fmt.Errorf("unknown safety probability %q", cfg.FailOnSafety)
+		}
+		g.failOnSafety = threshold
+	}
+	return g, nil
+}
+
+// Generate sends the prompt to the model, streaming the reply if configured,
+// and checks the safety ratings of the reply. If the model is unavailable the
+// request is retried with the configured retry policy, and then once with the
+// fallback model. With a cache, replies are stored and repeated requests
+// answered from it.
+func (g *modelGenerator) Generate(ctx context.Context, prompt string) (r *reply, err error) {
+	ctx, span := tracer.Start(ctx, "generate", trace.WithAttributes(attribute.String("model", g.modelName)))
+	defer func() {
+		if r != nil {
+			span.SetAttributes(attribute.String("served_by", r.Model))
+			if r.Usage != nil {
+				span.SetAttributes(
+					attribute.Int("prompt_tokens", int(r.Usage.PromptTokenCount)),
+					attribute.Int("response_tokens", int(r.Usage.CandidatesTokenCount)),
+				)
+			}
+		}
+		endSpan(span, err)
+	}()
+
+	// Answer a request made before from the cache
+	var key string
+	if g.cache != nil {
+		key = cacheKey(g.modelName, g.model.GenerationConfig, g.parts(prompt))
+		if cached, ok := g.cache.get(key); ok {
+			fmt.Println("Using cached response")
+			span.SetAttributes(attribute.Bool("cached", true))
+			// The reply may have been cached by a run with a laxer
+			// --fail-on-safety
+			if err := checkSafety(cached.SafetyRatings, g.failOnSafety); err != nil {
+				return nil, err
+			}
+			return cached, nil
+		}
+	}
+
+	r, err = g.generateWith(ctx, g.model, g.modelName, prompt)
+	for attempt := 1; err != nil && attempt <= g.retry.retries && isRetriableError(err); attempt++ {
+		delay := g.retry.delay(attempt)
+		fmt.Printf("Model %s failed: %v\nRetrying in %v (%d of %d)\n", g.modelName, err, delay, attempt, g.retry.retries)
+		if err := g.retry.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		r, err = g.generateWith(ctx, g.model, g.modelName, prompt)
+	}
+	if err != nil && g.fallback != nil && isRetriableError(err) {
+		fmt.Printf("Model %s failed: %v\nRetrying with fallback model %s\n", g.modelName, err, g.fallbackName)
+		if r, err = g.generateWith(ctx, g.fallback, g.fallbackName, prompt); err == nil {
+			fmt.Printf("Response served by fallback model %s\n", r.Model)
+		}
+	}
+	if err == nil && g.cache != nil {
+		if err := g.cache.put(key, r); err != nil {
+			fmt.Printf("Warning: could not cache response: %v\n", err)
+		}
+	}
+	return r, err
+}
+
+// generateWith sends the prompt to model, which has the given name, and
+// completes a truncated reply.
+func (g *modelGenerator) generateWith(ctx context.Context, model *genai.GenerativeModel, name, prompt string) (*reply, error) {
+	var r *reply
+	parts := g.parts(prompt)
+	if g.cost != nil {
+		if err := g.cost.reserve(ctx, model, name, parts); err != nil {
+			return nil, err
+		}
+	}
+	if g.stream {
+		var onObject func(string)
+		if g.onFile != nil && model.ResponseMIMEType == "application/json" {
+			onObject = func(object string) {
+				var file File
+				if err := json.Unmarshal([]byte(object), &file); err == nil && file.Name != "" {
+					g.onFile(file)
+				}
+			}
+		}
+		var err error
+		if r, err = streamContent(ctx, model, parts, onObject); err != nil {
+			return nil, err
+		}
+	} else {
+		resp, err := model.GenerateContent(ctx, parts...)
+		if err != nil {
+			return nil, err
+		}
+		if r, err = newReply(resp); err != nil {
+			return nil, err
+		}
+	}
+	r.Model = name
+	if g.cost != nil {
+		g.cost.add(name, r.Usage)
+	}
+	if err := checkSafety(r.SafetyRatings, g.failOnSafety); err != nil {
+		return nil, err
+	}
+	if r.FinishReason == genai.FinishReasonMaxTokens && model.ResponseMIMEType == "application/json" {
+		if err := g.continueReply(ctx, model, name, prompt, r); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// continueReply completes a JSON reply that was cut off at the output token
+// limit. It asks the model to resume the output in plain text, appending each
+// continuation to r until the top-level array is complete or the number of
+// continuations is exhausted.
+func (g *modelGenerator) continueReply(ctx context.Context, m *genai.GenerativeModel, name, prompt string, r *reply) error {
+	var buf jsonStreamBuffer
+	buf.Write(r.Text)
+
+	// The continuation is a fragment of JSON, so it cannot follow the schema
+	model := *m
+	model.ResponseMIMEType = "text/plain"
+	model.ResponseSchema = nil
+
+	for n := 1; !buf.Complete() && n <= g.maxContinuations; n++ {
+		fmt.Printf("Response was truncated, requesting continuation %d of %d\n", n, g.maxContinuations)
+		part := genai.Text(continuationPrompt(prompt, buf.String()))
+		if g.cost != nil {
+			if err := g.cost.reserve(ctx, &model, name, []genai.Part{part}); err != nil {
+				return err
+			}
+		}
+		resp, err := model.GenerateContent(ctx, part)
+		if err != nil {
+			return fmt.Errorf("continuing truncated response: %w", err)
+		}
+		next, err := newReply(resp)
+		if err != nil {
+			return fmt.Errorf("continuing truncated response: %w", err)
+		}
+		if g.cost != nil {
+			g.cost.add(name, next.Usage)
+		}
+		buf.Write(next.Text)
+		r.FinishReason = next.FinishReason
+	}
+	r.Text = buf.String()
+	if !buf.Complete() {
+		return fmt.Errorf("response is still truncated after %d continuation(s)", g.maxContinuations)
+	}
+	return nil
+}
+
+// parts returns the parts of a request for prompt.
+func (g *modelGenerator) parts(prompt string) []genai.Part {
+	return append([]genai.Part{genai.Text(prompt)}, g.attachments...)
+}
+
+// Summarize asks the model for a plain text summary of a context file.
+func (g *modelGenerator) Summarize(ctx context.Context, name, content string, maxChars int) (string, error) {
+	model := *g.model
+	model.ResponseMIMEType = "text/plain"
+	model.ResponseSchema = nil
+	part := genai.Text(summaryPrompt(name, content, maxChars))
+	if g.cost != nil {
+		if err := g.cost.reserve(ctx, &model, g.modelName, []genai.Part{part}); err != nil {
+			return "", err
+		}
+	}
+	resp, err := model.GenerateContent(ctx, part)
+	if err != nil {
+		return "", err
+	}
+	r, err := newReply(resp)
+	if err != nil {
+		return "", err
+	}
+	if g.cost != nil {
+		g.cost.add(g.modelName, r.Usage)
+	}
+	return strings.TrimSpace(r.Text), nil
+}
+
+// CountTokens returns the number of tokens the prompt takes up for the model.
+func (g *modelGenerator) CountTokens(ctx context.Context, prompt string) (int32, error) {
+	resp, err := g.model.CountTokens(ctx, g.parts(prompt)...)
+	if err != nil {
+		return 0, err
+	}
+	return resp.TotalTokens, nil
+}
+
+// withSchema returns a generator sharing the client and settings of g whose
+// model answers with the given schema. Its replies are not files, so they are
+// not passed to onFile.
+func (g *modelGenerator) withSchema(schema *genai.Schema) *modelGenerator {
+	withSchema := func(m *genai.GenerativeModel) *genai.GenerativeModel {
+		model := *m
+		model.ResponseMIMEType = "application/json"
+		model.ResponseSchema = schema
+		return &model
+	}
+	derived := *g
+	derived.onFile = nil
+	derived.model = withSchema(g.model)
+	if g.fallback != nil {
+		derived.fallback = withSchema(g.fallback)
+	}
+	return &derived
+}
+
+// isRetriableError reports whether err suggests the model is overloaded or
+// temporarily unavailable, so that another model may succeed.
+func isRetriableError(err error) bool {
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "overloaded") || strings.Contains(msg, "unavailable")
+}
+
+// Close releases the client.
+func (g *modelGenerator) Close() error {
+	return g.client.Close()
+}
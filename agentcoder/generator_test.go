@@ -0,0 +1,209 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// fakeGenerator answers prompts with canned replies, in order, and records the
+// prompts it receives.
+type fakeGenerator struct {
+	replies []*reply
+	errs    []error
+	prompts []string
+}
+
+func (g *fakeGenerator) Generate(ctx context.Context, prompt string) (*reply, error) {
+	g.prompts = append(g.prompts, prompt)
+	i := len(g.prompts) - 1
+	if i < len(g.errs) && g.errs[i] != nil {
+		return nil, g.errs[i]
+	}
+	if i >= len(g.replies) {
+		return nil, fmt.Errorf("unexpected request %d", i+1)
+	}
+	return g.replies[i], nil
+}
+
+// filesReply returns a reply with the files encoded as the JSON response of the
+// model.
+func filesReply(t *testing.T, files ...File) *reply {
+	t.Helper()
+	data, err := json.Marshal(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &reply{Text: string(data)}
+}
+
+// apiResponse is a canned answer of the fake API: a JSON body sent with the
+// status code, 200 if zero.
+type apiResponse struct {
+	status int
+	body   string
+}
+
+// fakeAPI is an HTTP server standing in for the generative AI service. It
+// answers the requests with its responses, in order, and records the request
+// paths and bodies.
+type fakeAPI struct {
+	t         *testing.T
+	mu        sync.Mutex
+	responses []apiResponse
+	paths     []string
+	bodies    []string
+}
+
+// newFakeAPI starts a fake API with the responses and returns a client for it.
+func newFakeAPI(t *testing.T, responses ...apiResponse) (*fakeAPI, *genai.Client) {
+	t.Helper()
+	api := &fakeAPI{t: t, responses: responses}
+	srv := httptest.NewServer(api)
+	t.Cleanup(srv.Close)
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey("test-key"), option.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return api, client
+}
+
+func (api *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	api.paths = append(api.paths, r.URL.Path)
+	api.bodies = append(api.bodies, string(body))
+	if len(api.responses) == 0 {
+		api.t.Errorf("unexpected request %s %s", r.URL.Path, body)
+		http.Error(w, `{"error": {"code": 500, "message": "no response"}}`, http.StatusInternalServerError)
+		return
+	}
+	resp := api.responses[0]
+	api.responses = api.responses[1:]
+	w.Header().Set("Content-Type", "application/json")
+	if resp.status != 0 {
+		w.WriteHeader(resp.status)
+	}
+	io.WriteString(w, resp.body)
+}
+
+// requests returns the number of requests received so far.
+func (api *fakeAPI) requests() int {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+	return len(api.paths)
+}
+
+// textResponse returns the body of a response with a single candidate whose
+// content is text and which finished for the given reason.
+func textResponse(text, finishReason string) apiResponse {
+	content, _ := json.Marshal(text)
+	return apiResponse{body: fmt.Sprintf(`{"candidates": [{"content": {"role": "model", "parts": [{"text": %s}]}, "finishReason": %q}], "usageMetadata": {"promptTokenCount": 10, "candidatesTokenCount": 20, "totalTokenCount": 30}}`, content, finishReason)}
+}
+
+// errorResponse returns an error of the API with the given status code.
+func errorResponse(status int, message string) apiResponse {
+	return apiResponse{status: status, body: fmt.Sprintf(`{"error": {"code": %d, "message": %q}}`, status, message)}
+}
+
+// newTestGenerator returns the generator configured by cfg for the fake API
+// answering with the responses.
+func newTestGenerator(t *testing.T, cfg Config, responses ...apiResponse) (*modelGenerator, *fakeAPI) {
+	t.Helper()
+	api, client := newFakeAPI(t, responses...)
+	gen, err := newClientGenerator(client, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen, api
+}
+
+func TestContinueTruncatedReply(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxContinuations = 2
+	gen, api := newTestGenerator(t, cfg,
+		textResponse(`[{"file_name": "a.go", "source_code": "package a // [`, "MAX_TOKENS"),
+		textResponse(`{"}, {"file_name": "b.go", `, "MAX_TOKENS"),
+		textResponse(`"source_code": "package b"}]`, "STOP"),
+	)
+	r, err := gen.Generate(context.Background(), "Write two packages.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := parseFiles(r.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 || files[0].Code != "package a // [{" || files[1].Name != "b.go" {
+		t.Errorf("files = %v, want a.go and b.go", files)
+	}
+	if api.requests() != 3 {
+		t.Fatalf("%d requests, want the original and two continuations", api.requests())
+	}
+	for _, body := range api.bodies[1:] {
+		if !strings.Contains(body, "Continue the output exactly where it stopped") || !strings.Contains(body, `"responseMimeType":"text/plain"`) {
+			t.Errorf("continuation request %s does not ask for a plain text continuation", body)
+		}
+	}
+}
+
+func TestContinueTruncatedReplyLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxContinuations = 1
+	gen, _ := newTestGenerator(t, cfg,
+		textResponse(`[{"file_name": "a.go", `, "MAX_TOKENS"),
+		textResponse(`"source_code": `, "MAX_TOKENS"),
+	)
+	if _, err := gen.Generate(context.Background(), "Write a package."); err == nil || !strings.Contains(err.Error(), "still truncated after 1 continuation") {
+		t.Errorf("Generate() error = %v, want the reply to stay truncated", err)
+	}
+}
+
+func TestFallbackModel(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Model = "primary-model"
+	cfg.FallbackModel = "backup-model"
+	gen, api := newTestGenerator(t, cfg,
+		errorResponse(http.StatusInternalServerError, "An internal error has occurred."),
+		textResponse("[]", "STOP"),
+	)
+	var r *reply
+	var err error
+	out := captureStdout(t, func() { r, err = gen.Generate(context.Background(), "Write nothing.") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Model != "backup-model" {
+		t.Errorf("reply served by %q, want backup-model", r.Model)
+	}
+	if !strings.Contains(out, "Response served by fallback model backup-model") {
+		t.Errorf("output %q does not name the fallback model", out)
+	}
+	if len(api.paths) != 2 || !strings.Contains(api.paths[0], "primary-model") || !strings.Contains(api.paths[1], "backup-model") {
+		t.Errorf("requests = %v, want primary-model then backup-model", api.paths)
+	}
+}
+
+func TestFallbackModelNotRetriable(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Model = "primary-model"
+	cfg.FallbackModel = "backup-model"
+	gen, api := newTestGenerator(t, cfg, errorResponse(http.StatusBadRequest, "Invalid argument."))
+	if _, err := gen.Generate(context.Background(), "Write nothing."); err == nil {
+		t.Fatal("Generate() succeeded")
+	}
+	if api.requests() != 1 {
+		t.Errorf("%d requests, want no fallback for a bad request", api.requests())
+	}
+}
@@ -0,0 +1,132 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// gitBranch is the branch the generated files are committed to with
+// --output-git-branch, so they can be reviewed in a pull request while the
+// working branch stays as it was.
+type gitBranch struct {
+	cfg      Config
+	name     string // Branch the files are committed to
+	original string // Branch checked out before, restored afterwards
+	active   bool   // Whether the new branch is checked out
+}
+
+// newGitBranch checks that the output directory is in a git repository on a
+// branch and without uncommitted changes, and picks a new branch named after
+// prompt. Nothing is changed until checkout is called.
+func newGitBranch(cfg Config, prompt string) (*gitBranch, error) {
+	if err := os.MkdirAll(cfg.OutputDir, os.FileMode(cfg.DirMode)); err != nil {
+		return nil, err
+	}
+	if _, err := cfg.git("rev-parse", "--is-inside-work-tree"); err != nil {
+		return nil, fmt.Errorf("%s is not in a git repository", cfg.OutputDir)
+	}
+	status, err := cfg.git("status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	if status != "" {
+		return nil, errors.New("the repository has uncommitted changes, commit or stash them before using --output-git-branch")
+	}
+	original, err := cfg.git("symbolic-ref", "--quiet", "--short", "HEAD")
+	if err != nil {
+		return nil, errors.New("HEAD is detached, check out the branch to return to before using --output-git-branch")
+	}
+
+	base := "agent-coder/" + promptSlug(prompt)
+	name := base
+	for i := 2; ; i++ {
+		if _, err := cfg.git("rev-parse", "--verify", "--quiet", "refs/heads/"+name); err != nil {
+			break
+		}
+		name = fmt.Sprintf("%s-%d", base, i)
+	}
+	return &gitBranch{cfg: cfg, name: name, original: original}, nil
+}
+
+// checkout switches to the new branch. The working tree is clean, so this
+// changes no files.
+func (b *gitBranch) checkout() error {
+	if _, err := b.cfg.git("checkout", "--quiet", "-b", b.name); err != nil {
+		return err
+	}
+	b.active = true
+	fmt.Printf("Writing the files on branch %s\n", b.name)
+	return nil
+}
+
+// commit commits everything written to the output directory on the new
+// branch with message and checks out the original branch again.
+func (b *gitBranch) commit(message string) error {
+	if _, err := b.cfg.git("add", "--all", "--", "."); err != nil {
+		return err
+	}
+	if _, err := b.cfg.git("diff", "--cached", "--quiet"); err == nil {
+		fmt.Printf("Nothing to commit on branch %s\n", b.name)
+	} else if _, err := b.cfg.git("commit", "--quiet", "--message", message); err != nil {
+		return err
+	} else {
+		fmt.Printf("Committed the files on branch %s\n", b.name)
+	}
+	if _, err := b.cfg.git("checkout", "--quiet", b.original); err != nil {
+		return err
+	}
+	b.active = false
+	fmt.Printf("Checked out %s again\n", b.original)
+	return nil
+}
+
+// git runs git in the output directory and returns its trimmed output.
+func (cfg Config) git(args ...string) (string, error) {
+	out, err := runCommand(cfg.commandTimeout(), cfg.OutputDir, append([]string{"git"}, args...)...)
+	text := strings.TrimSpace(string(out))
+	if err != nil {
+		return text, fmt.Errorf("git %s: %w: %s", args[0], err, text)
+	}
+	return text, nil
+}
+
+// promptSlug returns a short branch name component made from the first words
+// of the first line of prompt.
+func promptSlug(prompt string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(line) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			if b.Len() >= 40 {
+				break
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	if b.Len() == 0 {
+		return "changes"
+	}
+	return strings.TrimRight(b.String(), "-")
+}
+
+// commitMessage returns the commit message for the files generated for
+// prompt, which is its first line.
+func commitMessage(prompt string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(prompt), "\n")
+	if runes := []rune(line); len(runes) > 72 {
+		line = string(runes[:69]) + "..."
+	}
+	if line == "" {
+		return "Add generated files"
+	}
+	return line
+}
@@ -0,0 +1,169 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitOutputConfig returns a configuration writing into the repository dir and
+// sets the identity its commits are made with.
+func gitOutputConfig(t *testing.T, dir string) Config {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR", "GIT_COMMITTER"} {
+		t.Setenv(key+"_NAME", "Test")
+		t.Setenv(key+"_EMAIL", "test@example.com")
+	}
+	cfg := DefaultConfig()
+	cfg.OutputDir = dir
+	cfg.OutputGitBranch = true
+	return cfg
+}
+
+func TestGitBranch(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"README.md": "# Project\n"})
+	cfg := gitOutputConfig(t, dir)
+	original, err := cfg.git("symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	prompt := "Add a Hello function\nwith a test"
+	var branch *gitBranch
+	out := captureStdout(t, func() {
+		if branch, err = newGitBranch(cfg, prompt); err != nil {
+			t.Fatal(err)
+		}
+		if err := branch.checkout(); err != nil {
+			t.Fatal(err)
+		}
+		if err := writeFiles(osFS{}, cfg, []File{{Name: "hello.go", Code: "package hello\n"}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := branch.commit(commitMessage(prompt)); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if want := "agent-coder/add-a-hello-function"; branch.name != want {
+		t.Errorf("branch = %q, want %q", branch.name, want)
+	}
+	if !strings.Contains(out, "Committed the files on branch "+branch.name) {
+		t.Errorf("output %q does not report the commit", out)
+	}
+
+	if head, _ := cfg.git("symbolic-ref", "--short", "HEAD"); head != original {
+		t.Errorf("checked out %q after the commit, want %q", head, original)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "hello.go")); !os.IsNotExist(err) {
+		t.Errorf("hello.go is in the working tree of %s: %v", original, err)
+	}
+	if status, _ := cfg.git("status", "--porcelain"); status != "" {
+		t.Errorf("working tree is not clean after the commit:\n%s", status)
+	}
+	if subject, err := cfg.git("log", "-1", "--format=%s", branch.name); err != nil || subject != "Add a Hello function" {
+		t.Errorf("last commit on %s = %q, %v, want %q", branch.name, subject, err, "Add a Hello function")
+	}
+	if code, err := cfg.git("show", branch.name+":hello.go"); err != nil || code != "package hello" {
+		t.Errorf("hello.go on %s = %q, %v, want %q", branch.name, code, err, "package hello")
+	}
+
+	// The branch exists now, so the next run with the same prompt gets
+	// another one
+	next, err := newGitBranch(cfg, prompt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := branch.name + "-2"; next.name != want {
+		t.Errorf("second branch = %q, want %q", next.name, want)
+	}
+}
+
+func TestGitBranchNothingToCommit(t *testing.T) {
+	dir := gitRepo(t, map[string]string{"README.md": "# Project\n"})
+	cfg := gitOutputConfig(t, dir)
+	out := captureStdout(t, func() {
+		branch, err := newGitBranch(cfg, "Nothing")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := branch.checkout(); err != nil {
+			t.Fatal(err)
+		}
+		if err := branch.commit("Nothing"); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(out, "Nothing to commit on branch agent-coder/nothing") {
+		t.Errorf("output %q does not report the empty commit", out)
+	}
+}
+
+func TestGitBranchErrors(t *testing.T) {
+	dirty := gitRepo(t, map[string]string{"README.md": "# Project\n"})
+	writeTree(t, dirty, map[string]string{"README.md": "# Changed\n"})
+
+	detached := gitRepo(t, map[string]string{"README.md": "# Project\n"})
+	if _, err := gitOutputConfig(t, detached).git("checkout", "--quiet", "--detach"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"uncommitted changes", dirty, "uncommitted changes"},
+		{"detached HEAD", detached, "HEAD is detached"},
+		{"not a repository", t.TempDir(), "is not in a git repository"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := gitOutputConfig(t, tt.dir)
+			before, _ := cfg.git("status", "--porcelain")
+			_, err := newGitBranch(cfg, "Add files")
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("newGitBranch() = %v, want an error containing %q", err, tt.want)
+			}
+			if after, _ := cfg.git("status", "--porcelain"); after != before {
+				t.Errorf("status changed from %q to %q", before, after)
+			}
+			if branches, err := cfg.git("branch", "--list", "agent-coder/*"); err == nil && branches != "" {
+				t.Errorf("branches created: %s", branches)
+			}
+		})
+	}
+}
+
+func TestPromptSlug(t *testing.T) {
+	tests := []struct {
+		prompt, want string
+	}{
+		{"Add a Hello function", "add-a-hello-function"},
+		{"  Fix: the parser's bug!\nin detail", "fix-the-parser-s-bug"},
+		{"Create a REST API for todo items with users and tags and comments", "create-a-rest-api-for-todo-items-with-us"},
+		{"???", "changes"},
+		{"", "changes"},
+	}
+	for _, tt := range tests {
+		if got := promptSlug(tt.prompt); got != tt.want {
+			t.Errorf("promptSlug(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestCommitMessage(t *testing.T) {
+	long := strings.Repeat("x", 80)
+	tests := []struct {
+		prompt, want string
+	}{
+		{"Add a Hello function\nwith a test", "Add a Hello function"},
+		{long, strings.Repeat("x", 69) + "..."},
+		{"  \n", "Add generated files"},
+	}
+	for _, tt := range tests {
+		if got := commitMessage(tt.prompt); got != tt.want {
+			t.Errorf("commitMessage(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package agentcoder
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// modulePath returns the module path declared in the contents of a go.mod file.
+func modulePath(gomod []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(gomod))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+	return ""
+}
+
+// findGoModule looks for the go.mod governing dir, which does not need to
+// exist yet, and returns the import path that corresponds to dir. It returns
+// an empty string when dir is not inside a Go module.
+func findGoModule(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		data, err := os.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			module := modulePath(data)
+			if module == "" {
+				return "", nil
+			}
+			rel, err := filepath.Rel(root, abs)
+			if err != nil {
+				return "", err
+			}
+			return path.Join(module, filepath.ToSlash(rel)), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+		if filepath.Dir(root) == root {
+			return "", nil
+		}
+	}
+}
+
+// goMention matches prompts that ask for Go code.
+var goMention = regexp.MustCompile(`\bGo\b|(?i:\bgolang\b|\.go\b|\bgo (?:module|package|program|cli|code|service|server)\b)`)
+
+// mentionsGo reports whether the prompt asks for Go code.
+func mentionsGo(prompt string) bool {
+	return goMention.MatchString(prompt)
+}
@@ -0,0 +1,60 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestModulePathInPrompt(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "go.mod"), []byte("module example.com/app\n\ngo 1.22\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a Go package for parsing dates."
+	cfg.OutputDir = filepath.Join(root, "internal", "dates")
+	req, err := readPrompt(context.Background(), cfg, &fakeGenerator{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Text, "with Go import path example.com/app/internal/dates.") {
+		t.Errorf("prompt %q does not contain the import path", req.Text)
+	}
+}
+
+func TestMissingGoModWarning(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a Go package for parsing dates."
+	cfg.OutputDir = t.TempDir()
+	var req *promptRequest
+	out := captureStdout(t, func() {
+		var err error
+		if req, err = readPrompt(context.Background(), cfg, &fakeGenerator{}); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(out, "Warning: no go.mod found") {
+		t.Errorf("output %q does not warn about the missing go.mod", out)
+	}
+	if req != nil && strings.Contains(req.Text, "import path") {
+		t.Errorf("prompt %q mentions an import path without a module", req.Text)
+	}
+}
+
+func TestMentionsGo(t *testing.T) {
+	for prompt, want := range map[string]bool{
+		"Write a Go CLI":            true,
+		"a golang service":          true,
+		"update main.go":            true,
+		"let's go build a website":  false,
+		"Write a Python script":     false,
+		"Create a go module for it": true,
+	} {
+		if got := mentionsGo(prompt); got != want {
+			t.Errorf("mentionsGo(%q) = %v, want %v", prompt, got, want)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// goVet runs go vet on every package in the output directory and reports its
+// findings. When the code does not build, vet cannot check it and is skipped
+// with a warning. With --fail-on-vet, findings are returned as an error.
+func goVet(cfg Config) error {
+	out, err := runCommand(cfg.commandTimeout(), cfg.OutputDir, "go", "vet", "./...")
+	if errors.Is(err, errCommandTimeout) {
+		return err
+	}
+	if err == nil {
+		fmt.Println("\ngo vet found no problems")
+		return nil
+	}
+
+	// Tell findings apart from code that vet could not load
+	if buildOut, buildErr := goBuild(cfg.OutputDir, cfg.commandTimeout()); buildErr != nil {
+		fmt.Printf("\nWarning: skipping go vet, %s is not a buildable Go package:\n%s", cfg.OutputDir, buildOut)
+		return nil
+	}
+	findings := strings.TrimRight(string(out), "\n")
+	fmt.Printf("\ngo vet reported problems:\n%s\n", findings)
+	if cfg.FailOnVet {
+		return fmt.Errorf("go vet reported problems in %s", cfg.OutputDir)
+	}
+	return nil
+}
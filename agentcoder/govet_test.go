@@ -0,0 +1,80 @@
+package agentcoder
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// goModule returns a config whose output directory holds a Go module with
+// the given files. Tests using it are skipped without a Go toolchain.
+func goModule(t *testing.T, files map[string]string) Config {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go vet needs the go toolchain")
+	}
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	files["go.mod"] = "module example.com/vet\n\ngo 1.21\n"
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(cfg.OutputDir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+const suspiciousCode = `package main
+
+import "fmt"
+
+func main() {
+	fmt.Printf("%d\n", "not a number")
+}
+`
+
+func TestGoVet(t *testing.T) {
+	cfg := goModule(t, map[string]string{"main.go": suspiciousCode})
+	var err error
+	out := captureStdout(t, func() { err = goVet(cfg) })
+	if err != nil {
+		t.Fatalf("goVet() = %v, want findings reported without an error", err)
+	}
+	if !strings.Contains(out, "go vet reported problems") || !strings.Contains(out, "main.go:6") {
+		t.Errorf("output %q does not report the Printf finding", out)
+	}
+
+	cfg.FailOnVet = true
+	captureStdout(t, func() { err = goVet(cfg) })
+	if err == nil || !strings.Contains(err.Error(), "go vet reported problems") {
+		t.Errorf("goVet() with --fail-on-vet = %v, want an error", err)
+	}
+}
+
+func TestGoVetClean(t *testing.T) {
+	cfg := goModule(t, map[string]string{"main.go": "package main\n\nfunc main() {}\n"})
+	cfg.FailOnVet = true
+	var err error
+	out := captureStdout(t, func() { err = goVet(cfg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "go vet found no problems") {
+		t.Errorf("output %q, want no problems", out)
+	}
+}
+
+func TestGoVetNotBuildable(t *testing.T) {
+	cfg := goModule(t, map[string]string{"main.go": "package main\n\nfunc main() { undefined() }\n"})
+	cfg.FailOnVet = true
+	var err error
+	out := captureStdout(t, func() { err = goVet(cfg) })
+	if err != nil {
+		t.Fatalf("goVet() = %v, want code that does not build to be skipped", err)
+	}
+	if !strings.Contains(out, "skipping go vet") {
+		t.Errorf("output %q does not warn that vet was skipped", out)
+	}
+}
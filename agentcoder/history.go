@@ -0,0 +1,101 @@
+package agentcoder
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// loadHistory reads the prompts saved in the history file, oldest first. A
+// missing file is an empty history.
+func loadHistory(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history []string
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			history = append(history, line)
+		}
+	}
+	return history, nil
+}
+
+// appendHistory adds a prompt to the end of the history file.
+func appendHistory(path, prompt string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(f, prompt); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// printHistory lists the saved prompts with the numbers used to recall them.
+func printHistory(history []string) {
+	if len(history) == 0 {
+		fmt.Println("No prompts in the history yet")
+		return
+	}
+	for i, prompt := range history {
+		fmt.Printf("%4d  %s\n", i+1, prompt)
+	}
+	fmt.Println("Enter !<number> to reuse a prompt")
+}
+
+// readInteractivePrompt reads the prompt from scanner. With a history file,
+// "/history" lists the previous prompts, "!<number>" reuses one of them, and
+// the chosen prompt is added to the history.
+func readInteractivePrompt(scanner *bufio.Scanner, historyFile string) (string, error) {
+	var history []string
+	if historyFile != "" {
+		var err error
+		if history, err = loadHistory(historyFile); err != nil {
+			return "", fmt.Errorf("reading history: %w", err)
+		}
+	}
+
+	var prompt string
+	for {
+		fmt.Print("Enter your prompt: ")
+		if !scanner.Scan() { // Get user input
+			break
+		}
+		prompt = scanner.Text()
+		if historyFile == "" {
+			break
+		}
+		if strings.TrimSpace(prompt) == "/history" {
+			printHistory(history)
+			continue
+		}
+		if ref, ok := strings.CutPrefix(strings.TrimSpace(prompt), "!"); ok {
+			n, err := strconv.Atoi(ref)
+			if err != nil || n < 1 || n > len(history) {
+				fmt.Printf("No prompt number %s in the history\n", ref)
+				continue
+			}
+			prompt = history[n-1]
+			fmt.Printf("Using prompt: %s\n", prompt)
+		}
+		break
+	}
+
+	if historyFile != "" && strings.TrimSpace(prompt) != "" {
+		if err := appendHistory(historyFile, prompt); err != nil {
+			fmt.Printf("Warning: could not save prompt to history: %v\n", err)
+		}
+	}
+	return prompt, nil
+}
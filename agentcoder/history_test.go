@@ -0,0 +1,61 @@
+package agentcoder
+
+import (
+	"bufio"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestHistoryAppendAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	history, err := loadHistory(path)
+	if err != nil || history != nil {
+		t.Fatalf("loadHistory() of a missing file = %v, %v; want an empty history", history, err)
+	}
+	for _, prompt := range []string{"first prompt", "second prompt"} {
+		if err := appendHistory(path, prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	history, err = loadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"first prompt", "second prompt"}; !reflect.DeepEqual(history, want) {
+		t.Errorf("history = %q, want %q", history, want)
+	}
+}
+
+func TestReadInteractivePromptRecallsHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	for _, prompt := range []string{"build a CLI", "build a server"} {
+		if err := appendHistory(path, prompt); err != nil {
+			t.Fatal(err)
+		}
+	}
+	scanner := bufio.NewScanner(strings.NewReader("/history\n!7\n!1\n"))
+	var prompt string
+	out := captureStdout(t, func() {
+		var err error
+		if prompt, err = readInteractivePrompt(scanner, path); err != nil {
+			t.Error(err)
+		}
+	})
+	if prompt != "build a CLI" {
+		t.Errorf("prompt = %q, want the first prompt of the history", prompt)
+	}
+	for _, want := range []string{"   1  build a CLI", "   2  build a server", "No prompt number 7 in the history"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not contain %q", out, want)
+		}
+	}
+	history, err := loadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"build a CLI", "build a server", "build a CLI"}; !reflect.DeepEqual(history, want) {
+		t.Errorf("history = %q, want the recalled prompt appended", history)
+	}
+}
@@ -0,0 +1,44 @@
+package agentcoder
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// newHTTPClient returns the HTTP client for the configured proxy, CA
+// certificate and API keys, or nil if the default client can be used with a
+// single key. Without --proxy the proxy is taken from HTTPS_PROXY and related
+// variables.
+func newHTTPClient(cfg Config) (*http.Client, error) {
+	keys := cfg.apiKeys()
+	if cfg.Proxy == "" && cfg.CACert == "" && len(keys) < 2 {
+		return nil, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.Proxy != "" {
+		proxy, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	if cfg.CACert != "" {
+		pem, err := os.ReadFile(cfg.CACert)
+		if err != nil {
+			return nil, fmt.Errorf("reading CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return &http.Client{Transport: &apiKeyTransport{ring: newKeyRing(keys), base: transport}}, nil
+}
@@ -0,0 +1,109 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+func TestHTTPClientDefault(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIKey = "key"
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if client != nil {
+		t.Error("custom HTTP client created without proxy, CA certificate or extra keys")
+	}
+}
+
+func TestHTTPClientProxy(t *testing.T) {
+	// The proxy answers the API requests itself
+	api := &fakeAPI{t: t, responses: []apiResponse{textResponse("[]", "STOP")}}
+	var hosts, keys []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hosts = append(hosts, r.URL.Host)
+		keys = append(keys, r.Header.Get("x-goog-api-key"))
+		api.ServeHTTP(w, r)
+	}))
+	t.Cleanup(proxy.Close)
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "proxy-key"
+	cfg.Proxy = proxy.URL
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey), option.WithHTTPClient(httpClient), option.WithEndpoint("http://api.example.invalid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := newClientGenerator(client, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gen.Close()
+	if _, err := gen.Generate(ctx, "Write nothing."); err != nil {
+		t.Fatal(err)
+	}
+	if len(hosts) != 1 || hosts[0] != "api.example.invalid" {
+		t.Errorf("proxied requests for hosts %v, want one for api.example.invalid", hosts)
+	}
+	if len(keys) != 1 || keys[0] != cfg.APIKey {
+		t.Errorf("API keys sent through the proxy = %v, want %q", keys, cfg.APIKey)
+	}
+}
+
+func TestHTTPClientCACert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	t.Cleanup(srv.Close)
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(path, cert, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "key"
+	cfg.CACert = path
+	client, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("request to a server signed by the CA certificate: %v", err)
+	}
+	resp.Body.Close()
+
+	// The test server is not trusted without the certificate
+	if resp, err := http.Get(srv.URL); err == nil {
+		resp.Body.Close()
+		t.Error("untrusted test server accepted by the default client")
+	}
+}
+
+func TestHTTPClientInvalidCACert(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, caCert := range []string{path, filepath.Join(t.TempDir(), "missing.pem")} {
+		cfg := DefaultConfig()
+		cfg.APIKey = "key"
+		cfg.CACert = caCert
+		if _, err := newHTTPClient(cfg); err == nil {
+			t.Errorf("newHTTPClient() accepted CA certificate %s", caCert)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ignoreFileName is the file in the output directory listing, in gitignore
+// syntax, the generated paths that must never be written.
+const ignoreFileName = ".agentcoderignore"
+
+// ignoreRule is a pattern of the ignore file.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool // The pattern started with ! and re-includes matching paths
+	dirOnly bool // The pattern ended with / and only matches directories
+}
+
+// loadIgnore reads the ignore file of dir. A missing file has no rules.
+func loadIgnore(dir string) ([]ignoreRule, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ignoreFileName))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseIgnore(string(data))
+}
+
+// parseIgnore parses patterns in gitignore syntax: blank lines and lines
+// starting with # are skipped, ! negates a pattern, a trailing / matches only
+// directories, and a pattern containing a / other than at the end is relative
+// to the output directory, while others match at any depth. *, ? and [...]
+// do not match /, and ** matches across directories.
+func parseIgnore(text string) ([]ignoreRule, error) {
+	var rules []ignoreRule
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if rule.negate = strings.HasPrefix(line, "!"); rule.negate {
+			line = line[1:]
+		}
+		line = strings.TrimPrefix(line, `\`)
+		if rule.dirOnly = strings.HasSuffix(line, "/"); rule.dirOnly {
+			line = strings.TrimRight(line, "/")
+		}
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		if line == "" {
+			continue
+		}
+		expr := globRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q in %s: %w", line, ignoreFileName, err)
+		}
+		rule.re = re
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// globRegexp translates a gitignore glob into a regular expression.
+func globRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// isIgnored reports whether the slash-separated path, or one of the
+// directories it is in, matches the rules. As in git, the last matching rule
+// wins and a file in an ignored directory cannot be re-included.
+func isIgnored(rules []ignoreRule, path string) bool {
+	parts := strings.Split(path, "/")
+	for i := range parts {
+		isDir := i < len(parts)-1
+		if matchIgnore(rules, strings.Join(parts[:i+1], "/"), isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchIgnore applies the rules to a single path.
+func matchIgnore(rules []ignoreRule, path string, isDir bool) bool {
+	ignored := false
+	for _, rule := range rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if rule.re.MatchString(path) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// filterIgnored drops the files matched by the ignore file of the output
+// directory, warning about each of them.
+func filterIgnored(outputDir string, files []File) ([]File, error) {
+	rules, err := loadIgnore(outputDir)
+	if err != nil {
+		return nil, fmt.Errorf("reading %s: %w", ignoreFileName, err)
+	}
+	if len(rules) == 0 {
+		return files, nil
+	}
+	var kept []File
+	for _, file := range files {
+		if isIgnored(rules, filepath.ToSlash(filepath.Clean(file.Name))) {
+			fmt.Printf("Warning: skipping %s, it matches %s\n", file.Name, ignoreFileName)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept, nil
+}
@@ -0,0 +1,87 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestIsIgnored(t *testing.T) {
+	rules, err := parseIgnore(`# Protected files
+README.md
+/.github/
+*.lock
+!keep.lock
+docs/**/*.html
+gen?/
+build/
+!build/output.txt
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"README.md", true},
+		{"pkg/README.md", true},
+		{"README.txt", false},
+		{".github/workflows/ci.yml", true},
+		{"pkg/.github/ci.yml", false},
+		{"go.lock", true},
+		{"vendor/deps.lock", true},
+		{"keep.lock", false},
+		{"docs/a/b/index.html", true},
+		{"docs/index.html", true},
+		{"docs/index.md", false},
+		{"gen1/x.go", true},
+		{"gen1", false},
+		{"build/output.txt", true},
+		{"main.go", false},
+	}
+	for _, tt := range tests {
+		if got := isIgnored(rules, tt.path); got != tt.want {
+			t.Errorf("isIgnored(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIgnoreFileProtectsExistingFile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	for name, content := range map[string]string{
+		ignoreFileName: "README.md\n",
+		"README.md":    "# Hand-written\n",
+	} {
+		if err := os.WriteFile(filepath.Join(cfg.OutputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := []File{{Name: "README.md", Code: "# Generated\n"}, {Name: "main.txt", Code: "hello"}}
+	var err error
+	out := captureStdout(t, func() { files, err = filterFiles(cfg, files) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := fileNames(files); len(names) != 1 || names[0] != "main.txt" {
+		t.Errorf("files = %v, want only main.txt", names)
+	}
+	if want := "skipping README.md, it matches .agentcoderignore"; !strings.Contains(out, want) {
+		t.Errorf("output %q does not contain %q", out, want)
+	}
+	captureStdout(t, func() { err = writeFiles(osFS{}, cfg, files) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(cfg.OutputDir, "README.md")); string(data) != "# Hand-written\n" {
+		t.Errorf("README.md = %q, want it untouched", data)
+	}
+}
+
+func TestInvalidIgnorePattern(t *testing.T) {
+	if _, err := parseIgnore("[z-a]\n"); err == nil {
+		t.Error("parseIgnore() succeeded, want an error for an invalid range")
+	}
+}
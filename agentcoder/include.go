@@ -0,0 +1,69 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// expandIncludes replaces each @path token in prompt with the content of the
+// file at path. A token starts at the beginning of the prompt or after
+// whitespace, so addresses such as user@example.com are left alone, and \@
+// stands for a literal @. Trailing punctuation is not part of the path unless
+// a file of that name exists. The included content may total at most
+// maxChars characters; zero means no limit.
+func expandIncludes(prompt string, maxChars int) (string, error) {
+	var b strings.Builder
+	included := 0
+	runes := []rune(prompt)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if c == '\\' && i+1 < len(runes) && runes[i+1] == '@' {
+			b.WriteRune('@')
+			i++
+			continue
+		}
+		if c != '@' || (i > 0 && !unicode.IsSpace(runes[i-1])) {
+			b.WriteRune(c)
+			continue
+		}
+		end := i + 1
+		for end < len(runes) && !unicode.IsSpace(runes[end]) {
+			end++
+		}
+		path := string(runes[i+1 : end])
+		if path == "" {
+			b.WriteRune(c)
+			continue
+		}
+		data, rest, err := readInclude(path)
+		if err != nil {
+			return "", err
+		}
+		if included += len([]rune(string(data))); maxChars > 0 && included > maxChars {
+			return "", fmt.Errorf("included files exceed the limit of %d characters at @%s", maxChars, path)
+		}
+		b.Write(data)
+		b.WriteString(rest)
+		i = end - 1
+	}
+	return b.String(), nil
+}
+
+// readInclude reads the file referenced by an @ token, retrying without the
+// trailing punctuation, which is returned to be kept in the prompt.
+func readInclude(path string) (data []byte, rest string, err error) {
+	for trimmed := path; trimmed != ""; trimmed = trimmed[:len(trimmed)-1] {
+		data, err = os.ReadFile(trimmed)
+		if err == nil {
+			return data, path[len(trimmed):], nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) || !strings.ContainsRune(".,;:!?)]}'\"", rune(trimmed[len(trimmed)-1])) {
+			break
+		}
+	}
+	return nil, "", fmt.Errorf("including @%s: %w", path, err)
+}
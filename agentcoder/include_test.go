@@ -0,0 +1,72 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandIncludes(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	if err := os.WriteFile(spec, []byte("GET /users returns the users."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		prompt, want string
+	}{
+		{"Implement @" + spec + " in Go.", "Implement GET /users returns the users. in Go."},
+		{"@" + spec, "GET /users returns the users."},
+		{"Implement @" + spec + ", then test it.", "Implement GET /users returns the users., then test it."},
+		{`Mail \@admin about it.`, "Mail @admin about it."},
+		{"Mail admin@example.com about it.", "Mail admin@example.com about it."},
+		{"A lone @ stays.", "A lone @ stays."},
+	}
+	for _, tt := range tests {
+		got, err := expandIncludes(tt.prompt, 0)
+		if err != nil {
+			t.Errorf("expandIncludes(%q): %v", tt.prompt, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("expandIncludes(%q) = %q, want %q", tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestExpandIncludesErrors(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "spec.md")
+	if err := os.WriteFile(spec, []byte(strings.Repeat("x", 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := expandIncludes("Use @"+spec+" and @"+spec, 150); err == nil || !strings.Contains(err.Error(), "exceed the limit of 150 characters") {
+		t.Errorf("expandIncludes() over the limit error = %v, want the limit", err)
+	}
+	if _, err := expandIncludes("Use @"+spec, 100); err != nil {
+		t.Errorf("expandIncludes() at the limit: %v", err)
+	}
+	missing := filepath.Join(dir, "missing.md")
+	if _, err := expandIncludes("Use @"+missing+".", 0); err == nil || !strings.Contains(err.Error(), "including @"+missing+".") {
+		t.Errorf("expandIncludes() of a missing file error = %v, want the reference", err)
+	}
+}
+
+func TestPromptIncludeInFinalPrompt(t *testing.T) {
+	spec := filepath.Join(t.TempDir(), "spec.md")
+	if err := os.WriteFile(spec, []byte("The tool prints the time."), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	cfg.PromptInclude = true
+	cfg.Prompt = "Write a tool. Spec: @" + spec
+	req, err := readPrompt(context.Background(), cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Text, "Spec: The tool prints the time.") || strings.Contains(req.Text, "@"+spec) {
+		t.Errorf("prompt %q does not replace the token with the file", req.Text)
+	}
+}
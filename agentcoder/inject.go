@@ -0,0 +1,107 @@
+package agentcoder
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// insertMarker matches a marker comment such as "// AGENTCODER:INSERT foo.go"
+// and captures the name of the generated file to insert there.
+var insertMarker = regexp.MustCompile(`AGENTCODER:INSERT\s+(\S+)`)
+
+// markerLocation is where the marker of a generated file was found.
+type markerLocation struct {
+	Path string // Path of the existing file containing the marker
+	Line int    // Index of the marker line
+}
+
+// findMarkers scans the files below dir for insert markers and returns their
+// locations by the name in the marker.
+func findMarkers(dir string) (map[string]markerLocation, error) {
+	markers := make(map[string]markerLocation)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		data, err := osFS{}.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if m := insertMarker.FindStringSubmatch(line); m != nil {
+				if prev, ok := markers[m[1]]; ok {
+					return fmt.Errorf("marker for %s found in both %s and %s", m[1], prev.Path, path)
+				}
+				markers[m[1]] = markerLocation{Path: path, Line: i}
+			}
+		}
+		return nil
+	})
+	return markers, err
+}
+
+// injectFiles inserts each generated file at the marker naming it in the
+// existing files of the output directory, replacing the marker line and
+// keeping its indentation. Markers name files with forward slashes on every
+// platform. Nothing is written unless every file has a marker.
+func injectFiles(fsys FS, cfg Config, files []File) error {
+	markers, err := findMarkers(cfg.OutputDir)
+	if err != nil {
+		return fmt.Errorf("finding insert markers: %w", err)
+	}
+
+	// Group the insertions by the file they go into
+	inserts := make(map[string]map[int]string)
+	var missing []string
+	for _, file := range files {
+		loc, ok := markers[filepath.ToSlash(file.Name)]
+		if !ok {
+			missing = append(missing, file.Name)
+			continue
+		}
+		if inserts[loc.Path] == nil {
+			inserts[loc.Path] = make(map[int]string)
+		}
+		inserts[loc.Path][loc.Line] = file.Code
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no AGENTCODER:INSERT marker for %s", strings.Join(missing, ", "))
+	}
+
+	for path, at := range inserts {
+		data, err := fsys.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		lines := strings.Split(string(data), "\n")
+		var out []string
+		for i, line := range lines {
+			code, ok := at[i]
+			if !ok {
+				out = append(out, line)
+				continue
+			}
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			for _, l := range strings.Split(strings.TrimRight(code, "\n"), "\n") {
+				if l != "" {
+					l = indent + l
+				}
+				out = append(out, l)
+			}
+		}
+		if err := fsys.WriteFile(path, []byte(strings.Join(out, "\n")), 0644); err != nil {
+			return err
+		}
+		fmt.Printf("Inserted %d file(s) into %s\n", len(at), path)
+	}
+	return nil
+}
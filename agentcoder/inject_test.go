@@ -0,0 +1,83 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInjectAtMarker(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	path := filepath.Join(cfg.OutputDir, "main.go")
+	skeleton := "package main\n\nfunc main() {\n\t// AGENTCODER:INSERT body.go\n}\n"
+	if err := os.WriteFile(path, []byte(skeleton), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []File{{Name: "body.go", Code: "x := 1\n\nprintln(x)\n"}}
+	if err := injectFiles(osFS{}, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package main\n\nfunc main() {\n\tx := 1\n\n\tprintln(x)\n}\n"
+	if string(data) != want {
+		t.Errorf("injected file = %q, want %q", data, want)
+	}
+}
+
+func TestInjectNestedName(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	path := filepath.Join(cfg.OutputDir, "routes.go")
+	skeleton := "package main\n\n// AGENTCODER:INSERT handlers/user.go\n"
+	if err := os.WriteFile(path, []byte(skeleton), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The marker uses forward slashes whatever the separator of the name
+	files := []File{{Name: filepath.Join("handlers", "user.go"), Code: "func user() {}\n"}}
+	if err := injectFiles(osFS{}, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "package main\n\nfunc user() {}\n" {
+		t.Errorf("injected file = %q", data)
+	}
+}
+
+func TestInjectMissingMarker(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	path := filepath.Join(cfg.OutputDir, "main.go")
+	skeleton := "package main\n\n// AGENTCODER:INSERT a.go\n"
+	if err := os.WriteFile(path, []byte(skeleton), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	files := []File{{Name: "a.go", Code: "var a int\n"}, {Name: "b.go", Code: "var b int\n"}}
+	err := injectFiles(osFS{}, cfg, files)
+	if err == nil || !strings.Contains(err.Error(), "no AGENTCODER:INSERT marker for b.go") {
+		t.Fatalf("injectFiles() error = %v, want a missing marker for b.go", err)
+	}
+	// Nothing is written unless every file has a marker
+	if data, _ := os.ReadFile(path); string(data) != skeleton {
+		t.Errorf("skeleton changed to %q", data)
+	}
+}
+
+func TestInjectDuplicateMarker(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(cfg.OutputDir, name), []byte("// AGENTCODER:INSERT x.go\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := injectFiles(osFS{}, cfg, []File{{Name: "x.go", Code: "var x int\n"}}); err == nil {
+		t.Error("marker found in two files accepted")
+	}
+}
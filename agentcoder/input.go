@@ -0,0 +1,39 @@
+package agentcoder
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// isInteractive reports whether f is attached to a terminal rather than a
+// pipe or a regular file.
+func isInteractive(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm prints question and reads a yes/no answer from scanner. Anything
+// other than "y" or "yes" counts as no.
+func confirm(scanner *bufio.Scanner, question string) bool {
+	fmt.Printf("%s [y/N]: ", question)
+	if !scanner.Scan() {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(scanner.Text()))
+	return answer == "y" || answer == "yes"
+}
+
+// terminalConfirm returns a function asking for confirmation on stdin, or nil
+// if stdin is not a terminal to answer from.
+func terminalConfirm() func(question string) bool {
+	if !isInteractive(os.Stdin) {
+		return nil
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	return func(question string) bool { return confirm(scanner, question) }
+}
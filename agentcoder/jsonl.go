@@ -0,0 +1,58 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// jsonlReporter writes one JSON object per written file followed by a summary
+// object, for consumption by CI dashboards and log processors. It is safe for
+// concurrent use; each object is written as a single line.
+type jsonlReporter struct {
+	mu     sync.Mutex
+	enc    *json.Encoder
+	counts map[string]int
+	total  int
+	ms     float64
+}
+
+// jsonlSummary is the final object written by a jsonlReporter.
+type jsonlSummary struct {
+	Type   string         `json:"type"`   // Always "summary"
+	Files  int            `json:"files"`  // Number of files reported
+	Counts map[string]int `json:"counts"` // Number of files per status
+
+	// Milliseconds taken to process all files, with --verbose
+	DurationMS float64 `json:"duration_ms,omitempty"`
+
+	// Set when the run timed out and only the files completed before were
+	// written
+	Partial bool `json:"partial,omitempty"`
+}
+
+// newJSONLReporter returns a reporter writing to w.
+func newJSONLReporter(w io.Writer) *jsonlReporter {
+	return &jsonlReporter{enc: json.NewEncoder(w), counts: make(map[string]int)}
+}
+
+// Report writes the outcome of one file.
+func (r *jsonlReporter) Report(file File, result writeResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.counts[result.Status]++
+	r.total++
+	r.ms += result.DurationMS
+	r.enc.Encode(struct {
+		Type string `json:"type"`
+		writeResult
+	}{"file", result})
+}
+
+// Summary writes the summary of all reported files, marking it as partial if
+// the run timed out.
+func (r *jsonlReporter) Summary(partial bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.enc.Encode(jsonlSummary{Type: "summary", Files: r.total, Counts: r.counts, DurationMS: r.ms, Partial: partial})
+}
@@ -0,0 +1,124 @@
+package agentcoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestJSONLReporter(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newJSONLReporter(&buf)
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	files := []File{{Name: "a.go", Code: "package a\n"}, {Name: "../b.go", Code: "package b\n"}, {Name: "c.txt", Code: "c"}}
+	if err := writeFilesFunc(newMemFS(), cfg, files, reporter.Report); err != nil {
+		t.Fatal(err)
+	}
+	reporter.Summary(false)
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != len(files)+1 {
+		t.Fatalf("%d lines, want one per file and a summary:\n%s", len(lines), buf.String())
+	}
+	for i, file := range files {
+		var line struct {
+			Type   string `json:"type"`
+			Path   string `json:"path"`
+			Size   int    `json:"size"`
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line.Type != "file" || line.Path != file.Name || line.Size != len(file.Code) || line.Status == "" {
+			t.Errorf("line %d = %s, want the outcome of %s", i+1, lines[i], file.Name)
+		}
+	}
+	var summary jsonlSummary
+	if err := json.Unmarshal([]byte(lines[len(files)]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Type != "summary" || summary.Files != 3 || summary.Counts[writeWritten] != 2 || summary.Counts[writeRejected] != 1 {
+		t.Errorf("summary = %+v, want 2 written and 1 rejected", summary)
+	}
+}
+
+func TestJSONLReporterConcurrent(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := newJSONLReporter(&buf)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reporter.Report(File{Name: "f"}, writeResult{Path: strings.Repeat("x", 100), Status: writeWritten})
+		}()
+	}
+	wg.Wait()
+	reporter.Summary(false)
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 51 {
+		t.Fatalf("%d lines, want 51", len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Errorf("interleaved line %q", line)
+		}
+	}
+}
+
+func TestJSONLReporterTimings(t *testing.T) {
+	files := []File{{Name: "a.go", Code: "package a\n"}, {Name: "b/c.txt", Code: "c"}}
+	for _, verbose := range []bool{false, true} {
+		var buf bytes.Buffer
+		reporter := newJSONLReporter(&buf)
+		cfg := DefaultConfig()
+		cfg.OutputDir = t.TempDir()
+		cfg.Verbose = verbose
+		out := captureStdout(t, func() {
+			if err := writeFilesFunc(osFS{}, cfg, files, reporter.Report); err != nil {
+				t.Fatal(err)
+			}
+			reporter.Summary(false)
+		})
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(lines) != len(files)+1 {
+			t.Fatalf("%d lines, want one per file and a summary:\n%s", len(lines), buf.String())
+		}
+		var total float64
+		for i, file := range files {
+			var line struct {
+				Path       string   `json:"path"`
+				DurationMS *float64 `json:"duration_ms"`
+			}
+			if err := json.Unmarshal([]byte(lines[i]), &line); err != nil {
+				t.Fatal(err)
+			}
+			if !verbose {
+				if line.DurationMS != nil {
+					t.Errorf("line %d = %s, want no timing without --verbose", i+1, lines[i])
+				}
+				continue
+			}
+			if line.DurationMS == nil || *line.DurationMS <= 0 {
+				t.Errorf("line %d = %s, want the time taken for %s", i+1, lines[i], file.Name)
+				continue
+			}
+			total += *line.DurationMS
+			if !strings.Contains(out, file.Name+" processed in ") {
+				t.Errorf("output %q does not print the time taken for %s", out, file.Name)
+			}
+		}
+		var summary jsonlSummary
+		if err := json.Unmarshal([]byte(lines[len(files)]), &summary); err != nil {
+			t.Fatal(err)
+		}
+		if summary.DurationMS != total {
+			t.Errorf("summary duration with verbose %v = %v, want the sum %v of the files", verbose, summary.DurationMS, total)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package agentcoder
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultKeyCooldown is how long a key that hit a rate limit is left unused
+// when the response does not say when to retry.
+const defaultKeyCooldown = time.Minute
+
+// apiKeys returns the configured API keys, the one given with --key first,
+// without duplicates.
+func (cfg Config) apiKeys() []string {
+	var keys []string
+	for _, key := range append([]string{cfg.APIKey}, cfg.APIKeys...) {
+		if key != "" && !slices.Contains(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// keyRing hands out API keys, skipping those cooling down after a rate limit.
+// It is safe for concurrent use.
+type keyRing struct {
+	mu       sync.Mutex
+	keys     []string
+	current  int
+	cooldown []time.Time // Time until which each key is not used
+}
+
+func newKeyRing(keys []string) *keyRing {
+	return &keyRing{keys: keys, cooldown: make([]time.Time, len(keys))}
+}
+
+// pick returns the index of the key to use, the current one unless it is
+// cooling down. If every key is cooling down it returns the current one.
+func (r *keyRing) pick(now time.Time) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n := range r.keys {
+		i := (r.current + n) % len(r.keys)
+		if !now.Before(r.cooldown[i]) {
+			r.current = i
+			return i
+		}
+	}
+	return r.current
+}
+
+// limited puts the key with index i on cooldown for d and reports whether
+// another key is available.
+func (r *keyRing) limited(i int, now time.Time, d time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cooldown[i] = now.Add(d)
+	for j := range r.keys {
+		if !now.Before(r.cooldown[j]) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the cooldown requested by a rate limited response.
+func retryAfter(resp *http.Response) time.Duration {
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return defaultKeyCooldown
+}
+
+// apiKeyTransport authenticates requests with the keys of the ring, which the
+// client library does not do itself when given a custom HTTP client. A
+// request rejected with 429 Too Many Requests is retried with the next key.
+type apiKeyTransport struct {
+	ring *keyRing
+	base http.RoundTripper
+}
+
+func (t *apiKeyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		now := time.Now()
+		i := t.ring.pick(now)
+		r := req.Clone(req.Context())
+		r.Header.Set("x-goog-api-key", t.ring.keys[i])
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			r.Body = body
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || resp.StatusCode != http.StatusTooManyRequests || len(t.ring.keys) == 1 {
+			return resp, err
+		}
+		if attempt+1 >= len(t.ring.keys) || (req.Body != nil && req.GetBody == nil) || !t.ring.limited(i, now, retryAfter(resp)) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		fmt.Printf("API key %d of %d hit a rate limit, retrying with the next key\n", i+1, len(t.ring.keys))
+	}
+}
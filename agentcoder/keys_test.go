@@ -0,0 +1,103 @@
+package agentcoder
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+func TestAPIKeys(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIKey = "a"
+	cfg.APIKeys = []string{"b", "a", "", "c"}
+	if got, want := cfg.apiKeys(), []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("apiKeys() = %v, want %v", got, want)
+	}
+}
+
+func TestRotateKeyOnRateLimit(t *testing.T) {
+	var keys []string
+	api := &fakeAPI{t: t, responses: []apiResponse{
+		errorResponse(http.StatusTooManyRequests, "Resource has been exhausted"),
+		textResponse("[]", "STOP"),
+	}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("x-goog-api-key"))
+		api.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	cfg := DefaultConfig()
+	cfg.APIKey = "first-key"
+	cfg.APIKeys = []string{"second-key"}
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	client, err := genai.NewClient(ctx, option.WithAPIKey(cfg.APIKey), option.WithHTTPClient(httpClient), option.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := newClientGenerator(client, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer gen.Close()
+
+	out := captureStdout(t, func() {
+		if _, err := gen.Generate(ctx, "Write nothing."); err != nil {
+			t.Error(err)
+		}
+	})
+	if want := []string{"first-key", "second-key"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("requests sent with keys %v, want %v", keys, want)
+	}
+	if !strings.Contains(out, "API key 1 of 2 hit a rate limit") {
+		t.Errorf("output %q does not report the rate limit", out)
+	}
+	for _, key := range cfg.apiKeys() {
+		if strings.Contains(out, key) {
+			t.Errorf("output contains API key %q", key)
+		}
+	}
+}
+
+func TestKeyRingCooldown(t *testing.T) {
+	ring := newKeyRing([]string{"a", "b"})
+	now := time.Now()
+	if i := ring.pick(now); i != 0 {
+		t.Fatalf("pick() = %d, want 0", i)
+	}
+	if !ring.limited(0, now, time.Minute) {
+		t.Error("limited() reports no other key available")
+	}
+	if i := ring.pick(now); i != 1 {
+		t.Errorf("pick() during the cooldown = %d, want 1", i)
+	}
+	if ring.limited(1, now, time.Minute) {
+		t.Error("limited() reports a key available while all are cooling down")
+	}
+	// Once the cooldown is over the first key is used again
+	if i := ring.pick(now.Add(2 * time.Minute)); i != 1 {
+		t.Errorf("pick() after the cooldown = %d, want the current key 1", i)
+	}
+	ring.limited(1, now.Add(2*time.Minute), time.Minute)
+	if i := ring.pick(now.Add(2 * time.Minute)); i != 0 {
+		t.Errorf("pick() = %d, want 0", i)
+	}
+}
+
+func TestRedactKeys(t *testing.T) {
+	got := redactKeys("use first-key or second-key", []string{"first-key", "second-key"})
+	if want := "use [REDACTED] or [REDACTED]"; got != want {
+		t.Errorf("redactKeys() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,76 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// languageExtensions maps file extensions to the language they are written in.
+var languageExtensions = map[string]string{
+	".go":  "go",
+	".py":  "python",
+	".js":  "javascript",
+	".jsx": "javascript",
+	".ts":  "typescript",
+	".tsx": "typescript",
+	".rs":  "rust",
+	".css": "css",
+}
+
+// languageOf returns the language of the file with the given name, or an
+// empty string if it is not known.
+func languageOf(name string) string {
+	return languageExtensions[strings.ToLower(filepath.Ext(name))]
+}
+
+// detectLanguage returns the language most of the files are written in and
+// how many files are, or an empty string if none is known.
+func detectLanguage(files []File) (string, int) {
+	counts := make(map[string]int)
+	best := ""
+	for _, file := range files {
+		lang := languageOf(file.Name)
+		if lang == "" {
+			continue
+		}
+		counts[lang]++
+		if counts[lang] > counts[best] || counts[lang] == counts[best] && lang < best {
+			best = lang
+		}
+	}
+	return best, counts[best]
+}
+
+// resolveLanguage returns the language whose validators are enabled for the
+// files: the one given with --language, none for "none", or the detected one
+// for "auto".
+func resolveLanguage(language string, files []File) string {
+	switch language {
+	case "auto":
+		lang, n := detectLanguage(files)
+		if lang != "" {
+			fmt.Printf("Detected language: %s (%d of %d file(s))\n", lang, n, len(files))
+		}
+		return lang
+	case "none":
+		return ""
+	}
+	return language
+}
+
+// autoFormat reports whether a file not covered by --format-code is formatted
+// anyway because it is in the detected language. External formatters are only
+// used if they are installed.
+func autoFormat(cfg Config, name string) bool {
+	if cfg.Language == "" || languageOf(name) != cfg.Language {
+		return false
+	}
+	command := cfg.Formatters[strings.ToLower(filepath.Ext(name))]
+	if command == "" {
+		return cfg.Language == "go"
+	}
+	_, err := exec.LookPath(strings.Fields(command)[0])
+	return err == nil
+}
@@ -0,0 +1,66 @@
+package agentcoder
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		names []string
+		lang  string
+		n     int
+	}{
+		{names: []string{"main.go", "util.go", "script.py", "README.md"}, lang: "go", n: 2},
+		{names: []string{"app.TS", "view.tsx", "main.go"}, lang: "typescript", n: 2},
+		{names: []string{"a.py", "b.rs"}, lang: "python", n: 1},
+		{names: []string{"README.md", "Makefile"}},
+	}
+	for _, tt := range tests {
+		var files []File
+		for _, name := range tt.names {
+			files = append(files, File{Name: name})
+		}
+		if lang, n := detectLanguage(files); lang != tt.lang || n != tt.n {
+			t.Errorf("detectLanguage(%v) = %q, %d, want %q, %d", tt.names, lang, n, tt.lang, tt.n)
+		}
+	}
+}
+
+func TestDetectedLanguageFormatsGo(t *testing.T) {
+	files := []File{
+		{Name: "main.go", Code: "package main\nfunc main() {\nrun()\n}\n"},
+		{Name: "run.go", Code: "package main\nfunc run() {}\n"},
+		{Name: "notes.txt", Code: "package main\nfunc  x\n"},
+	}
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{language: "auto", want: "package main\n\nfunc main() {\n\trun()\n}\n"},
+		{language: "go", want: "package main\n\nfunc main() {\n\trun()\n}\n"},
+		{language: "none", want: files[0].Code},
+		{language: "python", want: files[0].Code},
+	}
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			fsys := newMemFS()
+			cfg := DefaultConfig()
+			cfg.OutputDir = "out"
+			cfg.Language = tt.language
+			if err := writeFiles(fsys, cfg, files); err != nil {
+				t.Fatal(err)
+			}
+			data, err := fsys.ReadFile(filepath.Join("out", "main.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("main.go = %q, want %q", data, tt.want)
+			}
+			if data, _ := fsys.ReadFile(filepath.Join("out", "notes.txt")); string(data) != files[2].Code {
+				t.Errorf("notes.txt changed to %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package agentcoder
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// proseExtensions are the extensions of files that are not code, whose line
+// length is not checked.
+var proseExtensions = []string{".md", ".markdown", ".txt", ".rst", ".csv", ".json", ".svg"}
+
+// maxReportedLines is the number of long lines reported per file.
+const maxReportedLines = 5
+
+// checkLineLength reports the lines of the code files longer than max
+// characters, with their location. In strict mode long lines fail the run. A
+// max of zero disables the check.
+func checkLineLength(files []File, max int, strict bool) error {
+	if max <= 0 {
+		return nil
+	}
+	total := 0
+	for _, file := range files {
+		if slices.Contains(proseExtensions, strings.ToLower(filepath.Ext(file.Name))) {
+			continue
+		}
+		long := 0
+		for i, line := range strings.Split(file.Code, "\n") {
+			n := utf8.RuneCountInString(strings.TrimSuffix(line, "\r"))
+			if n <= max {
+				continue
+			}
+			if long++; long <= maxReportedLines {
+				fmt.Printf("Warning: %s:%d is %d characters long, more than the limit of %d\n", file.Name, i+1, n, max)
+			}
+		}
+		if long > maxReportedLines {
+			fmt.Printf("Warning: %s has %d more long line(s)\n", file.Name, long-maxReportedLines)
+		}
+		total += long
+	}
+	if strict && total > 0 {
+		return fmt.Errorf("%d line(s) exceed the limit of %d characters", total, max)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckLineLength(t *testing.T) {
+	long := strings.Repeat("x", 81)
+	files := []File{
+		{Name: "main.go", Code: "package main\n\nvar s = \"" + long + "\"\n"},
+		{Name: "short.go", Code: "package main\n\n// " + strings.Repeat("é", 77) + "\n"},
+		{Name: "README.md", Code: long + "\n"},
+	}
+	out := captureStdout(t, func() {
+		if err := checkLineLength(files, 80, false); err != nil {
+			t.Error(err)
+		}
+	})
+	if want := "Warning: main.go:3 is 91 characters long, more than the limit of 80\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	captureStdout(t, func() {
+		if err := checkLineLength(files, 80, true); err == nil {
+			t.Error("long line accepted in strict mode")
+		}
+	})
+	if err := checkLineLength(files, 0, true); err != nil {
+		t.Errorf("disabled check failed: %v", err)
+	}
+}
+
+func TestCheckLineLengthReportLimit(t *testing.T) {
+	code := strings.Repeat(strings.Repeat("x", 20)+"\n", maxReportedLines+3)
+	out := captureStdout(t, func() { checkLineLength([]File{{Name: "gen.py", Code: code}}, 10, false) })
+	if n := strings.Count(out, "characters long"); n != maxReportedLines {
+		t.Errorf("%d long lines reported, want %d", n, maxReportedLines)
+	}
+	if !strings.Contains(out, "Warning: gen.py has 3 more long line(s)") {
+		t.Errorf("output %q does not count the unreported lines", out)
+	}
+}
@@ -0,0 +1,32 @@
+package agentcoder
+
+import (
+	"regexp"
+	"strings"
+)
+
+// minPromptWords is the number of words below which a prompt is considered too
+// short to describe the code wanted.
+const minPromptWords = 8
+
+// languageHint matches the names of languages, frameworks and targets.
+var languageHint = regexp.MustCompile(`(?i)\b(go|golang|python|javascript|typescript|node(\.js)?|rust|java|kotlin|swift|c|c\+\+|c#|ruby|php|html|css|sql|bash|shell|react|vue|svelte|django|flask|cli|wasm)\b`)
+
+// structureHint matches mentions of files, directories or packages.
+var structureHint = regexp.MustCompile(`(?i)\b(files?|director(y|ies)|folders?|packages?|modules?|layout|structure)\b|\w\.[a-z]{1,4}\b`)
+
+// lintPrompt returns suggestions for a prompt that is likely too vague to
+// produce good code.
+func lintPrompt(prompt string) []string {
+	var suggestions []string
+	if n := len(strings.Fields(prompt)); n < minPromptWords {
+		suggestions = append(suggestions, "The prompt is very short; describe what the code should do, its inputs and its outputs.")
+	}
+	if !languageHint.MatchString(prompt) && !mentionsGo(prompt) {
+		suggestions = append(suggestions, "Name the language or framework to use, for example \"in Go\" or \"a React component\".")
+	}
+	if !structureHint.MatchString(prompt) {
+		suggestions = append(suggestions, "Say which files or packages you expect, for example \"a main.go and a handler package\".")
+	}
+	return suggestions
+}
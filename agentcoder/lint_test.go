@@ -0,0 +1,45 @@
+package agentcoder
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestLintPrompt(t *testing.T) {
+	tests := []struct {
+		prompt string
+		want   int
+	}{
+		{prompt: "app", want: 3},
+		{prompt: "make an app in Go", want: 2},
+		{prompt: "Write a Python CLI that counts the words of a file given as argument", want: 0},
+		{prompt: "Write a command that counts the words in its input, with a main.go and a counter package", want: 0},
+	}
+	for _, tt := range tests {
+		if got := lintPrompt(tt.prompt); len(got) != tt.want {
+			t.Errorf("lintPrompt(%q) = %q, want %d suggestion(s)", tt.prompt, got, tt.want)
+		}
+	}
+}
+
+func TestLintPromptStrict(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "app"
+	cfg.LintPrompt = true
+
+	var err error
+	out := captureStdout(t, func() { _, err = readPrompt(context.Background(), cfg, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "The prompt could be more specific:\n  - The prompt is very short") {
+		t.Errorf("output %q has no suggestions", out)
+	}
+
+	cfg.Strict = true
+	captureStdout(t, func() { _, err = readPrompt(context.Background(), cfg, nil) })
+	if err == nil {
+		t.Error("vague prompt accepted with --strict")
+	}
+}
@@ -0,0 +1,173 @@
+package agentcoder
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// manifestFileName is the name of the manifest written to the output directory.
+const manifestFileName = "agent_coder_manifest.json"
+
+// Manifest records the files written by a run so later edits can be detected.
+type Manifest struct {
+	SchemaVersion int             `json:"schema_version"`     // Version of the manifest format
+	Model         string          `json:"model"`              // Model that generated the files
+	GeneratedAt   time.Time       `json:"generated_at"`       // Time the manifest was written
+	Files         []ManifestEntry `json:"files"`              // Written files
+	ReadOnly      bool            `json:"readonly,omitempty"` // Whether the files were made read-only
+}
+
+// ManifestEntry records the content hash of a written file and the problems
+// found with it. Files that could not be written have no hash.
+type ManifestEntry struct {
+	Name   string            `json:"file_name"`        // Name of the file relative to the output directory
+	SHA256 string            `json:"sha256,omitempty"` // Hex-encoded SHA-256 of the file contents
+	Size   int               `json:"size"`             // Size of the file in bytes
+	Errors []validationIssue `json:"errors,omitempty"` // Problems found while validating and writing the file
+}
+
+// hashContent returns the hex-encoded SHA-256 of data.
+func hashContent(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// newManifest hashes the files as they are stored in the output directory,
+// after any formatting was applied, and records the problems reported in the
+// write results.
+func newManifest(fsys FS, cfg Config, files []File, results []writeResult) (Manifest, error) {
+	manifest := Manifest{SchemaVersion: schemaVersion, Model: cfg.Model, GeneratedAt: time.Now().UTC(), ReadOnly: cfg.Readonly}
+	issues := make(map[string][]validationIssue, len(results))
+	for _, result := range results {
+		issues[result.Path] = append(issues[result.Path], result.Issues...)
+	}
+	for _, file := range files {
+		entry := ManifestEntry{Name: file.Name, Errors: issues[file.Name]}
+		data, err := fsys.ReadFile(filepath.Join(cfg.OutputDir, file.Name))
+		if err != nil && len(entry.Errors) > 0 {
+			// Not written because of the recorded problems
+			entry.Size = len(file.Code)
+			manifest.Files = append(manifest.Files, entry)
+			continue
+		}
+		if err != nil {
+			return manifest, err
+		}
+		entry.SHA256, entry.Size = hashContent(data), len(data)
+		manifest.Files = append(manifest.Files, entry)
+	}
+	if cfg.sortFiles() {
+		sort.SliceStable(manifest.Files, func(i, j int) bool { return manifest.Files[i].Name < manifest.Files[j].Name })
+	}
+	return manifest, nil
+}
+
+// writeManifest stores the manifest in the output directory.
+func writeManifest(fsys FS, dir string, manifest Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(dir, manifestFileName)
+	if err := fsys.WriteFile(path, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Manifest written to %s\n", path)
+	return nil
+}
+
+// saveManifest hashes the written files and stores the manifest, with the
+// problems found in the write results, in the output directory, reporting any
+// failure.
+func saveManifest(fsys FS, cfg Config, files []File, results []writeResult) {
+	manifest, err := newManifest(fsys, cfg, files, results)
+	if err == nil {
+		err = writeManifest(fsys, cfg.OutputDir, manifest)
+	}
+	if err != nil {
+		fmt.Printf("Error writing manifest: %v\n", err)
+	}
+}
+
+// loadManifest reads the manifest from dir, upgrading manifests written by
+// older releases.
+func loadManifest(fsys FS, dir string) (Manifest, error) {
+	var manifest Manifest
+	path := filepath.Join(dir, manifestFileName)
+	data, err := fsys.ReadFile(path)
+	if err != nil {
+		return manifest, err
+	}
+	if err := decodeVersioned(data, "manifest", manifestMigrations, &manifest); err != nil {
+		return manifest, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	return manifest, nil
+}
+
+// verifyManifest re-hashes the files recorded in the manifest and returns a
+// description of each file that is missing or has changed, or was made
+// writable after being generated read-only.
+func verifyManifest(fsys FS, dir string, manifest Manifest) ([]string, error) {
+	var problems []string
+	for _, entry := range manifest.Files {
+		if entry.SHA256 == "" {
+			// Never written
+			continue
+		}
+		data, err := fsys.ReadFile(filepath.Join(dir, entry.Name))
+		if errors.Is(err, fs.ErrNotExist) {
+			problems = append(problems, fmt.Sprintf("%s: missing", entry.Name))
+			continue
+		}
+		if err != nil {
+			return problems, err
+		}
+		if hashContent(data) != entry.SHA256 {
+			problems = append(problems, fmt.Sprintf("%s: modified since generation", entry.Name))
+			continue
+		}
+		if manifest.ReadOnly {
+			info, err := fsys.Stat(filepath.Join(dir, entry.Name))
+			if err == nil && info.Mode().Perm()&0222 != 0 {
+				problems = append(problems, fmt.Sprintf("%s: made writable since generation", entry.Name))
+			}
+		}
+	}
+	return problems, nil
+}
+
+// runVerify implements the verify subcommand, which checks the files in the
+// output directory against its manifest and exits non-zero on any mismatch.
+func runVerify(args []string) {
+	cfg, _, err := parseConfig(args, nil)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	manifest, err := loadManifest(osFS{}, cfg.OutputDir)
+	if err != nil {
+		fmt.Printf("Error reading manifest: %v\n", err)
+		os.Exit(1)
+	}
+	problems, err := verifyManifest(osFS{}, cfg.OutputDir, manifest)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("%d of %d file(s) do not match the manifest\n", len(problems), len(manifest.Files))
+		os.Exit(1)
+	}
+	fmt.Printf("All %d file(s) match the manifest\n", len(manifest.Files))
+}
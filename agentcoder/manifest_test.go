@@ -0,0 +1,86 @@
+package agentcoder
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeWithManifest writes the files to fsys and saves their manifest.
+func writeWithManifest(t *testing.T, fsys FS, cfg Config, files []File) {
+	t.Helper()
+	var results []writeResult
+	if err := writeFilesFunc(fsys, cfg, files, func(_ File, result writeResult) { results = append(results, result) }); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := newManifest(fsys, cfg, files, results)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeManifest(fsys, cfg.OutputDir, manifest); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyManifestDetectsChanges(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	writeWithManifest(t, fsys, cfg, []File{
+		{Name: "a.txt", Code: "a"},
+		{Name: "b.txt", Code: "b"},
+		{Name: "c.txt", Code: "c"},
+	})
+	manifest, err := loadManifest(fsys, "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	problems, err := verifyManifest(fsys, "out", manifest)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("verifyManifest() = %v, %v before any change", problems, err)
+	}
+
+	if err := fsys.WriteFile(filepath.Join("out", "b.txt"), []byte("edited"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	delete(fsys.files, filepath.Join("out", "c.txt"))
+	problems, err = verifyManifest(fsys, "out", manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"b.txt: modified since generation", "c.txt: missing"}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %q, want %q", problems, want)
+	}
+}
+
+func TestManifestRecordsIssues(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	files := []File{
+		{Name: "ok.go", Code: "package main\n"},
+		{Name: "broken.go", Code: "package main\n\nfunc {\n"},
+		{Name: "../escape.go", Code: "package main\n"},
+	}
+	writeWithManifest(t, fsys, cfg, files)
+	manifest, err := loadManifest(fsys, cfg.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := make(map[string]ManifestEntry)
+	for _, entry := range manifest.Files {
+		entries[entry.Name] = entry
+	}
+	if e := entries["ok.go"]; e.SHA256 == "" || len(e.Errors) != 0 {
+		t.Errorf("ok.go = %+v, want a hash and no errors", e)
+	}
+	if e := entries["broken.go"]; e.SHA256 == "" || len(e.Errors) == 0 || e.Errors[0].Category != "parse" || !strings.Contains(e.Errors[0].Message, "broken.go:3:6") {
+		t.Errorf("broken.go = %+v, want it written with a parse error", e)
+	}
+	if e := entries["../escape.go"]; e.SHA256 != "" || len(e.Errors) != 1 || e.Errors[0].Category != "path" {
+		t.Errorf("../escape.go = %+v, want no hash and a path error", e)
+	}
+}
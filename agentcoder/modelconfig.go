@@ -0,0 +1,56 @@
+package agentcoder
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// modelConfig holds the generation settings read from the --model-config
+// file. Unset fields keep the defaults of the model.
+type modelConfig struct {
+	Temperature     *float32 `json:"temperature"`       // Randomness of the output
+	TopP            *float32 `json:"top_p"`             // Cumulative probability of the tokens sampled from
+	TopK            *int32   `json:"top_k"`             // Number of most likely tokens sampled from
+	MaxOutputTokens *int32   `json:"max_output_tokens"` // Maximum length of the response
+	StopSequences   []string `json:"stop_sequences"`    // Sequences that end the response
+	CandidateCount  *int32   `json:"candidate_count"`   // Number of responses generated, of which the first is used
+}
+
+// loadModelConfig reads the generation settings from the JSON file at path,
+// rejecting unknown fields.
+func loadModelConfig(path string) (modelConfig, error) {
+	var mc modelConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return mc, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err = dec.Decode(&mc)
+	return mc, err
+}
+
+// apply sets the settings of mc in gc.
+func (mc modelConfig) apply(gc *genai.GenerationConfig) {
+	if mc.Temperature != nil {
+		gc.Temperature = mc.Temperature
+	}
+	if mc.TopP != nil {
+		gc.TopP = mc.TopP
+	}
+	if mc.TopK != nil {
+		gc.TopK = mc.TopK
+	}
+	if mc.MaxOutputTokens != nil {
+		gc.MaxOutputTokens = mc.MaxOutputTokens
+	}
+	if mc.StopSequences != nil {
+		gc.StopSequences = mc.StopSequences
+	}
+	if mc.CandidateCount != nil {
+		gc.CandidateCount = mc.CandidateCount
+	}
+}
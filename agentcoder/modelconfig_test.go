@@ -0,0 +1,111 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// writeModelConfig writes a model config file and returns its path.
+func writeModelConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "model.json")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestModelConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ModelConfig = writeModelConfig(t, `{"temperature": 0.2, "top_p": 0.9, "top_k": 40, "max_output_tokens": 8192, "stop_sequences": ["END"], "candidate_count": 1}`)
+	model, err := newModel(&genai.Client{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := genai.GenerationConfig{
+		Temperature:      genai.Ptr[float32](0.2),
+		TopP:             genai.Ptr[float32](0.9),
+		TopK:             genai.Ptr[int32](40),
+		MaxOutputTokens:  genai.Ptr[int32](8192),
+		StopSequences:    []string{"END"},
+		CandidateCount:   genai.Ptr[int32](1),
+		ResponseMIMEType: "application/json",
+		ResponseSchema:   filesSchema(),
+	}
+	if !reflect.DeepEqual(model.GenerationConfig, want) {
+		t.Errorf("GenerationConfig = %+v, want %+v", model.GenerationConfig, want)
+	}
+}
+
+func TestModelConfigFlagsTakePrecedence(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ModelConfig = writeModelConfig(t, `{"temperature": 0.2, "top_k": 40}`)
+	cfg.Temperature = genai.Ptr[float32](0.7)
+	model, err := newModel(&genai.Client{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *model.Temperature != 0.7 {
+		t.Errorf("Temperature = %v, want the flag value 0.7", *model.Temperature)
+	}
+	if *model.TopK != 40 {
+		t.Errorf("TopK = %v, want 40", *model.TopK)
+	}
+}
+
+func TestModelConfigUnknownField(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ModelConfig = writeModelConfig(t, `{"temprature": 0.2}`)
+	if _, err := newModel(&genai.Client{}, cfg); err == nil {
+		t.Error("unknown field accepted")
+	}
+}
+
+func TestStopSequences(t *testing.T) {
+	cfg, _, err := parseConfig([]string{"-response-format", "text", "-stop", "END", "-stop", "---"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model, err := newModel(&genai.Client{}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"END", "---"}; !reflect.DeepEqual(model.StopSequences, want) {
+		t.Errorf("StopSequences = %q, want %q", model.StopSequences, want)
+	}
+
+	// Flags replace the stop sequences of the model config
+	cfg.ModelConfig = writeModelConfig(t, `{"stop_sequences": ["STOP"]}`)
+	if model, err = newModel(&genai.Client{}, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"END", "---"}; !reflect.DeepEqual(model.StopSequences, want) {
+		t.Errorf("StopSequences = %q, want %q", model.StopSequences, want)
+	}
+}
+
+func TestStopSequencesLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StopSequences = []string{"1", "2", "3", "4", "5", "6"}
+	if _, err := newModel(&genai.Client{}, cfg); err == nil {
+		t.Error("more stop sequences than the API accepts")
+	}
+}
+
+func TestStopSequencesJSONWarning(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StopSequences = []string{"END"}
+	out := captureStdout(t, func() {
+		if _, err := newModel(&genai.Client{}, cfg); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(out, "Warning: a stop sequence") {
+		t.Errorf("output %q has no warning about JSON responses", out)
+	}
+}
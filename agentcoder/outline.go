@@ -0,0 +1,57 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// OutlineEntry is a file proposed by the model in outline mode.
+type OutlineEntry struct {
+	Name        string `json:"file_name"`   // Name of the file
+	Description string `json:"description"` // One-line purpose of the file
+}
+
+// outlineSchema describes the JSON array of outline entries the model answers
+// with in outline mode.
+func outlineSchema() *genai.Schema {
+	return &genai.Schema{
+		Type:        genai.TypeArray,
+		Description: "List of the files needed, without their source code.",
+		Items: &genai.Schema{
+			Type:        genai.TypeObject,
+			Description: "Object representing a proposed file.",
+			Properties: map[string]*genai.Schema{
+				"file_name": {
+					Type:        genai.TypeString,
+					Description: "Name of the file: relative_path/file_name.file_extension",
+				},
+				"description": {
+					Type:        genai.TypeString,
+					Description: "One-line description of the purpose of the file.",
+				},
+			},
+			Required: []string{"file_name", "description"},
+		},
+	}
+}
+
+// parseOutline decodes the JSON array of outline entries returned by the model.
+func parseOutline(text string) ([]OutlineEntry, error) {
+	var outline []OutlineEntry
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &outline); err != nil {
+		return nil, fmt.Errorf("parsing outline: %w", err)
+	}
+	return outline, nil
+}
+
+// printOutline prints the proposed file structure in a form that can be pasted
+// into a follow-up prompt.
+func printOutline(outline []OutlineEntry) {
+	fmt.Printf("\nProposed structure (%d file(s)):\n", len(outline))
+	for _, entry := range outline {
+		fmt.Printf("- %s: %s\n", entry.Name, entry.Description)
+	}
+}
@@ -0,0 +1,38 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOutlineShowsOnlyNamesAndDescriptions(t *testing.T) {
+	response := `[
+		{"file_name": "main.go", "description": "Entry point of the server", "source_code": "package main"},
+		{"file_name": "handlers/user.go", "description": "HTTP handlers for users"}
+	]`
+	outline, err := parseOutline(response)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() { printOutline(outline) })
+	want := "\nProposed structure (2 file(s)):\n- main.go: Entry point of the server\n- handlers/user.go: HTTP handlers for users\n"
+	if out != want {
+		t.Errorf("printed %q, want %q", out, want)
+	}
+	if strings.Contains(out, "package main") {
+		t.Error("source code shown in outline mode")
+	}
+}
+
+func TestOutlineSchemaHasNoSourceCode(t *testing.T) {
+	props := outlineSchema().Items.Properties
+	if _, ok := props["source_code"]; ok || len(props) != 2 {
+		t.Errorf("outline schema properties = %v, want only file_name and description", props)
+	}
+}
+
+func TestParseOutlineInvalid(t *testing.T) {
+	if _, err := parseOutline(`{"file_name": "a"}`); err == nil {
+		t.Error("parseOutline accepted an object")
+	}
+}
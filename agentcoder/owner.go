@@ -0,0 +1,67 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+)
+
+// parseOwner parses an owner given as "uid:gid", "uid" or with user and group
+// names instead of numbers. A missing group is returned as -1, which leaves
+// the group unchanged.
+func parseOwner(owner string) (uid, gid int, err error) {
+	name, group, hasGroup := strings.Cut(owner, ":")
+	if uid, err = strconv.Atoi(name); err != nil {
+		u, err := user.Lookup(name)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid owner %q: %w", owner, err)
+		}
+		uid, _ = strconv.Atoi(u.Uid)
+	}
+	gid = -1
+	if hasGroup && group != "" {
+		if gid, err = strconv.Atoi(group); err != nil {
+			g, err := user.LookupGroup(group)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid owner %q: %w", owner, err)
+			}
+			gid, _ = strconv.Atoi(g.Gid)
+		}
+	}
+	return uid, gid, nil
+}
+
+// fileOwner hands written files to the configured owner. It only works on the
+// OS filesystem and with the privileges to do so; after the first failure it
+// warns and stops trying.
+type fileOwner struct {
+	enabled  bool
+	uid, gid int
+}
+
+// newFileOwner returns the fileOwner for --owner, which does nothing if no
+// owner is set or fsys is not the OS filesystem.
+func newFileOwner(fsys FS, owner string) (*fileOwner, error) {
+	if owner == "" {
+		return &fileOwner{}, nil
+	}
+	uid, gid, err := parseOwner(owner)
+	if err != nil {
+		return nil, err
+	}
+	_, ok := fsys.(osFS)
+	return &fileOwner{enabled: ok, uid: uid, gid: gid}, nil
+}
+
+// chown changes the owner of the file at path.
+func (o *fileOwner) chown(path string) {
+	if !o.enabled {
+		return
+	}
+	if err := os.Chown(path, o.uid, o.gid); err != nil {
+		fmt.Printf("Warning: not changing the owner of the files: %v\n", err)
+		o.enabled = false
+	}
+}
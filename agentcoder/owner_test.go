@@ -0,0 +1,39 @@
+package agentcoder
+
+import "testing"
+
+func TestParseOwner(t *testing.T) {
+	tests := []struct {
+		owner    string
+		uid, gid int
+		wantErr  bool
+	}{
+		{owner: "1000:1001", uid: 1000, gid: 1001},
+		{owner: "1000", uid: 1000, gid: -1},
+		{owner: "1000:", uid: 1000, gid: -1},
+		{owner: "no-such-user-agent-coder", wantErr: true},
+		{owner: "1000:no-such-group-agent-coder", wantErr: true},
+	}
+	for _, tt := range tests {
+		uid, gid, err := parseOwner(tt.owner)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseOwner(%q) accepted", tt.owner)
+			}
+			continue
+		}
+		if err != nil || uid != tt.uid || gid != tt.gid {
+			t.Errorf("parseOwner(%q) = %d, %d, %v, want %d, %d", tt.owner, uid, gid, err, tt.uid, tt.gid)
+		}
+	}
+}
+
+func TestOwnerIgnoredForMemFS(t *testing.T) {
+	owner, err := newFileOwner(newMemFS(), "1000:1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if owner.enabled {
+		t.Error("owner enabled for an in-memory filesystem")
+	}
+}
@@ -0,0 +1,54 @@
+//go:build unix
+
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestOwnerSet(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner of files requires root")
+	}
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Owner = "1234:5678"
+	if err := writeFiles(osFS{}, cfg, []File{{Name: "main.go", Code: "package main\n"}}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(filepath.Join(cfg.OutputDir, "main.go"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	st := info.Sys().(*syscall.Stat_t)
+	if st.Uid != 1234 || st.Gid != 5678 {
+		t.Errorf("owner = %d:%d, want 1234:5678", st.Uid, st.Gid)
+	}
+}
+
+func TestOwnerNotPermitted(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root may change the owner of files")
+	}
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Owner = "0:0"
+	files := []File{{Name: "a.txt", Code: "a"}, {Name: "b.txt", Code: "b"}}
+	out := captureStdout(t, func() {
+		if err := writeFiles(osFS{}, cfg, files); err != nil {
+			t.Error(err)
+		}
+	})
+	if n := strings.Count(out, "Warning: not changing the owner of the files"); n != 1 {
+		t.Errorf("%d warnings in %q, want 1", n, out)
+	}
+	for _, file := range files {
+		if _, err := os.Stat(filepath.Join(cfg.OutputDir, file.Name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
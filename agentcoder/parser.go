@@ -0,0 +1,155 @@
+package agentcoder
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// responseParser turns the raw text of a response into files.
+type responseParser struct {
+	// Parse extracts the files from the response text.
+	Parse func(text string) ([]File, error)
+
+	// Structured is set for parsers of responses constrained by the files
+	// schema. Other responses are requested as plain text.
+	Structured bool
+
+	// Task tells the model how to present the files, replacing the default
+	// instruction if not empty.
+	Task string
+}
+
+// responseParsers are the parsers selectable with --parser.
+var responseParsers = map[string]responseParser{
+	"json": {Parse: parseFiles, Structured: true},
+	"fenced-files": {
+		Parse: parseFencedFiles,
+		Task:  "generate the necessary code files, each as a Markdown code block directly preceded by a line with its relative path",
+	},
+}
+
+// lookupParser returns the response parser with the given name.
+func lookupParser(name string) (responseParser, error) {
+	p, ok := responseParsers[name]
+	if !ok {
+		names := make([]string, 0, len(responseParsers))
+		for n := range responseParsers {
+			names = append(names, n)
+		}
+		slices.Sort(names)
+		return p, fmt.Errorf("unknown parser %q, expected one of %s", name, strings.Join(names, ", "))
+	}
+	return p, nil
+}
+
+// parseResponse extracts the files from the response text with the
+// configured parser and checks them against the --validate-schema file.
+func parseResponse(cfg Config, text string) ([]File, error) {
+	p, err := lookupParser(cfg.Parser)
+	if err != nil {
+		return nil, err
+	}
+	files, err := p.Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.ValidateSchema != "" {
+		if err := checkResponseSchema(cfg.ValidateSchema, text, files); err != nil {
+			return nil, err
+		}
+	}
+	return files, checkRequiredFields(files, cfg.JSONSchemaStrict)
+}
+
+// checkRequiredFields reports the files missing a name or source code, which
+// the files schema requires but which decode to empty strings when omitted.
+// They are errors in strict mode and warnings otherwise.
+func checkRequiredFields(files []File, strict bool) error {
+	var bad []string
+	for i, file := range files {
+		var missing []string
+		if file.Name == "" {
+			missing = append(missing, "file_name")
+		}
+		if file.Code == "" {
+			missing = append(missing, "source_code")
+		}
+		if len(missing) > 0 {
+			bad = append(bad, fmt.Sprintf("file %d (%s) has no %s", i+1, cmp.Or(file.Name, "unnamed"), strings.Join(missing, " or ")))
+		}
+	}
+	if len(bad) == 0 {
+		return nil
+	}
+	if strict {
+		return fmt.Errorf("response is missing required fields:\n  %s", strings.Join(bad, "\n  "))
+	}
+	for _, b := range bad {
+		fmt.Printf("Warning: %s\n", b)
+	}
+	return nil
+}
+
+// parseFencedFiles extracts the Markdown code blocks of the response as files.
+// The file name is taken from the info string of the opening fence when it
+// looks like a path ("```go main.go"), or else from the line preceding the
+// block, stripped of Markdown decoration ("### `main.go`", "File: main.go").
+func parseFencedFiles(text string) ([]File, error) {
+	var files []File
+	var header string
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if !strings.HasPrefix(line, "```") {
+			if line != "" {
+				header = line
+			}
+			continue
+		}
+
+		// Find the name and the closing fence of the block
+		name := fileNameFromHeader(header)
+		if info := strings.Fields(line[3:]); len(info) > 1 && looksLikePath(info[1]) {
+			name = info[1]
+		}
+		var body []string
+		for i++; i < len(lines) && strings.TrimSpace(lines[i]) != "```"; i++ {
+			body = append(body, lines[i])
+		}
+		header = ""
+		if name == "" {
+			continue
+		}
+		files = append(files, File{Name: name, Code: strings.Join(body, "\n") + "\n"})
+	}
+	if len(files) == 0 {
+		return nil, errors.New("parsing response: no code blocks with file names found")
+	}
+	fmt.Printf("\nSuccessfully parsed %d file(s)\n", len(files))
+	return files, nil
+}
+
+// fileNameFromHeader returns the path in a line preceding a code block, or an
+// empty string if the line does not name a file.
+func fileNameFromHeader(header string) string {
+	name := strings.TrimLeft(header, "#*-> \t")
+	if label, rest, ok := strings.Cut(name, ":"); ok {
+		switch strings.ToLower(strings.Trim(label, "*` ")) {
+		case "file", "filename", "file name", "path":
+			name = rest
+		}
+	}
+	name = strings.Trim(strings.TrimSpace(name), "*`\"':")
+	if strings.ContainsAny(name, " \t") || !looksLikePath(name) {
+		return ""
+	}
+	return name
+}
+
+// looksLikePath reports whether s has the shape of a relative file path.
+func looksLikePath(s string) bool {
+	return strings.ContainsAny(s, "./") && !strings.HasPrefix(s, "http")
+}
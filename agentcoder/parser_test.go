@@ -0,0 +1,112 @@
+package agentcoder
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONParser(t *testing.T) {
+	p, err := lookupParser("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{Name: "main.go", Code: "package main\n"}}
+	for _, text := range []string{
+		`[{"file_name": "main.go", "source_code": "package main\n"}]`,
+		"```json\n[{\"file_name\": \"main.go\", \"source_code\": \"package main\\n\"}]\n```",
+	} {
+		files, err := p.Parse(text)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", text, err)
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Errorf("Parse(%q) = %v, want %v", text, files, want)
+		}
+	}
+	if _, err := p.Parse("not json"); err == nil {
+		t.Error("invalid JSON parsed")
+	}
+}
+
+func TestFencedFilesParser(t *testing.T) {
+	p, err := lookupParser("fenced-files")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "Here you go:\n\n" +
+		"### `main.go`\n```go\npackage main\n```\n\n" +
+		"**File: internal/util.go**\n```go\npackage internal\n```\n\n" +
+		"```sh scripts/run.sh\necho hi\n```\n\n" +
+		"An example without a file:\n\n```\ngo run .\n```\n"
+	files, err := p.Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "internal/util.go", Code: "package internal\n"},
+		{Name: "scripts/run.sh", Code: "echo hi\n"},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("Parse() = %v, want %v", files, want)
+	}
+	if _, err := p.Parse("No code here."); err == nil {
+		t.Error("response without code blocks parsed")
+	}
+}
+
+func TestFencedFilesFixture(t *testing.T) {
+	dump, err := loadDump(filepath.Join("testdata", "responses", "fenced-files.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := DefaultConfig()
+	dump.apply(&cfg)
+	files, err := parseResponse(cfg, dump.Text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{
+		{Name: "main.go", Code: "package main\n\nfunc main() {\n\tgreet()\n}\n"},
+		{Name: "internal/greet.go", Code: "package main\n\nimport \"fmt\"\n\nfunc greet() { fmt.Println(\"hi\") }\n"},
+		{Name: "notes/TODO.md", Code: "- more greetings\n"},
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("parseResponse() = %v, want %v", files, want)
+	}
+}
+
+func TestUnknownParser(t *testing.T) {
+	if _, err := lookupParser("xml"); err == nil {
+		t.Error("unknown parser found")
+	}
+}
+
+func TestRequiredFields(t *testing.T) {
+	cfg := DefaultConfig()
+	text := `[{"file_name": "main.go", "source_code": "package main\n"}, {"file_name": "empty.go"}, {"source_code": "x"}]`
+
+	// Lenient mode warns and keeps the files
+	var files []File
+	var err error
+	out := captureStdout(t, func() { files, err = parseResponse(cfg, text) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Errorf("parsed %d files, want 3", len(files))
+	}
+	for _, want := range []string{"Warning: file 2 (empty.go) has no source_code", "Warning: file 3 (unnamed) has no file_name"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q does not contain %q", out, want)
+		}
+	}
+
+	cfg.JSONSchemaStrict = true
+	_, err = parseResponse(cfg, text)
+	if err == nil || !strings.Contains(err.Error(), "file 2 (empty.go) has no source_code") || !strings.Contains(err.Error(), "file 3 (unnamed) has no file_name") {
+		t.Errorf("strict parseResponse() error = %v, want the files missing fields", err)
+	}
+}
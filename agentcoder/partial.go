@@ -0,0 +1,54 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// exitPartial is the exit status of a run that timed out and wrote only the
+// files completed before the timeout.
+const exitPartial = 3
+
+// partialFiles collects the files completed in a streamed response, so that
+// they can still be written when the run times out before the response is
+// complete. Each file is passed on to next, if not nil.
+type partialFiles struct {
+	files []File
+	next  func(File)
+}
+
+// add records a completed file.
+func (p *partialFiles) add(file File) {
+	p.files = append(p.files, file)
+	if p.next != nil {
+		p.next(file)
+	}
+}
+
+// generateWithTimeout generates the files like generateFiles, within
+// --timeout seconds if set. When the time runs out, the files completed in the
+// stream so far are returned, checked like the complete response would be, and
+// timedOut is set; if none was complete, the timeout is an error.
+func generateWithTimeout(ctx context.Context, cfg Config, gen *modelGenerator) (files []File, timedOut bool, err error) {
+	if cfg.Timeout <= 0 {
+		files, err = generateFiles(ctx, cfg, gen)
+		return files, false, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+	partial := partialFiles{next: gen.onFile}
+	gen.onFile = partial.add
+	defer func() { gen.onFile = partial.next }()
+
+	files, err = generateFiles(ctx, cfg, gen)
+	if err == nil || ctx.Err() != context.DeadlineExceeded {
+		return files, false, err
+	}
+	if len(partial.files) == 0 {
+		return nil, true, fmt.Errorf("timed out after %d seconds before any file was complete", cfg.Timeout)
+	}
+	fmt.Printf("\nTimed out after %d seconds, keeping the %d file(s) completed so far\n", cfg.Timeout, len(partial.files))
+	files, err = filterFiles(cfg, transformFiles(cfg, partial.files))
+	return files, true, err
+}
@@ -0,0 +1,130 @@
+package agentcoder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/option"
+)
+
+// stalledStream returns a generator for an API that streams the chunks of
+// text and then stalls without ending the response until the request is
+// cancelled.
+func stalledStream(t *testing.T, cfg Config, chunks ...string) *modelGenerator {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, "[")
+		for i, chunk := range chunks {
+			text, _ := json.Marshal(chunk)
+			if i > 0 {
+				io.WriteString(w, ",")
+			}
+			fmt.Fprintf(w, `{"candidates": [{"content": {"role": "model", "parts": [{"text": %s}]}}]}`, text)
+		}
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	client, err := genai.NewClient(context.Background(), option.WithAPIKey("test-key"), option.WithEndpoint(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Close() })
+	gen, err := newClientGenerator(client, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+func TestGenerateWithTimeoutKeepsCompletedFiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write three files."
+	cfg.OutputDir = t.TempDir()
+	cfg.NoRaw = true
+	cfg.Stream = true
+	cfg.Timeout = 1
+	gen := stalledStream(t, cfg,
+		`[{"file_name": "a.txt", "source_code": "first"}, {"file_name": "b.t`,
+		`xt", "source_code": "second"}, {"file_name": "c.txt", "source_co`,
+	)
+
+	var files []File
+	var timedOut bool
+	var err error
+	out := captureStdout(t, func() { files, timedOut, err = generateWithTimeout(context.Background(), cfg, gen) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !timedOut {
+		t.Error("timedOut = false, want the run marked as partial")
+	}
+	if got := fileNames(files); !reflect.DeepEqual(got, []string{"a.txt", "b.txt"}) {
+		t.Errorf("files = %v, want the two completed files", got)
+	}
+	if !strings.Contains(out, "Timed out after 1 seconds, keeping the 2 file(s) completed so far") {
+		t.Errorf("output %q does not report the partial files", out)
+	}
+
+	// The files are written and the JSON summary marks the run as partial
+	var buf bytes.Buffer
+	reporter := newJSONLReporter(&buf)
+	captureStdout(t, func() {
+		if err := writeFilesFunc(osFS{}, cfg, files, reporter.Report); err != nil {
+			t.Fatal(err)
+		}
+		reporter.Summary(timedOut)
+	})
+	if tree := readTree(t, cfg.OutputDir); !reflect.DeepEqual(tree, map[string]string{"a.txt": "first", "b.txt": "second"}) {
+		t.Errorf("output directory = %v, want the completed files", tree)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	var summary jsonlSummary
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if !summary.Partial || summary.Files != 2 {
+		t.Errorf("summary = %+v, want 2 files of a partial run", summary)
+	}
+}
+
+func TestGenerateWithTimeoutNothingComplete(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a file."
+	cfg.NoRaw = true
+	cfg.Stream = true
+	cfg.Timeout = 1
+	gen := stalledStream(t, cfg, `[{"file_name": "a.txt", "source_co`)
+	var timedOut bool
+	var err error
+	captureStdout(t, func() { _, timedOut, err = generateWithTimeout(context.Background(), cfg, gen) })
+	if err == nil || !strings.Contains(err.Error(), "timed out after 1 seconds before any file was complete") || !timedOut {
+		t.Errorf("generateWithTimeout() = %v, %v, want a timeout error", timedOut, err)
+	}
+}
+
+func TestGenerateWithTimeoutCompletes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a file."
+	cfg.NoRaw = true
+	cfg.Timeout = 60
+	gen, _ := newTestGenerator(t, cfg, textResponse(`[{"file_name": "a.txt", "source_code": "a"}]`, "STOP"))
+	var files []File
+	var timedOut bool
+	var err error
+	captureStdout(t, func() { files, timedOut, err = generateWithTimeout(context.Background(), cfg, gen) })
+	if err != nil || timedOut || len(files) != 1 {
+		t.Errorf("generateWithTimeout() = %v, %v, %v, want the complete response", files, timedOut, err)
+	}
+}
@@ -0,0 +1,68 @@
+package agentcoder
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// checkPath rejects generated file names that would be written outside the
+// output directory or that are nested more than maxDepth directories deep. A
+// maxDepth of zero disables the depth check.
+func checkPath(name string, maxDepth int) error {
+	if !filepath.IsLocal(name) {
+		return fmt.Errorf("path %q is outside the output directory", name)
+	}
+	depth := strings.Count(path.Clean(filepath.ToSlash(name)), "/")
+	if maxDepth > 0 && depth > maxDepth {
+		return fmt.Errorf("path %q is nested %d directories deep, more than the limit of %d", name, depth, maxDepth)
+	}
+	return nil
+}
+
+// checkFilesPerDir warns about directories that more than max of the files
+// target, which usually means the model misunderstood the prompt. In strict
+// mode this fails instead. A max of zero disables the check.
+func checkFilesPerDir(files []File, max int, strict bool) error {
+	if max <= 0 {
+		return nil
+	}
+	counts := make(map[string]int)
+	var dirs []string
+	for _, file := range files {
+		dir := path.Dir(path.Clean(filepath.ToSlash(file.Name)))
+		if counts[dir] == 0 {
+			dirs = append(dirs, dir)
+		}
+		counts[dir]++
+	}
+	for _, dir := range dirs {
+		if counts[dir] <= max {
+			continue
+		}
+		if strict {
+			return fmt.Errorf("%d files target directory %q, more than the limit of %d", counts[dir], dir, max)
+		}
+		fmt.Printf("Warning: %d files target directory %q, more than the limit of %d\n", counts[dir], dir, max)
+	}
+	return nil
+}
+
+// normalizePaths rewrites backslash separators in the file names, which
+// models sometimes emit regardless of the platform, to the separator of the
+// OS, and reports how many names were changed.
+func normalizePaths(files []File) []File {
+	changed := 0
+	for i, file := range files {
+		name := filepath.FromSlash(strings.ReplaceAll(file.Name, `\`, "/"))
+		if name != file.Name {
+			files[i].Name = name
+			changed++
+		}
+	}
+	if changed > 0 {
+		fmt.Printf("Normalized the path separators of %d file(s)\n", changed)
+	}
+	return files
+}
@@ -0,0 +1,90 @@
+package agentcoder
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheckPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDepth int
+		wantErr  string
+	}{
+		{name: "main.go", maxDepth: 2},
+		{name: "a/b/c.go", maxDepth: 2},
+		{name: "a/b/c/d.go", maxDepth: 2, wantErr: "nested 3 directories deep, more than the limit of 2"},
+		{name: "a/b/c/d/e/f/g.go"},
+		{name: "../escape.go", wantErr: "outside the output directory"},
+		{name: "/etc/passwd", wantErr: "outside the output directory"},
+	}
+	for _, tt := range tests {
+		err := checkPath(tt.name, tt.maxDepth)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("checkPath(%q, %d) = %v, want nil", tt.name, tt.maxDepth, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("checkPath(%q, %d) = %v, want %q", tt.name, tt.maxDepth, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMaxDirDepthRejectsFile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	cfg.MaxDirDepth = 1
+	statuses := writeStatuses(t, newMemFS(), cfg, []File{{Name: "a/b/deep.go", Code: "package b\n"}, {Name: "a/ok.go", Code: "package a\n"}})
+	if statuses["a/b/deep.go"] != writeRejected || statuses["a/ok.go"] != writeWritten {
+		t.Errorf("statuses = %v, want the deep file rejected", statuses)
+	}
+}
+
+func TestCheckFilesPerDir(t *testing.T) {
+	files := []File{{Name: "a/1.go"}, {Name: "a/2.go"}, {Name: "a/3.go"}, {Name: "b/1.go"}, {Name: "main.go"}}
+
+	out := captureStdout(t, func() {
+		if err := checkFilesPerDir(files, 3, false); err != nil {
+			t.Error(err)
+		}
+	})
+	if out != "" {
+		t.Errorf("warning at the threshold: %q", out)
+	}
+
+	out = captureStdout(t, func() {
+		if err := checkFilesPerDir(files, 2, false); err != nil {
+			t.Error(err)
+		}
+	})
+	if want := "Warning: 3 files target directory \"a\", more than the limit of 2\n"; out != want {
+		t.Errorf("output = %q, want %q", out, want)
+	}
+
+	if err := checkFilesPerDir(files, 2, true); err == nil {
+		t.Error("strict mode accepted too many files in one directory")
+	}
+	if err := checkFilesPerDir(files, 0, true); err != nil {
+		t.Errorf("disabled check failed: %v", err)
+	}
+}
+
+func TestNormalizePaths(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	files := transformFiles(cfg, []File{{Name: `src\main.go`, Code: "package main\n"}})
+	fsys := newMemFS()
+	if err := writeFiles(fsys, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fsys.Paths(), []string{filepath.Join("out", "src", "main.go")}; !reflect.DeepEqual(got, want) {
+		t.Errorf("written paths = %v, want %v", got, want)
+	}
+
+	cfg.NormalizePaths = false
+	files = transformFiles(cfg, []File{{Name: `src\main.go`}})
+	if files[0].Name != `src\main.go` {
+		t.Errorf("name normalized to %q with --normalize-paths=false", files[0].Name)
+	}
+}
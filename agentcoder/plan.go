@@ -0,0 +1,190 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File statuses relative to an existing directory.
+const (
+	statusNew       = "new"
+	statusModified  = "modified"
+	statusIdentical = "identical"
+	statusRejected  = "rejected"
+)
+
+// Plan is the reviewable result of the plan subcommand: the generated files
+// annotated with their status relative to an existing directory.
+type Plan struct {
+	SchemaVersion int            `json:"schema_version"` // Version of the plan format
+	Against       string         `json:"against"`        // Directory the files were compared with
+	Files         []PlanFile     `json:"files"`          // Generated files and their status
+	Summary       map[string]int `json:"summary"`        // Number of files per status
+}
+
+// PlanFile is a generated file together with its status.
+type PlanFile struct {
+	File
+	Status string `json:"status"`          // One of new, modified, identical or rejected
+	Error  string `json:"error,omitempty"` // Why the file was rejected
+}
+
+// classifyFile compares a generated file with its counterpart in dir. Files
+// whose path is not allowed are rejected without reading anything, and the
+// returned error then holds the reason.
+func classifyFile(dir string, file File, maxDepth int) (string, error) {
+	if err := checkPath(file.Name, maxDepth); err != nil {
+		return statusRejected, err
+	}
+	existing, err := os.ReadFile(filepath.Join(dir, file.Name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return statusNew, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if string(existing) == file.Code {
+		return statusIdentical, nil
+	}
+	return statusModified, nil
+}
+
+// newPlan classifies every file against dir and counts the results. Files
+// deeper than maxDepth or outside dir are kept in the plan as rejected.
+func newPlan(dir string, files []File, maxDepth int) (Plan, error) {
+	plan := Plan{
+		SchemaVersion: schemaVersion,
+		Against:       dir,
+		Summary:       map[string]int{statusNew: 0, statusModified: 0, statusIdentical: 0, statusRejected: 0},
+	}
+	for _, file := range files {
+		status, err := classifyFile(dir, file, maxDepth)
+		planFile := PlanFile{File: file, Status: status}
+		if status == statusRejected {
+			planFile.Error = err.Error()
+		} else if err != nil {
+			return plan, fmt.Errorf("comparing %s: %w", file.Name, err)
+		}
+		plan.Files = append(plan.Files, planFile)
+		plan.Summary[status]++
+	}
+	return plan, nil
+}
+
+// loadPlan reads a plan previously written by the plan subcommand, upgrading
+// plans written by older releases.
+func loadPlan(path string) (Plan, error) {
+	var plan Plan
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return plan, err
+	}
+	if err := decodeVersioned(data, "plan", planMigrations, &plan); err != nil {
+		return plan, fmt.Errorf("parsing plan %s: %w", path, err)
+	}
+	return plan, nil
+}
+
+// runPlan implements the plan subcommand, which generates files and saves them
+// as a plan for review instead of writing them.
+func runPlan(args []string) error {
+	var against, planFile string
+	cfg, _, err := parseConfig(args, func(fs *flag.FlagSet) {
+		fs.StringVar(&against, "against", "", "Existing directory to compare the plan with (defaults to the output directory)")
+		fs.StringVar(&planFile, "plan-file", "plan.json", "File the plan is written to")
+	})
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if against == "" {
+		against = cfg.OutputDir
+	}
+
+	ctx := context.Background()
+	gen, err := newModelGenerator(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer gen.Close()
+
+	files, err := generateFiles(ctx, cfg, gen)
+	if err != nil {
+		return err
+	}
+	plan, err := newPlan(against, files, cfg.MaxDirDepth)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\nPlan against '%s':\n", against)
+	for _, file := range plan.Files {
+		if file.Status == statusRejected {
+			fmt.Printf("  %-10s %s: %s\n", file.Status, file.Name, file.Error)
+			continue
+		}
+		fmt.Printf("  %-10s %s\n", file.Status, file.Name)
+	}
+	fmt.Printf("\n%d new, %d modified, %d identical, %d rejected\n",
+		plan.Summary[statusNew], plan.Summary[statusModified], plan.Summary[statusIdentical], plan.Summary[statusRejected])
+	if cfg.SemanticDiff {
+		for _, file := range plan.Files {
+			if file.Status != statusModified || !strings.EqualFold(filepath.Ext(file.Name), ".go") {
+				continue
+			}
+			existing, _ := os.ReadFile(filepath.Join(against, file.Name))
+			fmt.Println()
+			printSemanticDiff(file.Name, string(existing), file.Code)
+		}
+	}
+	if cfg.ShowDiffStat {
+		var stats []diffStat
+		for _, file := range plan.Files {
+			if file.Status == statusIdentical || file.Status == statusRejected {
+				continue
+			}
+			existing, _ := os.ReadFile(filepath.Join(against, file.Name))
+			added, removed := countChanges(string(existing), file.Code)
+			stats = append(stats, diffStat{Name: file.Name, Added: added, Removed: removed})
+		}
+		fmt.Println()
+		printDiffStat(stats)
+	}
+
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("serializing plan: %w", err)
+	}
+	if err := os.WriteFile(planFile, data, 0644); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	fmt.Printf("Plan written to %s\n", planFile)
+	return nil
+}
+
+// runApply implements the apply subcommand, which writes the files of a saved
+// plan into the output directory.
+func runApply(args []string) error {
+	cfg, rest, err := parseConfig(args, nil)
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+	if len(rest) != 1 {
+		return errors.New("usage: apply [flags] <plan-file>")
+	}
+	plan, err := loadPlan(rest[0])
+	if err != nil {
+		return err
+	}
+	files := make([]File, len(plan.Files))
+	for i, file := range plan.Files {
+		files[i] = file.File
+	}
+	return writeFiles(osFS{}, cfg, files)
+}
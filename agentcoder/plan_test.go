@@ -0,0 +1,104 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestNewPlanAgainstDirectory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "same.go"), []byte("package same\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []File{
+		{Name: "same.go", Code: "package same\n"},
+		{Name: "sub/new.go", Code: "package sub\n"},
+	}
+	plan, err := newPlan(dir, files, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, file := range plan.Files {
+		statuses = append(statuses, file.Status)
+	}
+	if want := []string{statusIdentical, statusNew}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if want := map[string]int{statusNew: 1, statusModified: 0, statusIdentical: 1, statusRejected: 0}; !reflect.DeepEqual(plan.Summary, want) {
+		t.Errorf("summary = %v, want %v", plan.Summary, want)
+	}
+
+	// The classification is kept in the plan file
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "plan.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, plan) {
+		t.Errorf("loaded plan = %+v, want %+v", loaded, plan)
+	}
+}
+
+func TestNewPlanModified(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := newPlan(dir, []File{{Name: "a.txt", Code: "new"}}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Files[0].Status != statusModified || plan.Summary[statusModified] != 1 {
+		t.Errorf("plan = %+v, want a.txt modified", plan)
+	}
+}
+
+func TestNewPlanRejectsPaths(t *testing.T) {
+	dir := t.TempDir()
+	// A file next to the directory must not be compared with the one outside it
+	if err := os.WriteFile(filepath.Join(filepath.Dir(dir), "escape.txt"), []byte("same"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	files := []File{
+		{Name: "../escape.txt", Code: "same"},
+		{Name: "a/b/c.txt", Code: "deep"},
+		{Name: "a/ok.txt", Code: "ok"},
+	}
+	plan, err := newPlan(dir, files, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var statuses []string
+	for _, file := range plan.Files {
+		statuses = append(statuses, file.Status)
+		if file.Status == statusRejected && file.Error == "" {
+			t.Errorf("%s rejected without a reason", file.Name)
+		}
+	}
+	if want := []string{statusRejected, statusRejected, statusNew}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if plan.Summary[statusRejected] != 2 || plan.Summary[statusIdentical] != 0 {
+		t.Errorf("summary = %v, want 2 rejected and none identical", plan.Summary)
+	}
+}
+
+func TestRunApplyFails(t *testing.T) {
+	if err := runApply(nil); err == nil {
+		t.Error("apply without a plan file succeeded")
+	}
+	if err := runApply([]string{filepath.Join(t.TempDir(), "missing.json")}); err == nil {
+		t.Error("apply with a missing plan file succeeded")
+	}
+}
@@ -0,0 +1,162 @@
+package agentcoder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+)
+
+// A plugin is a WebAssembly module that transforms each generated file. It
+// may import WASI and must export:
+//
+//   - memory, its linear memory
+//   - alloc(size i32) i32, returning a buffer of size bytes for the host
+//   - transform(ptr i32, len i32) i64, taking a file as the JSON object of a
+//     response entry and returning the packed pointer (high 32 bits) and
+//     length (low 32 bits) of a pluginResult as JSON
+//
+// The host writes the input into a buffer obtained from alloc and never frees
+// memory. The module is compiled once per run and instantiated afresh for every
+// set of files, so its memory only lives as long as one set.
+
+// pluginResult is the output of a plugin for one file: the file to write,
+// possibly renamed or rewritten, or the reason it is rejected.
+type pluginResult struct {
+	File
+	Reject string `json:"reject,omitempty"` // Why the file must not be written
+}
+
+// compiledPlugin is a plugin compiled in the runtime it is instantiated in.
+type compiledPlugin struct {
+	runtime wazero.Runtime
+	module  wazero.CompiledModule
+}
+
+// compiledPlugins holds the plugins compiled so far by path, so that each is
+// only compiled once per run. They are released when the process exits.
+var compiledPlugins = struct {
+	sync.Mutex
+	m map[string]*compiledPlugin
+}{m: make(map[string]*compiledPlugin)}
+
+// compilePlugin returns the compiled plugin at path, compiling it on first
+// use.
+func compilePlugin(ctx context.Context, path string) (*compiledPlugin, error) {
+	compiledPlugins.Lock()
+	defer compiledPlugins.Unlock()
+	if c, ok := compiledPlugins.m[path]; ok {
+		return c, nil
+	}
+	wasm, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// The runtime outlives the context of the first caller
+	r := wazero.NewRuntime(context.Background())
+	wasi_snapshot_preview1.MustInstantiate(ctx, r)
+	compiled, err := r.CompileModule(ctx, wasm)
+	if err != nil {
+		r.Close(ctx)
+		return nil, fmt.Errorf("loading plugin %s: %w", path, err)
+	}
+	c := &compiledPlugin{runtime: r, module: compiled}
+	compiledPlugins.m[path] = c
+	return c, nil
+}
+
+// plugin is an instance of a WebAssembly plugin. It is not safe for
+// concurrent use.
+type plugin struct {
+	mod       api.Module
+	alloc     api.Function
+	transform api.Function
+}
+
+// loadPlugin instantiates the plugin at path, compiling it on first use.
+func loadPlugin(ctx context.Context, path string) (*plugin, error) {
+	c, err := compilePlugin(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	// Anonymous modules can be instantiated any number of times
+	mod, err := c.runtime.InstantiateModule(ctx, c.module, wazero.NewModuleConfig().
+		WithName("").
+		WithStartFunctions("_initialize").
+		WithStdout(os.Stderr).
+		WithStderr(os.Stderr))
+	if err != nil {
+		return nil, fmt.Errorf("loading plugin %s: %w", path, err)
+	}
+	p := &plugin{mod: mod, alloc: mod.ExportedFunction("alloc"), transform: mod.ExportedFunction("transform")}
+	if p.alloc == nil || p.transform == nil || mod.Memory() == nil {
+		mod.Close(ctx)
+		return nil, fmt.Errorf("plugin %s must export memory, alloc and transform", path)
+	}
+	return p, nil
+}
+
+// Transform passes file through the plugin and returns its result.
+func (p *plugin) Transform(ctx context.Context, file File) (pluginResult, error) {
+	var result pluginResult
+	input, err := json.Marshal(file)
+	if err != nil {
+		return result, err
+	}
+	res, err := p.alloc.Call(ctx, uint64(len(input)))
+	if err != nil {
+		return result, fmt.Errorf("plugin alloc: %w", err)
+	}
+	ptr := uint32(res[0])
+	if !p.mod.Memory().Write(ptr, input) {
+		return result, fmt.Errorf("plugin alloc returned an invalid buffer")
+	}
+	res, err = p.transform.Call(ctx, uint64(ptr), uint64(len(input)))
+	if err != nil {
+		return result, fmt.Errorf("plugin transform: %w", err)
+	}
+	out, ok := p.mod.Memory().Read(uint32(res[0]>>32), uint32(res[0]))
+	if !ok {
+		return result, fmt.Errorf("plugin transform returned an invalid buffer")
+	}
+	if err := json.Unmarshal(out, &result); err != nil {
+		return result, fmt.Errorf("plugin transform returned invalid JSON: %w", err)
+	}
+	return result, nil
+}
+
+// Close releases the instance of the plugin.
+func (p *plugin) Close(ctx context.Context) error {
+	return p.mod.Close(ctx)
+}
+
+// applyPlugin passes every file through the plugin at path, dropping the
+// files it rejects with a warning.
+func applyPlugin(ctx context.Context, path string, files []File) ([]File, error) {
+	p, err := loadPlugin(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Close(ctx)
+	var kept []File
+	for _, file := range files {
+		result, err := p.Transform(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file.Name, err)
+		}
+		if result.Reject != "" {
+			fmt.Printf("Warning: plugin rejected %s: %s\n", file.Name, result.Reject)
+			continue
+		}
+		if result.Name != file.Name {
+			fmt.Printf("Plugin renamed %s to %s\n", file.Name, result.Name)
+		}
+		kept = append(kept, result.File)
+	}
+	return kept, nil
+}
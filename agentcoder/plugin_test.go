@@ -0,0 +1,103 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildPlugin builds the example plugin of testdata/plugins with the given
+// name and returns the path of the module. Tests using it are skipped without
+// a Go toolchain.
+func buildPlugin(t *testing.T, name string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("building the plugin needs the go toolchain")
+	}
+	path := filepath.Join(t.TempDir(), name+".wasm")
+	cmd := exec.Command("go", "build", "-buildmode=c-shared", "-o", path, ".")
+	cmd.Dir = filepath.Join("testdata", "plugins", name)
+	cmd.Env = append(os.Environ(), "GOOS=wasip1", "GOARCH=wasm")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("building plugin %s: %v\n%s", name, err, out)
+	}
+	return path
+}
+
+func TestPlugin(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Plugin = buildPlugin(t, "upper")
+	files := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "secret.txt", Code: "hunter2"},
+		{Name: "docs/readme.md", Code: "# Docs\n"},
+	}
+	var err error
+	out := captureStdout(t, func() { files, err = filterFiles(cfg, files) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []File{{Name: "MAIN.GO", Code: "package main\n"}, {Name: "DOCS/README.MD", Code: "# Docs\n"}}
+	if !reflect.DeepEqual(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+	for _, msg := range []string{"Plugin renamed main.go to MAIN.GO", "plugin rejected secret.txt: secrets are not written"} {
+		if !strings.Contains(out, msg) {
+			t.Errorf("output %q does not contain %q", out, msg)
+		}
+	}
+}
+
+func TestPluginCompiledOncePerRun(t *testing.T) {
+	path := buildPlugin(t, "upper")
+	ctx := context.Background()
+	var compiled *compiledPlugin
+	for i := range 3 {
+		var files []File
+		var err error
+		captureStdout(t, func() { files, err = applyPlugin(ctx, path, []File{{Name: "a.txt", Code: "a"}}) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name != "A.TXT" {
+			t.Errorf("call %d: files = %v, want A.TXT", i+1, files)
+		}
+		compiledPlugins.Lock()
+		c := compiledPlugins.m[path]
+		compiledPlugins.Unlock()
+		if compiled != nil && c != compiled {
+			t.Errorf("call %d compiled the plugin again", i+1)
+		}
+		compiled = c
+	}
+	if compiled == nil {
+		t.Error("the compiled plugin was not kept")
+	}
+}
+
+func TestPluginErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := applyPlugin(context.Background(), filepath.Join(dir, "missing.wasm"), nil); err == nil {
+		t.Error("applyPlugin() of a missing file succeeded")
+	}
+	invalid := filepath.Join(dir, "invalid.wasm")
+	if err := os.WriteFile(invalid, []byte("not wasm"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyPlugin(context.Background(), invalid, nil); err == nil || !strings.Contains(err.Error(), "loading plugin") {
+		t.Errorf("applyPlugin() of an invalid module error = %v, want loading plugin", err)
+	}
+	// A valid module without the exports of the ABI
+	empty := filepath.Join(dir, "empty.wasm")
+	if err := os.WriteFile(empty, []byte("\x00asm\x01\x00\x00\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := applyPlugin(context.Background(), empty, nil); err == nil || !strings.Contains(err.Error(), "must export memory, alloc and transform") {
+		t.Errorf("applyPlugin() of a module without exports error = %v, want the missing exports", err)
+	}
+}
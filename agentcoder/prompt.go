@@ -0,0 +1,120 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// promptRequest is an assembled instruction prompt together with the parts it
+// was built from, so that it can be rebuilt with less context.
+type promptRequest struct {
+	Text    string // Instruction sent to the model
+	Prompt  string // Prompt entered by the user, after the prompt chain
+	Context []File // Existing files included as context
+	Module  string // Import path of the Go module the files are generated into
+
+	// Summarized holds the names of the context files replaced by a summary
+	Summarized map[string]bool
+}
+
+// buildPrompt wraps the user's prompt, as rewritten by the prompt chain, in
+// the instruction sent to the model, followed by the Go module and context
+// files, if any. In modify mode the model is asked to edit the context files
+// and return only those it changes.
+func buildPrompt(cfg Config, req *promptRequest) string {
+	task := "generate the necessary code files"
+	if cfg.Outline {
+		task = "propose the files that would be needed, with a one-line description of the purpose of each, without any source code"
+	} else if cfg.FunctionCalling {
+		task = functionCallTask(cfg.FileFunctions)
+	} else if cfg.ResponseFormat == "text" {
+		task = "respond with only the content of the single file that is needed, without any explanation or formatting"
+	} else if parser, ok := responseParsers[cfg.Parser]; ok && parser.Task != "" {
+		task = parser.Task
+	}
+	instruction := fmt.Sprintf("Based on the following request, %s:\n\n%s", task, req.Prompt)
+	if cfg.Mode == "modify" && !cfg.Outline {
+		instruction += "\n\nThe request changes existing code. Edit the existing files listed below rather than rewriting them from scratch, keep their names, and return only the files you change or add, each with its complete new content. Leave out every file that stays the same."
+	}
+	if req.Module != "" {
+		instruction += fmt.Sprintf("\n\nThe files are generated into the directory with Go import path %s. Use it as the prefix for imports between the generated packages.", req.Module)
+	}
+	if len(req.Context) == 0 {
+		return instruction
+	}
+
+	var b strings.Builder
+	b.WriteString(instruction)
+	if cfg.Mode == "modify" {
+		b.WriteString("\n\nExisting files to modify:\n")
+	} else {
+		b.WriteString("\n\nExisting files for context:\n")
+	}
+	for _, file := range req.Context {
+		if req.Summarized[file.Name] {
+			fmt.Fprintf(&b, "\n--- %s (summary) ---\n%s\n", file.Name, file.Code)
+			continue
+		}
+		fmt.Fprintf(&b, "\n--- %s ---\n%s\n", file.Name, file.Code)
+	}
+	return b.String()
+}
+
+// limitPrompt enforces the maximum prompt size. When prompt is longer than max
+// characters, the user is asked to confirm in interactive mode, and the prompt
+// is truncated otherwise. It returns the prompt to send and whether to proceed.
+// A max of zero disables the check.
+func limitPrompt(prompt string, max int, interactive bool, confirm func(question string) bool) (string, bool) {
+	runes := []rune(prompt)
+	if max <= 0 || len(runes) <= max {
+		return prompt, true
+	}
+	fmt.Printf("Warning: the prompt is %d characters long, which exceeds the limit of %d\n", len(runes), max)
+	if interactive {
+		return prompt, confirm("Send the full prompt anyway?")
+	}
+	fmt.Printf("Truncating the prompt to %d characters\n", max)
+	return string(runes[:max]), true
+}
+
+// fixPrompt asks the model to correct files that failed to build.
+func fixPrompt(files []File, buildOutput string) string {
+	data, _ := json.Marshal(files)
+	return fmt.Sprintf("The following generated Go files fail to build with these compiler errors:\n\n%s\n\nFiles:\n%s\n\nReturn the corrected versions of the files that need changes.", buildOutput, data)
+}
+
+// promptFileName is the name of the file the prompt is saved to in the output
+// directory with --save-prompt.
+const promptFileName = "agent_coder_prompt.txt"
+
+// redactKeys replaces every occurrence of the API keys in text.
+func redactKeys(text string, keys []string) string {
+	for _, key := range keys {
+		text = strings.ReplaceAll(text, key, "[REDACTED]")
+	}
+	return text
+}
+
+// savePrompt writes the assembled prompt to the output directory so the run
+// can be reproduced. The API keys and any secrets found in the context files
+// are redacted first.
+func savePrompt(fsys FS, cfg Config, prompt string) error {
+	if err := fsys.MkdirAll(cfg.OutputDir, os.FileMode(cfg.DirMode)); err != nil {
+		return err
+	}
+	path := filepath.Join(cfg.OutputDir, promptFileName)
+	redacted, _ := redactSecrets(redactKeys(prompt, cfg.apiKeys()))
+	if err := fsys.WriteFile(path, []byte(redacted), 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Prompt saved to %s\n", path)
+	return nil
+}
+
+// continuationPrompt asks the model to resume a response that was cut off.
+func continuationPrompt(prompt, partial string) string {
+	return fmt.Sprintf("%s\n\nYour previous response was cut off. This is what you produced so far:\n\n%s\n\nContinue the output exactly where it stopped. Do not repeat anything and do not add any explanation.", prompt, partial)
+}
@@ -0,0 +1,198 @@
+package agentcoder
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildPromptBracketsPrompt(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.PromptPrefix = "Use context.Context."
+	cfg.PromptSuffix = "Always include unit tests."
+	prompt, err := newPromptChain(cfg).Apply("Write a web server.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buildPrompt(cfg, &promptRequest{Prompt: prompt})
+	want := "Use context.Context.\n\nWrite a web server.\n\nAlways include unit tests."
+	if !strings.HasSuffix(got, ":\n\n"+want) {
+		t.Errorf("buildPrompt() = %q, want the request to end with %q", got, want)
+	}
+}
+
+func TestBuildPromptWithoutAffixes(t *testing.T) {
+	cfg := DefaultConfig()
+	prompt, err := newPromptChain(cfg).Apply("Write a web server.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := buildPrompt(cfg, &promptRequest{Prompt: prompt})
+	if !strings.HasSuffix(got, ":\n\nWrite a web server.") {
+		t.Errorf("buildPrompt() = %q, want the bare prompt", got)
+	}
+}
+
+func TestBuildPromptModifyMode(t *testing.T) {
+	req := &promptRequest{Prompt: "Rename Run to Start.", Context: []File{{Name: "server.go", Code: "package server\n\nfunc Run() {}\n"}}}
+	cfg := DefaultConfig()
+	generate := buildPrompt(cfg, req)
+	cfg.Mode = "modify"
+	modify := buildPrompt(cfg, req)
+
+	for _, want := range []string{"Edit the existing files", "return only the files you change", "Existing files to modify:", "--- server.go ---\npackage server"} {
+		if !strings.Contains(modify, want) {
+			t.Errorf("modify prompt %q does not contain %q", modify, want)
+		}
+	}
+	for _, unwanted := range []string{"Edit the existing files", "Existing files to modify:"} {
+		if strings.Contains(generate, unwanted) {
+			t.Errorf("generate prompt %q contains %q", generate, unwanted)
+		}
+	}
+	if !strings.Contains(generate, "Existing files for context:") {
+		t.Errorf("generate prompt %q does not list the context files", generate)
+	}
+}
+
+func TestReadPromptModifyMode(t *testing.T) {
+	cfg := contextConfig(t, map[string]string{"server.go": "package server\n"})
+	cfg.Mode = "modify"
+	var req *promptRequest
+	var err error
+	captureStdout(t, func() { req, err = readPrompt(context.Background(), cfg, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(req.Text, "Existing files to modify:\n\n--- server.go ---") {
+		t.Errorf("prompt %q does not list server.go to modify", req.Text)
+	}
+
+	cfg.ContextDir = ""
+	out := captureStdout(t, func() { _, err = readPrompt(context.Background(), cfg, nil) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out, "modify mode without context files") {
+		t.Errorf("output %q does not warn about the missing context", out)
+	}
+
+	cfg.Mode = "rewrite"
+	if _, err := readPrompt(context.Background(), cfg, nil); err == nil || !strings.Contains(err.Error(), `unknown mode "rewrite"`) {
+		t.Errorf("readPrompt() error = %v, want unknown mode", err)
+	}
+}
+
+func TestLimitPrompt(t *testing.T) {
+	tests := []struct {
+		name        string
+		prompt      string
+		max         int
+		interactive bool
+		answer      bool
+		want        string
+		wantOK      bool
+		wantAsked   bool
+	}{
+		{name: "within limit", prompt: "short", max: 10, interactive: true, want: "short", wantOK: true},
+		{name: "no limit", prompt: "a long prompt", max: 0, interactive: true, want: "a long prompt", wantOK: true},
+		{name: "confirmed", prompt: "a long prompt", max: 5, interactive: true, answer: true, want: "a long prompt", wantOK: true, wantAsked: true},
+		{name: "declined", prompt: "a long prompt", max: 5, interactive: true, want: "a long prompt", wantAsked: true},
+		{name: "truncated", prompt: "héllo world", max: 5, want: "héllo", wantOK: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			asked := false
+			got, ok := limitPrompt(tt.prompt, tt.max, tt.interactive, func(string) bool {
+				asked = true
+				return tt.answer
+			})
+			if got != tt.want || ok != tt.wantOK || asked != tt.wantAsked {
+				t.Errorf("limitPrompt() = %q, %v, asked %v; want %q, %v, asked %v", got, ok, asked, tt.want, tt.wantOK, tt.wantAsked)
+			}
+		})
+	}
+}
+
+func TestSavePromptMatchesRequest(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.APIKey = "secret-key"
+	cfg.OutputDir = t.TempDir()
+	cfg.Prompt = "Write a client that authenticates with secret-key."
+	cfg.PromptSuffix = "Add tests."
+	cfg.ContextDir = t.TempDir()
+	cfg.SavePrompt = true
+	cfg.NoRaw = true
+	if err := os.WriteFile(filepath.Join(cfg.ContextDir, "util.go"), []byte("package util\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gen := &fakeGenerator{replies: []*reply{{Text: "[]"}}}
+	captureStdout(t, func() {
+		if _, err := requestText(context.Background(), cfg, gen); err != nil {
+			t.Fatal(err)
+		}
+	})
+	saved, err := os.ReadFile(filepath.Join(cfg.OutputDir, promptFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := strings.ReplaceAll(gen.prompts[0], "secret-key", "[REDACTED]"); string(saved) != want {
+		t.Errorf("saved prompt = %q, want %q", saved, want)
+	}
+	for _, part := range []string{"Add tests.", "--- util.go ---", "[REDACTED]"} {
+		if !strings.Contains(string(saved), part) {
+			t.Errorf("saved prompt does not contain %q", part)
+		}
+	}
+}
+
+func TestSavePromptRedactsContextSecrets(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Prompt = "Write a client."
+	cfg.ContextDir = t.TempDir()
+	cfg.SavePrompt = true
+	cfg.NoRaw = true
+	const secret = "ghp_" + "abcdefghijklmnopqrstuvwxyz0123456789"
+	if err := os.WriteFile(filepath.Join(cfg.ContextDir, "client.go"), []byte("package client\n\nconst token = \""+secret+"\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gen := &fakeGenerator{replies: []*reply{{Text: "[]"}}}
+	captureStdout(t, func() {
+		if _, err := requestText(context.Background(), cfg, gen); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if !strings.Contains(gen.prompts[0], secret) {
+		t.Fatal("the context file was not sent to the model")
+	}
+	saved, err := os.ReadFile(filepath.Join(cfg.OutputDir, promptFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(saved), secret) {
+		t.Errorf("saved prompt contains the secret: %q", saved)
+	}
+	if !strings.Contains(string(saved), `const token = "REDACTED"`) {
+		t.Errorf("saved prompt = %q, want the token redacted", saved)
+	}
+}
+
+func TestSavePromptToMemFS(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	cfg.APIKey = "k1"
+	if err := savePrompt(fsys, cfg, "use k1 and k2"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := fsys.ReadFile(filepath.Join("out", promptFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "use [REDACTED] and k2" {
+		t.Errorf("saved prompt = %q, want the key redacted", data)
+	}
+}
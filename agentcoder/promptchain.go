@@ -0,0 +1,108 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// promptTransform is one step of the preprocessing of the user's prompt.
+type promptTransform struct {
+	Name  string                              // Reported with the errors of the step
+	Apply func(prompt string) (string, error) // Returns the rewritten prompt
+}
+
+// promptChain is the ordered list of transforms the user's prompt goes through
+// before it is wrapped in the instruction sent to the model.
+type promptChain []promptTransform
+
+// Apply runs the transforms in order, each on the output of the previous one,
+// and returns the final prompt.
+func (c promptChain) Apply(prompt string) (string, error) {
+	for _, t := range c {
+		var err error
+		if prompt, err = t.Apply(prompt); err != nil {
+			return "", fmt.Errorf("%s: %w", t.Name, err)
+		}
+	}
+	return prompt, nil
+}
+
+// without returns the chain with the transform of the given name left out.
+func (c promptChain) without(name string) promptChain {
+	var chain promptChain
+	for _, t := range c {
+		if t.Name != name {
+			chain = append(chain, t)
+		}
+	}
+	return chain
+}
+
+// Names of the prompt transforms.
+const (
+	transformEnv      = "expand env"
+	transformCompress = "compress"
+	transformIncludes = "expand includes"
+	transformAffixes  = "prefix and suffix"
+)
+
+// newPromptChain returns the transforms enabled by cfg, in the order they are
+// applied: environment variables are expanded first so that they can name
+// included files, then the whitespace of the prompt is compressed, before
+// @path references are replaced so that included code keeps its whitespace,
+// and finally the prefix and suffix are added.
+func newPromptChain(cfg Config) promptChain {
+	var chain promptChain
+	if cfg.PromptExpandEnv {
+		chain = append(chain, promptTransform{Name: transformEnv, Apply: func(prompt string) (string, error) {
+			return expandPromptEnv(prompt), nil
+		}})
+	}
+	if cfg.PromptCompress {
+		chain = append(chain, promptTransform{Name: transformCompress, Apply: func(prompt string) (string, error) {
+			return compressWhitespace(prompt), nil
+		}})
+	}
+	if cfg.PromptInclude {
+		chain = append(chain, promptTransform{Name: transformIncludes, Apply: func(prompt string) (string, error) {
+			return expandIncludes(prompt, cfg.PromptIncludeMaxChars)
+		}})
+	}
+	if cfg.PromptPrefix != "" || cfg.PromptSuffix != "" {
+		chain = append(chain, promptTransform{Name: transformAffixes, Apply: func(prompt string) (string, error) {
+			return addAffixes(prompt, cfg.PromptPrefix, cfg.PromptSuffix), nil
+		}})
+	}
+	return chain
+}
+
+// envReference matches a ${VAR} reference in a prompt.
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandPromptEnv replaces each ${VAR} reference in prompt with the value of
+// the environment variable. Unlike os.ExpandEnv, a bare $ is left alone, as
+// prompts often contain shell snippets and prices, and references to unset
+// variables are kept as they are.
+func expandPromptEnv(prompt string) string {
+	return envReference.ReplaceAllStringFunc(prompt, func(ref string) string {
+		if value, ok := os.LookupEnv(ref[2 : len(ref)-1]); ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// addAffixes brackets prompt with prefix and suffix, separated by blank lines.
+func addAffixes(prompt, prefix, suffix string) string {
+	var parts []string
+	if prefix != "" {
+		parts = append(parts, prefix)
+	}
+	parts = append(parts, prompt)
+	if suffix != "" {
+		parts = append(parts, suffix)
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -0,0 +1,110 @@
+package agentcoder
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPromptChainApply(t *testing.T) {
+	upper := promptTransform{Name: "upper", Apply: func(prompt string) (string, error) {
+		return strings.ToUpper(prompt), nil
+	}}
+	exclaim := promptTransform{Name: "exclaim", Apply: func(prompt string) (string, error) {
+		return prompt + "!", nil
+	}}
+	quote := promptTransform{Name: "quote", Apply: func(prompt string) (string, error) {
+		return `"` + prompt + `"`, nil
+	}}
+	tests := []struct {
+		name  string
+		chain promptChain
+		want  string
+	}{
+		{name: "empty", want: "write a server"},
+		{name: "one", chain: promptChain{upper}, want: "WRITE A SERVER"},
+		{name: "two", chain: promptChain{upper, exclaim}, want: "WRITE A SERVER!"},
+		{name: "order", chain: promptChain{exclaim, quote}, want: `"write a server!"`},
+		{name: "reversed", chain: promptChain{quote, exclaim}, want: `"write a server"!`},
+	}
+	for _, tt := range tests {
+		got, err := tt.chain.Apply("write a server")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: Apply() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// A failing transform stops the chain and is named in the error
+	called := false
+	fail := promptTransform{Name: "fail", Apply: func(string) (string, error) { return "", errors.New("boom") }}
+	after := promptTransform{Name: "after", Apply: func(prompt string) (string, error) {
+		called = true
+		return prompt, nil
+	}}
+	if _, err := (promptChain{upper, fail, after}).Apply("x"); err == nil || err.Error() != "fail: boom" {
+		t.Errorf("Apply() error = %v, want %q", err, "fail: boom")
+	}
+	if called {
+		t.Error("the transform after the failing one was applied")
+	}
+}
+
+func TestNewPromptChainOrder(t *testing.T) {
+	names := func(chain promptChain) []string {
+		var names []string
+		for _, t := range chain {
+			names = append(names, t.Name)
+		}
+		return names
+	}
+	cfg := DefaultConfig()
+	if chain := newPromptChain(cfg); len(chain) != 0 {
+		t.Errorf("default chain = %v, want no transforms", names(chain))
+	}
+
+	cfg.PromptExpandEnv = true
+	cfg.PromptCompress = true
+	cfg.PromptInclude = true
+	cfg.PromptSuffix = "Add tests."
+	chain := newPromptChain(cfg)
+	if want := []string{transformEnv, transformCompress, transformIncludes, transformAffixes}; !reflect.DeepEqual(names(chain), want) {
+		t.Errorf("chain = %v, want %v", names(chain), want)
+	}
+	if want := []string{transformEnv, transformIncludes, transformAffixes}; !reflect.DeepEqual(names(chain.without(transformCompress)), want) {
+		t.Errorf("chain without %s = %v, want %v", transformCompress, names(chain.without(transformCompress)), want)
+	}
+}
+
+func TestNewPromptChainComposes(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "spec.md"), []byte("GET /health   \n\n\n\nreturns 200\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SPEC_DIR", dir)
+	cfg := DefaultConfig()
+	cfg.PromptExpandEnv = true
+	cfg.PromptCompress = true
+	cfg.PromptInclude = true
+	cfg.PromptPrefix = "Use net/http."
+
+	// The variable names the included file, the request is compressed but not
+	// the file, and the prefix comes last
+	got, err := newPromptChain(cfg).Apply("Implement   \n\n\n\n@${SPEC_DIR}/spec.md costing $5 and ${UNSET_PROMPT_VAR}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Use net/http.\n\nImplement\n\nGET /health   \n\n\n\nreturns 200\n costing $5 and ${UNSET_PROMPT_VAR}"
+	if got != want {
+		t.Errorf("Apply() = %q, want %q", got, want)
+	}
+
+	if _, err := newPromptChain(cfg).Apply("@" + filepath.Join(dir, "missing.md")); err == nil || !strings.HasPrefix(err.Error(), transformIncludes+": ") {
+		t.Errorf("Apply() error = %v, want it to name the %q step", err, transformIncludes)
+	}
+}
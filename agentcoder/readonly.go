@@ -0,0 +1,31 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os"
+)
+
+// readonlyMode is the mode of the files written with --readonly.
+const readonlyMode = 0444
+
+// makeReadonly makes the written file at path read-only, warning if that
+// fails. Only files of the OS filesystem have a mode.
+func makeReadonly(fsys FS, path string) {
+	if _, ok := fsys.(osFS); !ok {
+		return
+	}
+	if err := os.Chmod(path, readonlyMode); err != nil {
+		fmt.Printf("Warning: could not make %s read-only: %v\n", path, err)
+	}
+}
+
+// makeWritable lets the owner write an existing file at path that an earlier
+// run made read-only, so that it can be regenerated.
+func makeWritable(fsys FS, path string) {
+	if _, ok := fsys.(osFS); !ok {
+		return
+	}
+	if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0200 == 0 {
+		os.Chmod(path, info.Mode().Perm()|0200)
+	}
+}
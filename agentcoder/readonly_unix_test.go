@@ -0,0 +1,86 @@
+//go:build unix
+
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// permOf returns the permission bits of the file name in dir.
+func permOf(t *testing.T, dir, name string) os.FileMode {
+	t.Helper()
+	info, err := os.Stat(filepath.Join(dir, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestReadonly(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Readonly = true
+	cfg.Language = "none"
+	out := captureStdout(t, func() {
+		if err := writeFiles(osFS{}, cfg, []File{{Name: "a.txt", Code: "a"}, {Name: "sub/b.txt", Code: "b"}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	for _, name := range []string{"a.txt", "sub/b.txt"} {
+		if mode := permOf(t, cfg.OutputDir, name); mode != readonlyMode {
+			t.Errorf("mode of %s = %v, want %v", name, mode, os.FileMode(readonlyMode))
+		}
+	}
+	if !strings.Contains(out, "The files are read-only to discourage manual edits") {
+		t.Errorf("output %q does not explain how to regenerate the read-only files", out)
+	}
+
+	// Regenerating replaces the read-only files and leaves them read-only
+	captureStdout(t, func() {
+		if err := writeFiles(osFS{}, cfg, []File{{Name: "a.txt", Code: "a2"}}); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if got := readTree(t, cfg.OutputDir)["a.txt"]; got != "a2" {
+		t.Errorf("a.txt = %q after regenerating, want %q", got, "a2")
+	}
+	if mode := permOf(t, cfg.OutputDir, "a.txt"); mode != readonlyMode {
+		t.Errorf("mode of a.txt after regenerating = %v, want %v", mode, os.FileMode(readonlyMode))
+	}
+
+}
+
+func TestReadonlyManifest(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Readonly = true
+	cfg.Language = "none"
+	captureStdout(t, func() {
+		writeWithManifest(t, osFS{}, cfg, []File{{Name: "a.txt", Code: "a"}, {Name: "b.txt", Code: "b"}})
+	})
+	manifest, err := loadManifest(osFS{}, cfg.OutputDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.ReadOnly {
+		t.Error("manifest does not record that the files are read-only")
+	}
+	if problems, err := verifyManifest(osFS{}, cfg.OutputDir, manifest); err != nil || len(problems) != 0 {
+		t.Fatalf("verifyManifest() = %v, %v before any change", problems, err)
+	}
+
+	if err := os.Chmod(filepath.Join(cfg.OutputDir, "b.txt"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := verifyManifest(osFS{}, cfg.OutputDir, manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b.txt: made writable since generation"}; !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems = %q, want %q", problems, want)
+	}
+}
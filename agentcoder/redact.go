@@ -0,0 +1,59 @@
+package agentcoder
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// secretPattern matches a kind of secret and describes its replacement.
+type secretPattern struct {
+	name    string
+	re      *regexp.Regexp
+	replace string
+}
+
+// secretPatterns are the well-known secret formats removed by --redact. The
+// specific token formats come first so that the generic assignments only see
+// what is left.
+var secretPatterns = []secretPattern{
+	{"private key", regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), "REDACTED_PRIVATE_KEY"},
+	{"AWS access key", regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), "REDACTED"},
+	{"Google API key", regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`), "REDACTED"},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`), "REDACTED"},
+	{"Slack token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9\-]{10,}\b`), "REDACTED"},
+	{"quoted secret", regexp.MustCompile(`(?i)((?:api[_-]?key|secret[_-]?key|client[_-]?secret|access[_-]?token|auth[_-]?token|password|passwd)["']?\s*[:=]+\s*)(["'])([^"'\s]{12,})(["'])`), "${1}${2}REDACTED${4}"},
+	{"environment secret", regexp.MustCompile(`(?m)^(\s*(?:export\s+)?[A-Z0-9_]*(?:API_KEY|SECRET|TOKEN|PASSWORD)[A-Z0-9_]*\s*=\s*)([^\s"'#]{12,})`), "${1}REDACTED"},
+}
+
+// redactSecrets replaces the secrets found in code with placeholders and
+// returns the redacted code together with the number of replacements.
+func redactSecrets(code string) (string, int) {
+	count := 0
+	for _, pattern := range secretPatterns {
+		matches := len(pattern.re.FindAllStringIndex(code, -1))
+		if matches == 0 {
+			continue
+		}
+		count += matches
+		code = pattern.re.ReplaceAllString(code, pattern.replace)
+	}
+	return code, count
+}
+
+// redactFiles removes secrets from every file and reports what was redacted.
+func redactFiles(files []File) []File {
+	total := 0
+	for i, file := range files {
+		code, count := redactSecrets(file.Code)
+		if count == 0 {
+			continue
+		}
+		fmt.Printf("Redacted %d secret(s) in %s\n", count, file.Name)
+		files[i].Code = code
+		total += count
+	}
+	if total > 0 {
+		fmt.Printf("Redacted %d secret(s) in total\n", total)
+	}
+	return files
+}
@@ -0,0 +1,139 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// responseDump is the raw response saved with --debug-dump.
+type responseDump struct {
+	Model          string    `json:"model"`            // Model that generated the response
+	ResponseFormat string    `json:"response_format"`  // Format of the response: json or text
+	OutputFile     string    `json:"output_file"`      // Name of the file written in text mode
+	Parser         string    `json:"parser,omitempty"` // Parser for the files in the response
+	CreatedAt      time.Time `json:"created_at"`       // Time the response was received
+	Text           string    `json:"text"`             // Raw text of the response
+}
+
+// saveDump writes the raw response to the --debug-dump file.
+func saveDump(cfg Config, text string) error {
+	dump := responseDump{
+		Model:          cfg.Model,
+		ResponseFormat: cfg.ResponseFormat,
+		OutputFile:     cfg.OutputFile,
+		Parser:         cfg.Parser,
+		CreatedAt:      time.Now().UTC(),
+		Text:           text,
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(cfg.DebugDump, data, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Raw response saved to %s\n", cfg.DebugDump)
+	return nil
+}
+
+// loadDump reads a response saved with --debug-dump.
+func loadDump(path string) (responseDump, error) {
+	var dump responseDump
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return dump, err
+	}
+	if err := json.Unmarshal(data, &dump); err != nil {
+		return dump, fmt.Errorf("parsing dump %s: %w", path, err)
+	}
+	return dump, nil
+}
+
+// apply sets the settings the response was requested with in cfg, so it is
+// parsed the same way.
+func (d responseDump) apply(cfg *Config) {
+	if d.ResponseFormat != "" {
+		cfg.ResponseFormat = d.ResponseFormat
+	}
+	if d.OutputFile != "" {
+		cfg.OutputFile = d.OutputFile
+	}
+	if d.Parser != "" {
+		cfg.Parser = d.Parser
+	}
+}
+
+// runReplay implements the replay subcommand, which writes the files of
+// responses saved with --debug-dump without calling the API. Every dump is
+// replayed even if an earlier one fails, so a directory of recorded responses
+// can be checked in one run.
+func runReplay(args []string) {
+	cfg, rest, err := parseConfig(args, nil)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(rest) == 0 {
+		fmt.Println("Usage: replay [flags] <dump-file>...")
+		os.Exit(1)
+	}
+	// Keep stdout for the files and log everything else to stderr
+	stdout := os.Stdout
+	if cfg.Stdout {
+		os.Stdout = os.Stderr
+	}
+	failed := 0
+	for _, path := range rest {
+		if err := replayDump(cfg, path, stdout); err != nil {
+			fmt.Printf("Error: %s: %v\n", path, err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		fmt.Printf("\n%d of %d dump(s) failed to replay\n", failed, len(rest))
+		os.Exit(1)
+	}
+}
+
+// replayDump writes the files of the response saved at path, emitting them to
+// stdout with --stdout.
+func replayDump(cfg Config, path string, stdout io.Writer) error {
+	dump, err := loadDump(path)
+	if err != nil {
+		return err
+	}
+
+	// Parse the response the way it was requested
+	dump.apply(&cfg)
+	files, err := filesFromText(cfg, dump.Text)
+	if err != nil {
+		return err
+	}
+	files = transformFiles(cfg, files)
+	if files, err = filterFiles(cfg, files); err != nil {
+		return err
+	}
+	if err := checkFileCount(cfg, files); err != nil {
+		return err
+	}
+	if cfg.Inject {
+		return injectFiles(osFS{}, cfg, files)
+	}
+	if cfg.Concat == "" {
+		if err := checkDestructive(osFS{}, cfg, files, terminalConfirm()); err != nil {
+			return err
+		}
+	}
+	var results []writeResult
+	report := func(_ File, result writeResult) { results = append(results, result) }
+	if err := writeSinks(newSinks(cfg, stdout, report, nil), files); err != nil {
+		return err
+	}
+	if cfg.Manifest && cfg.Concat == "" {
+		saveManifest(osFS{}, cfg, files, results)
+	}
+	return nil
+}
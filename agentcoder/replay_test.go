@@ -0,0 +1,78 @@
+package agentcoder
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readTree returns the content of every file under dir by slash separated
+// path relative to dir.
+func readTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	tree := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		tree[filepath.ToSlash(name)] = string(data)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestReplayProducesSameTree(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Prompt = "Write a package."
+	cfg.OutputDir = t.TempDir()
+	cfg.DebugDump = filepath.Join(t.TempDir(), "dump.json")
+	cfg.NoRaw = true
+	gen := &fakeGenerator{replies: []*reply{filesReply(t,
+		File{Name: "main.go", Code: "package main\n\nfunc main() {}\n"},
+		File{Name: "internal/util/util.go", Code: "package util\n"},
+		File{Name: "README.md", Code: "# Tool\n"},
+	)}}
+	files, err := generate(context.Background(), cfg, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFiles(osFS{}, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+
+	replayDir := t.TempDir()
+	captureStdout(t, func() { runReplay([]string{"-output", replayDir, cfg.DebugDump}) })
+	want := readTree(t, cfg.OutputDir)
+	if got := readTree(t, replayDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed tree = %v, want %v", got, want)
+	}
+	if len(want) != 3 {
+		t.Errorf("generated tree has %d files, want 3", len(want))
+	}
+}
+
+func TestReplayTextDump(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ResponseFormat = "text"
+	cfg.OutputFile = "notes.txt"
+	cfg.DebugDump = filepath.Join(t.TempDir(), "dump.json")
+	if err := saveDump(cfg, "plain text"); err != nil {
+		t.Fatal(err)
+	}
+	replayDir := t.TempDir()
+	captureStdout(t, func() { runReplay([]string{"-output", replayDir, cfg.DebugDump}) })
+	if got := readTree(t, replayDir); !reflect.DeepEqual(got, map[string]string{"notes.txt": "plain text"}) {
+		t.Errorf("replayed tree = %v, want notes.txt in text mode", got)
+	}
+}
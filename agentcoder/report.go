@@ -0,0 +1,107 @@
+package agentcoder
+
+import (
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// reportTemplate is the self-contained HTML report written with --report.
+var reportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>agent_coder report</title>
+<style>
+body { font-family: system-ui, sans-serif; margin: 2em auto; max-width: 60em; color: #222; }
+h1 { font-size: 1.5em; }
+h2 { font-size: 1.1em; font-family: ui-monospace, monospace; margin-top: 2em; }
+dt { font-weight: bold; }
+dd { margin: 0 0 1em 0; }
+.prompt { white-space: pre-wrap; background: #f6f8fa; padding: 1em; border-radius: 4px; }
+pre { background: #f6f8fa; padding: 1em; border-radius: 4px; overflow-x: auto; }
+.size { color: #666; font-weight: normal; font-family: system-ui, sans-serif; }
+.kw { color: #a626a4; font-weight: bold; }
+.str { color: #50a14f; }
+.com { color: #a0a1a7; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>agent_coder report</h1>
+<dl>
+<dt>Model</dt><dd>{{.Model}}</dd>
+<dt>Generated</dt><dd>{{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</dd>
+<dt>Prompt</dt><dd><div class="prompt">{{.Prompt}}</div></dd>
+</dl>
+<h1>Files ({{len .Files}})</h1>
+{{range .Files}}<h2 id="{{.Name}}">{{.Name}} <span class="size">{{.Size}} bytes</span></h2>
+<pre><code class="language-{{.Language}}">{{.Code}}</code></pre>
+{{end}}</body>
+</html>
+`))
+
+// reportFile is a generated file as shown in the report.
+type reportFile struct {
+	Name     string
+	Size     int
+	Language string        // Extension of the file, used as the code block language
+	Code     template.HTML // Escaped and highlighted source code
+}
+
+// htmlReport is the data of the report template.
+type htmlReport struct {
+	Model       string
+	GeneratedAt time.Time
+	Prompt      string
+	Files       []reportFile
+}
+
+// highlightToken matches the comments, strings and keywords highlighted in the
+// report. The keywords are the common ones of the languages usually generated.
+var highlightToken = regexp.MustCompile(`(?s)(//[^\n]*|/\*.*?\*/|#[^\n]*)|("(?:[^"\\\n]|\\.)*"|'(?:[^'\\\n]|\\.)*'|` + "`[^`]*`" + `)|\b(break|case|class|const|continue|def|default|defer|elif|else|export|false|fn|for|from|func|function|go|if|impl|import|interface|let|map|match|nil|None|null|package|pub|return|self|struct|switch|true|True|False|type|use|var|while)\b`)
+
+// highlight escapes code for HTML and wraps comments, strings and keywords in
+// spans styled by the report.
+func highlight(code string) template.HTML {
+	var b strings.Builder
+	last := 0
+	for _, m := range highlightToken.FindAllStringSubmatchIndex(code, -1) {
+		b.WriteString(html.EscapeString(code[last:m[0]]))
+		class := "kw"
+		if m[2] >= 0 {
+			class = "com"
+		} else if m[4] >= 0 {
+			class = "str"
+		}
+		b.WriteString(`<span class="` + class + `">` + html.EscapeString(code[m[0]:m[1]]) + `</span>`)
+		last = m[1]
+	}
+	b.WriteString(html.EscapeString(code[last:]))
+	return template.HTML(b.String())
+}
+
+// writeReport writes the HTML report of the generated files to path.
+func writeReport(path string, cfg Config, files []File) error {
+	report := htmlReport{Model: cfg.Model, GeneratedAt: time.Now().UTC(), Prompt: cfg.Prompt}
+	for _, file := range files {
+		report.Files = append(report.Files, reportFile{
+			Name:     file.Name,
+			Size:     len(file.Code),
+			Language: strings.TrimPrefix(strings.ToLower(filepath.Ext(file.Name)), "."),
+			Code:     highlight(file.Code),
+		})
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := reportTemplate.Execute(f, report); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
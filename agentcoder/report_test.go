@@ -0,0 +1,44 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWriteReport(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Model = "gemini-2.0-flash"
+	cfg.Prompt = "Write a <greeting> tool."
+	path := filepath.Join(t.TempDir(), "report.html")
+	files := []File{
+		{Name: "main.go", Code: "package main\n\n// Greets\nfunc main() { println(\"<hi>\") }\n"},
+		{Name: "README.md", Code: "# Greeting\n"},
+	}
+	if err := writeReport(path, cfg, files); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+	for _, want := range []string{
+		"<dd>gemini-2.0-flash</dd>",
+		"Write a &lt;greeting&gt; tool.",
+		`<h2 id="main.go">main.go`,
+		`<pre><code class="language-go"><span class="kw">package</span> main`,
+		`<span class="com">// Greets</span>`,
+		`<span class="str">&#34;&lt;hi&gt;&#34;</span>`,
+		`<h2 id="README.md">README.md`,
+		`<pre><code class="language-md">`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+	if strings.Contains(report, "<hi>") {
+		t.Error("report contains unescaped code")
+	}
+}
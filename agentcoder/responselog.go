@@ -0,0 +1,63 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"time"
+)
+
+// responseLogEntry is a line of the --response-log file.
+type responseLogEntry struct {
+	Time           time.Time `json:"time"`                      // Time the response was received
+	Model          string    `json:"model"`                     // Model that served the request
+	FinishReason   string    `json:"finish_reason"`             // Why the model stopped generating
+	PromptTokens   int32     `json:"prompt_tokens,omitempty"`   // Tokens of the prompt, if reported
+	ResponseTokens int32     `json:"response_tokens,omitempty"` // Tokens of the response, if reported
+	Prompt         string    `json:"prompt"`                    // Prompt sent, with the API keys redacted
+	Text           string    `json:"text"`                      // Raw text of the response, with the API keys redacted
+}
+
+// appendResponseLog appends the response to the log at path as a line of
+// JSON. When the entry would grow the log past maxSize bytes, the log is first
+// rotated to path.1, replacing an older rotated log. A maxSize of zero
+// disables rotation.
+func appendResponseLog(path string, maxSize int64, keys []string, prompt string, r *reply) error {
+	entry := responseLogEntry{
+		Time:         time.Now().UTC(),
+		Model:        r.Model,
+		FinishReason: r.FinishReason.String(),
+		Prompt:       redactKeys(prompt, keys),
+		Text:         redactKeys(r.Text, keys),
+	}
+	if r.Usage != nil {
+		entry.PromptTokens = r.Usage.PromptTokenCount
+		entry.ResponseTokens = r.Usage.CandidatesTokenCount
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	info, err := os.Stat(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if maxSize > 0 && err == nil && info.Size() > 0 && info.Size()+int64(len(line)) > maxSize {
+		if err := os.Rename(path, path+".1"); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
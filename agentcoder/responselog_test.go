@@ -0,0 +1,73 @@
+package agentcoder
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// readResponseLog returns the entries of the response log at path.
+func readResponseLog(t *testing.T, path string) []responseLogEntry {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var entries []responseLogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry responseLogEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("invalid log line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestResponseLogAppendAndRotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.jsonl")
+	keys := []string{"secret-key"}
+	r := &reply{
+		Model:        "gemini-2.0-flash",
+		Text:         `[{"file_name": "key.txt", "source_code": "secret-key"}]`,
+		FinishReason: genai.FinishReasonStop,
+		Usage:        &genai.UsageMetadata{PromptTokenCount: 10, CandidatesTokenCount: 20},
+	}
+	for _, prompt := range []string{"first with secret-key", "second"} {
+		if err := appendResponseLog(path, 0, keys, prompt, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := readResponseLog(t, path)
+	if len(entries) != 2 || entries[0].Prompt != "first with [REDACTED]" || entries[1].Prompt != "second" {
+		t.Fatalf("entries = %+v, want the two prompts in order", entries)
+	}
+	if e := entries[0]; e.Model != "gemini-2.0-flash" || e.FinishReason != "FinishReasonStop" || e.PromptTokens != 10 || e.ResponseTokens != 20 {
+		t.Errorf("entry = %+v, want the metadata of the reply", e)
+	}
+	if strings.Contains(entries[0].Text, "secret-key") {
+		t.Error("log contains the API key")
+	}
+
+	// The next entry does not fit, so the log is rotated
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := appendResponseLog(path, info.Size()+1, keys, "third", r); err != nil {
+		t.Fatal(err)
+	}
+	if rotated := readResponseLog(t, path+".1"); len(rotated) != 2 {
+		t.Errorf("rotated log has %d entries, want 2", len(rotated))
+	}
+	if current := readResponseLog(t, path); len(current) != 1 || current[0].Prompt != "third" {
+		t.Errorf("log after rotation = %+v, want only the third entry", current)
+	}
+}
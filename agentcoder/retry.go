@@ -0,0 +1,97 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultRetryMaxDelay caps the delay between retries when --retry-max-delay
+// is 0.
+const defaultRetryMaxDelay = 30 * time.Second
+
+// maxRetryShift bounds the exponent of the exponential strategy. Delays stop
+// growing long before, at the maximum delay.
+const maxRetryShift = 32
+
+// retryStrategies compute the delay before the given retry, counting from 1,
+// from the base delay. Delays too long to represent are returned as the
+// longest duration.
+var retryStrategies = map[string]func(base time.Duration, attempt int) time.Duration{
+	"exponential": func(base time.Duration, attempt int) time.Duration {
+		shift := min(attempt-1, maxRetryShift)
+		if base > math.MaxInt64>>shift {
+			return math.MaxInt64
+		}
+		return base << shift
+	},
+	"linear": func(base time.Duration, attempt int) time.Duration {
+		if base > math.MaxInt64/time.Duration(attempt) {
+			return math.MaxInt64
+		}
+		return base * time.Duration(attempt)
+	},
+	"constant": func(base time.Duration, attempt int) time.Duration { return base },
+}
+
+// retryPolicy decides how often and after which delays a failed request is
+// retried.
+type retryPolicy struct {
+	retries  int
+	base     time.Duration
+	max      time.Duration
+	strategy func(base time.Duration, attempt int) time.Duration
+
+	// sleep waits for the delay, returning early with an error when ctx is
+	// done. It is replaced to test the policy without waiting.
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// newRetryPolicy returns the retry policy configured in cfg.
+func newRetryPolicy(cfg Config) (retryPolicy, error) {
+	strategy, ok := retryStrategies[cfg.RetryStrategy]
+	if !ok {
+		names := make([]string, 0, len(retryStrategies))
+		for name := range retryStrategies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return retryPolicy{}, fmt.Errorf("unknown retry strategy %q, use one of %s", cfg.RetryStrategy, strings.Join(names, ", "))
+	}
+	max := time.Duration(cfg.RetryMaxDelay) * time.Millisecond
+	if max <= 0 {
+		max = defaultRetryMaxDelay
+	}
+	return retryPolicy{
+		retries:  cfg.Retries,
+		base:     time.Duration(cfg.RetryBaseDelay) * time.Millisecond,
+		max:      max,
+		strategy: strategy,
+		sleep:    sleepContext,
+	}, nil
+}
+
+// delay returns the delay before the given retry, counting from 1, capped at
+// the maximum delay.
+func (p retryPolicy) delay(attempt int) time.Duration {
+	d := p.strategy(p.base, attempt)
+	if d > p.max || d < 0 {
+		d = p.max
+	}
+	return d
+}
+
+// sleepContext waits for d or until ctx is done.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,109 @@
+package agentcoder
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRetryDelays(t *testing.T) {
+	tests := []struct {
+		strategy string
+		want     []time.Duration
+	}{
+		{"exponential", []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 800 * time.Millisecond, time.Second}},
+		{"linear", []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond}},
+		{"constant", []time.Duration{100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond, 100 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.RetryStrategy = tt.strategy
+		cfg.RetryBaseDelay = 100
+		cfg.RetryMaxDelay = 1000
+		p, err := newRetryPolicy(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []time.Duration
+		for attempt := 1; attempt <= len(tt.want); attempt++ {
+			got = append(got, p.delay(attempt))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s delays = %v, want %v", tt.strategy, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelayDoesNotOverflow(t *testing.T) {
+	for strategy := range retryStrategies {
+		cfg := DefaultConfig()
+		cfg.RetryStrategy = strategy
+		cfg.RetryBaseDelay = 1000
+		cfg.RetryMaxDelay = 0
+		p, err := newRetryPolicy(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, attempt := range []int{1, 30, 64, 100, 1000} {
+			if d := p.delay(attempt); d <= 0 || d > defaultRetryMaxDelay {
+				t.Errorf("%s delay(%d) = %v, want between 0 and %v", strategy, attempt, d, defaultRetryMaxDelay)
+			}
+		}
+		if d := p.delay(1000); strategy != "constant" && d != defaultRetryMaxDelay {
+			t.Errorf("%s delay(1000) = %v, want %v", strategy, d, defaultRetryMaxDelay)
+		}
+	}
+}
+
+func TestUnknownRetryStrategy(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RetryStrategy = "random"
+	if _, err := newRetryPolicy(cfg); err == nil {
+		t.Error("newRetryPolicy() succeeded, want an error for an unknown strategy")
+	}
+}
+
+func TestGenerateRetries(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retries = 2
+	cfg.RetryStrategy = "linear"
+	cfg.RetryBaseDelay = 500
+	gen, api := newTestGenerator(t, cfg,
+		errorResponse(http.StatusInternalServerError, "An internal error has occurred."),
+		errorResponse(http.StatusInternalServerError, "An internal error has occurred."),
+		textResponse("[]", "STOP"),
+	)
+	var slept []time.Duration
+	gen.retry.sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+	var err error
+	captureStdout(t, func() { _, err = gen.Generate(context.Background(), "Write nothing.") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []time.Duration{500 * time.Millisecond, time.Second}; !reflect.DeepEqual(slept, want) {
+		t.Errorf("slept %v, want %v", slept, want)
+	}
+	if api.requests() != 3 {
+		t.Errorf("%d requests, want 3", api.requests())
+	}
+}
+
+func TestGenerateRetriesCanceled(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Retries = 3
+	gen, api := newTestGenerator(t, cfg, errorResponse(http.StatusInternalServerError, "An internal error has occurred."))
+	gen.retry.sleep = func(ctx context.Context, d time.Duration) error { return context.Canceled }
+	var err error
+	captureStdout(t, func() { _, err = gen.Generate(context.Background(), "Write nothing.") })
+	if err != context.Canceled {
+		t.Errorf("Generate() error = %v, want %v", err, context.Canceled)
+	}
+	if api.requests() != 1 {
+		t.Errorf("%d requests, want 1", api.requests())
+	}
+}
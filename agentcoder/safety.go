@@ -0,0 +1,38 @@
+package agentcoder
+
+import (
+	"fmt"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+// safetyThresholds maps the names accepted by --safety to block thresholds.
+var safetyThresholds = map[string]genai.HarmBlockThreshold{
+	"none":   genai.HarmBlockNone,
+	"low":    genai.HarmBlockLowAndAbove,
+	"medium": genai.HarmBlockMediumAndAbove,
+	"high":   genai.HarmBlockOnlyHigh,
+}
+
+// safetyProbabilities maps the names accepted by --fail-on-safety to harm
+// probabilities.
+var safetyProbabilities = map[string]genai.HarmProbability{
+	"negligible": genai.HarmProbabilityNegligible,
+	"low":        genai.HarmProbabilityLow,
+	"medium":     genai.HarmProbabilityMedium,
+	"high":       genai.HarmProbabilityHigh,
+}
+
+// checkSafety returns an error naming the first rating whose probability meets
+// or exceeds threshold. A zero threshold disables the check.
+func checkSafety(ratings []*genai.SafetyRating, threshold genai.HarmProbability) error {
+	if threshold == genai.HarmProbabilityUnspecified {
+		return nil
+	}
+	for _, rating := range ratings {
+		if rating.Probability >= threshold {
+			return fmt.Errorf("safety check failed: %s has probability %s", rating.Category, rating.Probability)
+		}
+	}
+	return nil
+}
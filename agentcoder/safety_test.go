@@ -0,0 +1,46 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+)
+
+func TestCheckSafety(t *testing.T) {
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+		Content: &genai.Content{Parts: []genai.Part{genai.Text("[]")}},
+		SafetyRatings: []*genai.SafetyRating{
+			{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityLow},
+			{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh},
+		},
+	}}}
+	r, err := newReply(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		threshold string
+		want      string
+	}{
+		{threshold: "", want: ""},
+		{threshold: "high", want: "HarmCategoryDangerousContent has probability HarmProbabilityHigh"},
+		{threshold: "low", want: "HarmCategoryHarassment has probability HarmProbabilityLow"},
+	}
+	for _, tt := range tests {
+		err := checkSafety(r.SafetyRatings, safetyProbabilities[tt.threshold])
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("threshold %q: %v, want no error", tt.threshold, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("threshold %q: %v, want %q", tt.threshold, err, tt.want)
+		}
+	}
+}
+
+func TestCheckSafetyBelowThreshold(t *testing.T) {
+	ratings := []*genai.SafetyRating{{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityMedium}}
+	if err := checkSafety(ratings, genai.HarmProbabilityHigh); err != nil {
+		t.Errorf("checkSafety() = %v, want no error below the threshold", err)
+	}
+}
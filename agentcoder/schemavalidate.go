@@ -0,0 +1,69 @@
+package agentcoder
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// checkResponseSchema validates the response against the JSON Schema file
+// given with --validate-schema and reports every violation. A response that is
+// itself JSON is validated as it is, so the schema can require fields beyond
+// those of the files; other responses are validated as the array of files
+// they were parsed into.
+func checkResponseSchema(path, text string, files []File) error {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	schema, err := compiler.Compile(path)
+	if err != nil {
+		return fmt.Errorf("loading schema: %w", err)
+	}
+
+	doc, err := decodeJSON([]byte(text))
+	if err != nil {
+		data, err := json.Marshal(files)
+		if err != nil {
+			return err
+		}
+		if doc, err = decodeJSON(data); err != nil {
+			return err
+		}
+	}
+
+	err = schema.Validate(doc)
+	var verr *jsonschema.ValidationError
+	if !errors.As(err, &verr) {
+		return err
+	}
+	var violations []string
+	for _, e := range verr.BasicOutput().Errors {
+		if e.Error == "" || strings.HasPrefix(e.Error, "doesn't validate with") {
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("%s: %s", cmp.Or(e.InstanceLocation, "/"), e.Error))
+	}
+	if len(violations) == 0 {
+		violations = append(violations, verr.Message)
+	}
+	return fmt.Errorf("response does not match schema %s:\n  %s", path, strings.Join(violations, "\n  "))
+}
+
+// decodeJSON decodes a JSON document keeping its numbers exact, as the schema
+// validator expects.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return doc, nil
+}
@@ -0,0 +1,71 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeSchema writes a JSON Schema file and returns its path.
+func writeSchema(t *testing.T, schema string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "schema.json")
+	if err := os.WriteFile(path, []byte(schema), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// licenseSchema requires a license field in every file entry besides its name
+// and source code.
+const licenseSchema = `{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"type": "array",
+	"items": {
+		"type": "object",
+		"required": ["file_name", "source_code", "license"],
+		"properties": {"license": {"type": "string"}}
+	}
+}`
+
+func TestValidateSchema(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ValidateSchema = writeSchema(t, licenseSchema)
+
+	valid := `[{"file_name": "a.go", "source_code": "package a", "license": "MIT"}]`
+	if _, err := parseResponse(cfg, valid); err != nil {
+		t.Errorf("parseResponse() of a valid response: %v", err)
+	}
+
+	invalid := `[{"file_name": "a.go", "source_code": "package a", "license": "MIT"},
+		{"file_name": "b.go", "source_code": "package b"},
+		{"file_name": "c.go", "source_code": "package c", "license": 3}]`
+	_, err := parseResponse(cfg, invalid)
+	if err == nil {
+		t.Fatal("parseResponse() succeeded, want the violations of the schema")
+	}
+	for _, want := range []string{"response does not match schema", "/1: missing properties: 'license'", "/2/license: expected string, but got number"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not report %q", err, want)
+		}
+	}
+}
+
+func TestValidateSchemaFencedFiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Parser = "fenced-files"
+	cfg.ValidateSchema = writeSchema(t, licenseSchema)
+	_, err := parseResponse(cfg, "```go a.go\npackage a\n```\n")
+	if err == nil || !strings.Contains(err.Error(), "/0: missing properties: 'license'") {
+		t.Errorf("parseResponse() error = %v, want the parsed files validated", err)
+	}
+}
+
+func TestValidateSchemaInvalidFile(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.ValidateSchema = writeSchema(t, `{"type": 5}`)
+	if _, err := parseResponse(cfg, `[]`); err == nil || !strings.Contains(err.Error(), "loading schema") {
+		t.Errorf("parseResponse() error = %v, want loading schema", err)
+	}
+}
@@ -0,0 +1,86 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// schemaVersion is the version of the format of plans and manifests, recorded
+// in them so that older files can be upgraded on load and files from newer
+// releases rejected. Files written before the version was recorded are
+// version 1.
+const schemaVersion = 2
+
+// migration upgrades the JSON object of a plan or manifest by one version.
+type migration func(doc map[string]json.RawMessage) error
+
+// planMigrations and manifestMigrations upgrade plans and manifests from the
+// version at their index plus one to the next.
+var (
+	planMigrations = []migration{
+		// Version 2 records the version; fill in the summary of plans
+		// written by hand or by tools that left it out
+		func(doc map[string]json.RawMessage) error {
+			if _, ok := doc["summary"]; ok {
+				return nil
+			}
+			var files []PlanFile
+			if raw, ok := doc["files"]; ok {
+				if err := json.Unmarshal(raw, &files); err != nil {
+					return err
+				}
+			}
+			summary := map[string]int{statusNew: 0, statusModified: 0, statusIdentical: 0}
+			for _, file := range files {
+				summary[file.Status]++
+			}
+			return setField(doc, "summary", summary)
+		},
+	}
+	manifestMigrations = []migration{
+		// Version 2 records the version, the rest is unchanged
+		func(map[string]json.RawMessage) error { return nil },
+	}
+)
+
+// setField stores value as the field name of doc.
+func setField(doc map[string]json.RawMessage, name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	doc[name] = data
+	return nil
+}
+
+// decodeVersioned decodes the plan or manifest in data into v, described as
+// kind in errors, after upgrading it to the current schema version with
+// migrations. Versions newer than this release supports are rejected.
+func decodeVersioned(data []byte, kind string, migrations []migration, v any) error {
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	version := 1
+	if raw, ok := doc["schema_version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil || version < 1 {
+			return fmt.Errorf("invalid schema version %s", raw)
+		}
+	}
+	if version > schemaVersion {
+		return fmt.Errorf("%s has schema version %d, but this release of agent_coder reads up to version %d; upgrade agent_coder to read it", kind, version, schemaVersion)
+	}
+	for ; version < schemaVersion; version++ {
+		if err := migrations[version-1](doc); err != nil {
+			return fmt.Errorf("upgrading %s from schema version %d: %w", kind, version, err)
+		}
+	}
+	if err := setField(doc, "schema_version", schemaVersion); err != nil {
+		return err
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,99 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadPlanVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	v1 := `{
+  "against": "out",
+  "files": [
+    {"file_name": "main.go", "source_code": "package main\n", "status": "new"},
+    {"file_name": "util.go", "source_code": "package main\n", "status": "modified"},
+    {"file_name": "go.mod", "source_code": "module x\n", "status": "new"}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := loadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.SchemaVersion != schemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", plan.SchemaVersion, schemaVersion)
+	}
+	if len(plan.Files) != 3 || plan.Files[1].Name != "util.go" || plan.Files[1].Status != statusModified || plan.Against != "out" {
+		t.Errorf("plan = %+v, want the files of the version 1 plan", plan)
+	}
+	want := map[string]int{statusNew: 2, statusModified: 1, statusIdentical: 0}
+	if !reflect.DeepEqual(plan.Summary, want) {
+		t.Errorf("Summary = %v, want %v", plan.Summary, want)
+	}
+}
+
+func TestLoadPlanKeepsSummary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	data := `{"files": [{"file_name": "a.go", "status": "new"}], "summary": {"new": 7}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	plan, err := loadPlan(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{statusNew: 7}; !reflect.DeepEqual(plan.Summary, want) {
+		t.Errorf("Summary = %v, want the recorded %v", plan.Summary, want)
+	}
+}
+
+func TestLoadManifestVersion1(t *testing.T) {
+	fsys := newMemFS()
+	v1 := `{"model": "gemini-1.5-pro", "generated_at": "2024-05-01T10:00:00Z", "files": [{"file_name": "main.go", "sha256": "abc", "size": 13}]}`
+	if err := fsys.MkdirAll("out", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile(filepath.Join("out", manifestFileName), []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := loadManifest(fsys, "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.SchemaVersion != schemaVersion || manifest.Model != "gemini-1.5-pro" || len(manifest.Files) != 1 || manifest.Files[0].SHA256 != "abc" {
+		t.Errorf("manifest = %+v, want the version 1 manifest upgraded to %d", manifest, schemaVersion)
+	}
+}
+
+func TestDecodeVersionedErrors(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"schema_version": 3, "files": []}`, "plan has schema version 3, but this release of agent_coder reads up to version 2; upgrade agent_coder"},
+		{`{"schema_version": 0}`, "invalid schema version 0"},
+		{`{"schema_version": "2"}`, `invalid schema version "2"`},
+		{`{"files": "main.go"}`, "upgrading plan from schema version 1"},
+		{`[]`, "cannot unmarshal"},
+	}
+	for _, tt := range tests {
+		var plan Plan
+		err := decodeVersioned([]byte(tt.data), "plan", planMigrations, &plan)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("decodeVersioned(%s) = %v, want an error containing %q", tt.data, err, tt.want)
+		}
+	}
+}
+
+func TestMigrationsCoverEveryVersion(t *testing.T) {
+	for kind, migrations := range map[string][]migration{"plan": planMigrations, "manifest": manifestMigrations} {
+		if len(migrations) != schemaVersion-1 {
+			t.Errorf("%d %s migrations, want one per version up to %d", len(migrations), kind, schemaVersion)
+		}
+	}
+}
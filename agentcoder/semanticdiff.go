@@ -0,0 +1,124 @@
+package agentcoder
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+)
+
+// Kinds of change of a top-level declaration.
+const (
+	declAdded    = "added"
+	declRemoved  = "removed"
+	declModified = "modified"
+)
+
+// declChange is a top-level declaration added, removed or modified between
+// two versions of a Go file.
+type declChange struct {
+	Change string // One of added, removed or modified
+	Decl   string // Kind and name of the declaration, such as "func (*Server) Start"
+}
+
+// goDecls returns the top-level declarations of the Go source src, keyed by
+// kind and name, with their source normalized by the printer so that
+// formatting and comments do not count as changes. The keys are returned in
+// source order.
+func goDecls(src string) (map[string]string, []string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.SkipObjectResolution)
+	if err != nil {
+		return nil, nil, err
+	}
+	// Printed without the positions of the source, declarations are laid
+	// out the same way whatever the line breaks in the file
+	layout := token.NewFileSet()
+	decls := make(map[string]string)
+	var keys []string
+	add := func(key string, node any) {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, layout, node)
+		if _, ok := decls[key]; !ok {
+			keys = append(keys, key)
+		}
+		decls[key] += buf.String()
+	}
+	for _, decl := range file.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			key := "func " + d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				var recv bytes.Buffer
+				printer.Fprint(&recv, layout, d.Recv.List[0].Type)
+				key = fmt.Sprintf("func (%s) %s", recv.String(), d.Name.Name)
+			}
+			add(key, d)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					add("type "+s.Name.Name, s)
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name != "_" {
+							add(d.Tok.String()+" "+name.Name, s)
+						}
+					}
+				}
+			}
+		}
+	}
+	return decls, keys, nil
+}
+
+// semanticDiff compares the top-level declarations of two versions of a Go
+// file. A renamed declaration is reported as removed under its old name and
+// added under the new one.
+func semanticDiff(old, new string) ([]declChange, error) {
+	oldDecls, oldKeys, err := goDecls(old)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the existing file: %w", err)
+	}
+	newDecls, newKeys, err := goDecls(new)
+	if err != nil {
+		return nil, fmt.Errorf("parsing the generated file: %w", err)
+	}
+	var changes []declChange
+	for _, key := range oldKeys {
+		if _, ok := newDecls[key]; !ok {
+			changes = append(changes, declChange{Change: declRemoved, Decl: key})
+		}
+	}
+	for _, key := range newKeys {
+		source, ok := oldDecls[key]
+		switch {
+		case !ok:
+			changes = append(changes, declChange{Change: declAdded, Decl: key})
+		case source != newDecls[key]:
+			changes = append(changes, declChange{Change: declModified, Decl: key})
+		}
+	}
+	return changes, nil
+}
+
+// printSemanticDiff prints the declarations changed between the existing
+// version of the Go file name and the generated one.
+func printSemanticDiff(name, existing, generated string) {
+	changes, err := semanticDiff(existing, generated)
+	if err != nil {
+		fmt.Printf("Warning: no semantic diff for %s: %v\n", name, err)
+		return
+	}
+	if len(changes) == 0 {
+		fmt.Printf("Semantic diff of %s: no declaration changed\n", name)
+		return
+	}
+	fmt.Printf("Semantic diff of %s:\n", name)
+	marks := map[string]string{declAdded: "+", declRemoved: "-", declModified: "~"}
+	for _, c := range changes {
+		fmt.Printf("  %s %s\n", marks[c.Change], c.Decl)
+	}
+}
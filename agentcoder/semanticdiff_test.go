@@ -0,0 +1,120 @@
+package agentcoder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const semanticOld = `package shop
+
+import "fmt"
+
+// Cart holds items.
+type Cart struct{ Items []string }
+
+const Limit = 10
+
+var debug, verbose = false, false
+
+func Total(c *Cart) int { return len(c.Items) }
+
+func (c *Cart) Add(item string) { c.Items = append(c.Items, item) }
+
+func printCart(c *Cart) { fmt.Println(c.Items) }
+`
+
+func TestSemanticDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		new  string
+		want []declChange
+	}{
+		{
+			name: "renamed function",
+			new:  strings.Replace(semanticOld, "func printCart(", "func showCart(", 1),
+			want: []declChange{{declRemoved, "func printCart"}, {declAdded, "func showCart"}},
+		},
+		{
+			name: "modified and added",
+			new: strings.Replace(semanticOld, "const Limit = 10", "const Limit = 20", 1) +
+				"\nfunc (c Cart) Len() int { return len(c.Items) }\n\ntype Item string\n",
+			want: []declChange{{declModified, "const Limit"}, {declAdded, "func (Cart) Len"}, {declAdded, "type Item"}},
+		},
+		{
+			name: "method body",
+			new:  strings.Replace(semanticOld, "append(c.Items, item)", "append([]string{item}, c.Items...)", 1),
+			want: []declChange{{declModified, "func (*Cart) Add"}},
+		},
+		{
+			name: "one of several names removed",
+			new:  strings.Replace(semanticOld, "var debug, verbose = false, false", "var debug = false", 1),
+			want: []declChange{{declRemoved, "var verbose"}, {declModified, "var debug"}},
+		},
+		{
+			name: "formatting and comments only",
+			new: `package shop
+
+import "fmt"
+
+type Cart struct {
+	Items []string
+}
+
+// Limit caps the cart.
+const Limit = 10
+
+var debug, verbose = false, false
+
+func Total(c *Cart) int {
+	return len(c.Items) // the count
+}
+
+func (c *Cart) Add(item string) {
+	c.Items = append(c.Items, item)
+}
+
+func printCart(c *Cart) {
+	fmt.Println(c.Items)
+}
+`,
+		},
+	}
+	for _, tt := range tests {
+		got, err := semanticDiff(semanticOld, tt.new)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: semanticDiff() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := semanticDiff(semanticOld, "package shop\n\nfunc {"); err == nil || !strings.Contains(err.Error(), "parsing the generated file") {
+		t.Errorf("semanticDiff() of a broken file = %v, want a parse error", err)
+	}
+}
+
+func TestSemanticDiffOnWrite(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.SemanticDiff = true
+	cfg.Language = "none"
+	writeTree(t, cfg.OutputDir, map[string]string{"shop.go": semanticOld, "notes.txt": "old"})
+	files := []File{
+		{Name: "shop.go", Code: strings.Replace(semanticOld, "func printCart(", "func showCart(", 1)},
+		{Name: "notes.txt", Code: "new"},
+		{Name: "new.go", Code: "package shop\n"},
+	}
+	out := captureStdout(t, func() {
+		if err := writeFiles(osFS{}, cfg, files); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if want := "Semantic diff of shop.go:\n  - func printCart\n  + func showCart\n"; !strings.Contains(out, want) {
+		t.Errorf("output %q does not contain %q", out, want)
+	}
+	if strings.Contains(out, "Semantic diff of notes.txt") || strings.Contains(out, "Semantic diff of new.go") {
+		t.Errorf("output %q has a semantic diff for a file that is not an existing Go file", out)
+	}
+}
@@ -0,0 +1,179 @@
+package agentcoder
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Sink is a destination for the generated files of a run. Several sinks can
+// receive the same files, such as the output directory and a zip archive.
+type Sink interface {
+	Write(files []File) error
+}
+
+// dirSink writes the files into the output directory, staged with
+// --all-or-nothing, and calls report with the outcome of each file.
+type dirSink struct {
+	cfg    Config
+	report func(File, writeResult)
+
+	// written holds the outcome of files already written while streaming,
+	// which are reported without writing them again.
+	written map[string]writeResult
+}
+
+func (s dirSink) Write(files []File) error {
+	cfg := s.cfg
+	if len(s.written) > 0 {
+		// The language of --language auto is only known now that all
+		// files have arrived, so format the streamed files in it
+		detected := cfg.Language == "auto"
+		cfg.Language = resolveLanguage(cfg.Language, files)
+		var rest []File
+		for _, file := range files {
+			result, ok := s.written[file.Name]
+			if !ok {
+				rest = append(rest, file)
+				continue
+			}
+			if detected {
+				result = formatStreamed(cfg, file, result)
+			}
+			if s.report != nil {
+				s.report(file, result)
+			}
+		}
+		files = rest
+	}
+	if cfg.AllOrNothing {
+		return writeFilesStaged(cfg, files, s.report)
+	}
+	return writeFilesFunc(osFS{}, cfg, files, s.report)
+}
+
+// zipSink writes the files into a zip archive at path.
+type zipSink struct {
+	path     string
+	maxDepth int
+}
+
+func (s zipSink) Write(files []File) error {
+	f, err := os.Create(s.path)
+	if err != nil {
+		return fmt.Errorf("creating zip archive: %w", err)
+	}
+	zw := zip.NewWriter(f)
+	now := time.Now()
+	for _, file := range sanitizeFiles(files, s.maxDepth) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: filepath.ToSlash(file.Name), Method: zip.Deflate, Modified: now})
+		if err == nil {
+			_, err = io.WriteString(w, file.Code)
+		}
+		if err != nil {
+			f.Close()
+			return fmt.Errorf("writing %s to zip archive: %w", file.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return fmt.Errorf("writing zip archive: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing zip archive: %w", err)
+	}
+	fmt.Printf("Files written to zip archive %s\n", s.path)
+	return nil
+}
+
+// concatSink writes all files into the single file at path, each preceded
+// by a header line with its path, in the order they were generated.
+type concatSink struct {
+	path     string
+	maxDepth int
+}
+
+func (s concatSink) Write(files []File) error {
+	var b strings.Builder
+	for i, file := range sanitizeFiles(files, s.maxDepth) {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "// ==== %s ====\n%s", filepath.ToSlash(file.Name), file.Code)
+		if !strings.HasSuffix(file.Code, "\n") {
+			b.WriteString("\n")
+		}
+	}
+	if err := os.WriteFile(s.path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("writing concatenated output: %w", err)
+	}
+	fmt.Printf("All files have been written to '%s'\n", s.path)
+	return nil
+}
+
+// stdoutSink emits the files to w as a JSON array in the format of the model
+// response.
+type stdoutSink struct {
+	w        io.Writer
+	maxDepth int
+}
+
+func (s stdoutSink) Write(files []File) error {
+	files = sanitizeFiles(files, s.maxDepth)
+	if files == nil {
+		files = []File{}
+	}
+	enc := json.NewEncoder(s.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(files)
+}
+
+// sanitizeFiles returns the files whose paths pass checkPath, reporting the
+// rejected ones, so that every sink applies the same rules as the output
+// directory.
+func sanitizeFiles(files []File, maxDepth int) []File {
+	var kept []File
+	for i, file := range files {
+		if err := checkPath(file.Name, maxDepth); err != nil {
+			fmt.Printf("Error: rejected file %d: %v\n", i+1, err)
+			continue
+		}
+		kept = append(kept, file)
+	}
+	return kept
+}
+
+// newSinks returns the sinks configured in cfg: the output directory, whose
+// outcomes are passed to report, or the single file of --concat instead,
+// followed by the zip archive of --output-zip and the JSON of --stdout, which
+// is written to stdout. Files in written were already written to the output
+// directory while streaming.
+func newSinks(cfg Config, stdout io.Writer, report func(File, writeResult), written map[string]writeResult) []Sink {
+	sinks := []Sink{dirSink{cfg: cfg, report: report, written: written}}
+	if cfg.Concat != "" {
+		sinks[0] = concatSink{path: cfg.Concat, maxDepth: cfg.MaxDirDepth}
+	}
+	if cfg.OutputZip != "" {
+		sinks = append(sinks, zipSink{path: cfg.OutputZip, maxDepth: cfg.MaxDirDepth})
+	}
+	if cfg.Stdout {
+		sinks = append(sinks, stdoutSink{w: stdout, maxDepth: cfg.MaxDirDepth})
+	}
+	return sinks
+}
+
+// writeSinks writes the files to each sink in turn, stopping at the first
+// error.
+func writeSinks(sinks []Sink, files []File) error {
+	for _, sink := range sinks {
+		if err := sink.Write(files); err != nil {
+			return err
+		}
+	}
+	return nil
+}
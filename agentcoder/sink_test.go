@@ -0,0 +1,114 @@
+package agentcoder
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readZip returns the content of every file in the zip archive at path.
+func readZip(t *testing.T, path string) map[string]string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	contents := make(map[string]string)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		contents[f.Name] = string(data)
+	}
+	return contents
+}
+
+func TestWriteSinksDirZipAndStdout(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.OutputZip = filepath.Join(t.TempDir(), "out.zip")
+	cfg.Stdout = true
+	files := []File{
+		{Name: "main.go", Code: "package main\n"},
+		{Name: "docs/README.md", Code: "# Tool\n"},
+		{Name: "../escape.txt", Code: "outside"},
+	}
+
+	var stdout bytes.Buffer
+	var statuses []string
+	report := func(_ File, result writeResult) { statuses = append(statuses, result.Status) }
+	if err := writeSinks(newSinks(cfg, &stdout, report, nil), files); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]string{"main.go": "package main\n", "docs/README.md": "# Tool\n"}
+	if got := readTree(t, cfg.OutputDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("output directory = %v, want %v", got, want)
+	}
+	if got := readZip(t, cfg.OutputZip); !reflect.DeepEqual(got, want) {
+		t.Errorf("zip archive = %v, want %v", got, want)
+	}
+	var emitted []File
+	if err := json.Unmarshal(stdout.Bytes(), &emitted); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := fileNames(emitted), []string{"main.go", "docs/README.md"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("stdout files = %v, want %v", got, want)
+	}
+	if want := []string{writeWritten, writeWritten, writeRejected}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("reported statuses = %v, want %v", statuses, want)
+	}
+}
+
+func TestConcatSinkReplacesDir(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = filepath.Join(t.TempDir(), "out")
+	cfg.Concat = filepath.Join(t.TempDir(), "all.txt")
+	sinks := newSinks(cfg, io.Discard, nil, nil)
+	if len(sinks) != 1 {
+		t.Fatalf("%d sinks, want only the concatenated file", len(sinks))
+	}
+	files := []File{{Name: "a.go", Code: "package a\n"}, {Name: "b.go", Code: "package b"}}
+	if err := writeSinks(sinks, files); err != nil {
+		t.Fatal(err)
+	}
+	got := readTree(t, filepath.Dir(cfg.Concat))["all.txt"]
+	if want := "// ==== a.go ====\npackage a\n\n// ==== b.go ====\npackage b\n"; got != want {
+		t.Errorf("concatenated output = %q, want %q", got, want)
+	}
+}
+
+func TestConcatSinkKeepsOrder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "all.txt")
+	files := []File{
+		{Name: "z.go", Code: "package z\n"},
+		{Name: "cmd/tool/main.go", Code: "package main\n"},
+		{Name: "../escape.go", Code: "package escape\n"},
+		{Name: "a.md", Code: "# A"},
+	}
+	var err error
+	out := captureStdout(t, func() { err = concatSink{path: path}.Write(files) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := readTree(t, filepath.Dir(path))["all.txt"]
+	want := "// ==== z.go ====\npackage z\n\n// ==== cmd/tool/main.go ====\npackage main\n\n// ==== a.md ====\n# A\n"
+	if got != want {
+		t.Errorf("concatenated output = %q, want %q", got, want)
+	}
+	if !strings.Contains(out, "All files have been written to '"+path+"'") {
+		t.Errorf("output %q does not name the concatenated file", out)
+	}
+}
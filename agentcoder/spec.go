@@ -0,0 +1,77 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// parseSpec splits a spec file into its front-matter header and prompt body.
+// The header is a block of "key: value" lines between two lines that are
+// exactly "---" at the start of the file. A file without a header is all prompt.
+func parseSpec(text string) (map[string]string, string, error) {
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	if !strings.HasPrefix(text, "---\n") {
+		return nil, strings.TrimSpace(text), nil
+	}
+	lines := strings.Split(strings.TrimPrefix(text, "---\n"), "\n")
+	end := slices.Index(lines, "---")
+	if end < 0 {
+		return nil, "", fmt.Errorf("unterminated front matter")
+	}
+	header := make(map[string]string)
+	for i, line := range lines[:end] {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, "", fmt.Errorf("line %d: expected key: value", i+2)
+		}
+		header[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	body := strings.Join(lines[end+1:], "\n")
+	return header, strings.TrimSpace(body), nil
+}
+
+// headerValue converts a front-matter value to JSON. Values that are not
+// already valid JSON (numbers, booleans, quoted strings) are taken as strings.
+func headerValue(value string) json.RawMessage {
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		value = value[1 : len(value)-1]
+	} else if json.Valid([]byte(value)) {
+		return json.RawMessage(value)
+	}
+	data, _ := json.Marshal(value)
+	return data
+}
+
+// loadSpecFile applies the front-matter settings of the spec file at path to
+// cfg and sets its body as the prompt. The header uses the same setting names
+// as the config file.
+func loadSpecFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	header, body, err := parseSpec(string(data))
+	if err != nil {
+		return err
+	}
+	settings := make(map[string]json.RawMessage, len(header))
+	for key, value := range header {
+		settings[key] = headerValue(value)
+	}
+	encoded, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	if err := decodeConfig(encoded, cfg); err != nil {
+		return err
+	}
+	cfg.Prompt = body
+	return nil
+}
@@ -0,0 +1,61 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestParseSpec(t *testing.T) {
+	header, body, err := parseSpec("---\nmodel: gemini-pro\n# comment\ntemperature: 0.2\n---\n\nBuild a CLI.\nWith flags.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"model": "gemini-pro", "temperature": "0.2"}; !reflect.DeepEqual(header, want) {
+		t.Errorf("header = %v, want %v", header, want)
+	}
+	if body != "Build a CLI.\nWith flags." {
+		t.Errorf("body = %q", body)
+	}
+
+	if header, body, err := parseSpec("Just a prompt.\n"); err != nil || header != nil || body != "Just a prompt." {
+		t.Errorf("parseSpec() without header = %v, %q, %v", header, body, err)
+	}
+	if _, _, err := parseSpec("---\nmodel: x\n"); err == nil {
+		t.Error("unterminated front matter accepted")
+	}
+
+	// Only a line that is exactly --- ends the header
+	header, body, err = parseSpec("---\nmodel: x\n---title: y\n---\nBuild a CLI.\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"model": "x", "---title": "y"}; !reflect.DeepEqual(header, want) || body != "Build a CLI." {
+		t.Errorf("parseSpec() = %v, %q; want %v, %q", header, body, want, "Build a CLI.")
+	}
+	if _, _, err := parseSpec("---\nmodel: x\n----\nBuild a CLI.\n"); err == nil {
+		t.Error("front matter ended by ---- accepted")
+	}
+}
+
+func TestParseConfigSpecFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spec.md")
+	spec := "---\nmodel: spec-model\ntemperature: 0.3\noutput: 'generated'\nformat_code: true\n---\nBuild a REST API.\n"
+	if err := os.WriteFile(path, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, _, err := parseConfig([]string{"-spec", path, "-model", "flag-model"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Prompt != "Build a REST API." {
+		t.Errorf("Prompt = %q, want the body of the spec", cfg.Prompt)
+	}
+	if cfg.Model != "flag-model" {
+		t.Errorf("Model = %q, want the flag to override the header", cfg.Model)
+	}
+	if cfg.Temperature == nil || *cfg.Temperature != 0.3 || cfg.OutputDir != "generated" || !cfg.FormatCode {
+		t.Errorf("header not applied: temperature %v, output %q, format %v", cfg.Temperature, cfg.OutputDir, cfg.FormatCode)
+	}
+}
@@ -0,0 +1,83 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// writeFilesStaged writes the files into a staging directory next to the
+// output directory first and moves them into the output directory only if
+// every file was written, parsed, formatted and encoded without error.
+// Otherwise the output directory is left untouched. Files are compared with
+// those of the output directory, so unchanged files are not moved. The
+// staging directory is removed afterwards, even on error or panic, unless
+// --keep-temp is set.
+func writeFilesStaged(cfg Config, files []File, report func(File, writeResult)) error {
+	if err := checkOutputDir(osFS{}, cfg.OutputDir); err != nil {
+		return err
+	}
+	parent := filepath.Dir(filepath.Clean(cfg.OutputDir))
+	if err := os.MkdirAll(parent, os.FileMode(cfg.DirMode)); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+	// Stage on the same filesystem so the files can be renamed into place
+	stage, err := os.MkdirTemp(parent, ".agent_coder-stage-")
+	if err != nil {
+		return fmt.Errorf("creating staging directory: %w", err)
+	}
+	// Remove the staging directory however the write ends, unless it is kept
+	// for debugging
+	defer func() {
+		if cfg.KeepTemp {
+			fmt.Printf("Kept staging directory %s\n", stage)
+			return
+		}
+		os.RemoveAll(stage)
+	}()
+
+	staged := cfg
+	staged.OutputDir = stage
+	staged.existingDir = cfg.OutputDir
+	var written []string
+	var failed, invalid []string
+	err = writeFilesFunc(osFS{}, staged, files, func(file File, result writeResult) {
+		switch result.Status {
+		case writeWritten, writeLinked:
+			written = append(written, file.Name)
+		case writeRejected, writeFailed:
+			failed = append(failed, file.Name)
+		}
+		for _, issue := range result.Issues {
+			if issue.Category == "parse" || issue.Category == "format" {
+				invalid = append(invalid, file.Name)
+				break
+			}
+		}
+		if report != nil {
+			report(file, result)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("%w; nothing was written to %s", err, cfg.OutputDir)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d file(s) could not be written; nothing was written to %s", len(failed), cfg.OutputDir)
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("%d file(s) failed to parse or format (%s); nothing was written to %s", len(invalid), strings.Join(invalid, ", "), cfg.OutputDir)
+	}
+
+	for _, name := range written {
+		dst := filepath.Join(cfg.OutputDir, name)
+		if err := os.MkdirAll(filepath.Dir(dst), os.FileMode(cfg.DirMode)); err != nil {
+			return err
+		}
+		if err := os.Rename(filepath.Join(stage, name), dst); err != nil {
+			return fmt.Errorf("moving %s into place: %w", name, err)
+		}
+	}
+	fmt.Printf("Moved %d staged file(s) into '%s'\n", len(written), cfg.OutputDir)
+	return nil
+}
@@ -0,0 +1,158 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStagedWriteMovesAllFiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = filepath.Join(t.TempDir(), "out")
+	files := []File{{Name: "main.go", Code: "package main\n"}, {Name: "docs/README.md", Code: "# Tool\n"}}
+	if err := writeFilesStaged(cfg, files, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"main.go": "package main\n", "docs/README.md": "# Tool\n"}
+	if got := readTree(t, cfg.OutputDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("output = %v, want %v", got, want)
+	}
+	// The staging directory is removed
+	if entries, _ := os.ReadDir(filepath.Dir(cfg.OutputDir)); len(entries) != 1 {
+		t.Errorf("%d entries next to the output directory, want only the output directory", len(entries))
+	}
+}
+
+func TestStagedWriteInvalidFile(t *testing.T) {
+	failing := filepath.Join(t.TempDir(), "fail.sh")
+	if err := os.WriteFile(failing, []byte("#!/bin/sh\nexit 1\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		setup func(*Config)
+		files []File
+	}{
+		{
+			name:  "parse",
+			files: []File{{Name: "ok.go", Code: "package main\n"}, {Name: "broken.go", Code: "package main\n\nfunc {\n"}},
+		},
+		{
+			name:  "format",
+			setup: func(cfg *Config) { cfg.FormatCode = true; cfg.Formatters = map[string]string{".txt": failing} },
+			files: []File{{Name: "ok.go", Code: "package main\n"}, {Name: "notes.txt", Code: "notes\n"}},
+		},
+		{
+			name:  "rejected",
+			files: []File{{Name: "ok.go", Code: "package main\n"}, {Name: "../escape.go", Code: "package main\n"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputDir = t.TempDir()
+			if tt.setup != nil {
+				tt.setup(&cfg)
+			}
+			existing := filepath.Join(cfg.OutputDir, "existing.go")
+			if err := os.WriteFile(existing, []byte("package main\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := writeFilesStaged(cfg, tt.files, nil); err == nil {
+				t.Fatal("writeFilesStaged() succeeded")
+			}
+			want := map[string]string{"existing.go": "package main\n"}
+			if got := readTree(t, cfg.OutputDir); !reflect.DeepEqual(got, want) {
+				t.Errorf("output = %v, want it untouched", got)
+			}
+		})
+	}
+}
+
+func TestStagedWriteComparesWithOutputDir(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.SkipIdentical = true
+	cfg.ShowDiffStat = true
+	for name, content := range map[string]string{"same.txt": "same\n", "changed.txt": "a\nb\n"} {
+		if err := os.WriteFile(filepath.Join(cfg.OutputDir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results := make(map[string]writeResult)
+	files := []File{{Name: "same.txt", Code: "same\n"}, {Name: "changed.txt", Code: "a\nc\n"}}
+	err := writeFilesStaged(cfg, files, func(file File, result writeResult) { results[file.Name] = result })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := results["same.txt"].Status; got != writeUnchanged {
+		t.Errorf("same.txt status = %s, want %s", got, writeUnchanged)
+	}
+	if r := results["changed.txt"]; r.Status != writeWritten || r.Added != 1 || r.Removed != 1 {
+		t.Errorf("changed.txt = %+v, want written with 1 line added and 1 removed", r)
+	}
+	want := map[string]string{"same.txt": "same\n", "changed.txt": "a\nc\n"}
+	if got := readTree(t, cfg.OutputDir); !reflect.DeepEqual(got, want) {
+		t.Errorf("output = %v, want %v", got, want)
+	}
+}
+
+// stagingDirs returns the staging directories left next to the output
+// directory.
+func stagingDirs(t *testing.T, cfg Config) []string {
+	t.Helper()
+	dirs, err := filepath.Glob(filepath.Join(filepath.Dir(cfg.OutputDir), ".agent_coder-stage-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dirs
+}
+
+func TestStagingDirRemoved(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  []File
+		report func(File, writeResult)
+	}{
+		{name: "success", files: []File{{Name: "a.go", Code: "package a\n"}}},
+		{name: "error", files: []File{{Name: "a.go", Code: "package a\n\nfunc {\n"}}},
+		{name: "panic", files: []File{{Name: "a.go", Code: "package a\n"}}, report: func(File, writeResult) { panic("report failed") }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputDir = filepath.Join(t.TempDir(), "out")
+			func() {
+				defer func() { recover() }()
+				writeFilesStaged(cfg, tt.files, tt.report)
+			}()
+			if dirs := stagingDirs(t, cfg); len(dirs) != 0 {
+				t.Errorf("staging directories left: %v", dirs)
+			}
+		})
+	}
+}
+
+func TestKeepTemp(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = filepath.Join(t.TempDir(), "out")
+	cfg.KeepTemp = true
+	out := captureStdout(t, func() {
+		if err := writeFilesStaged(cfg, []File{{Name: "a.go", Code: "package a\n\nfunc {\n"}}, nil); err == nil {
+			t.Error("invalid file accepted")
+		}
+	})
+	dirs := stagingDirs(t, cfg)
+	if len(dirs) != 1 {
+		t.Fatalf("staging directories = %v, want one kept", dirs)
+	}
+	if !strings.Contains(out, "Kept staging directory "+dirs[0]) {
+		t.Errorf("output %q does not give the location of the staging directory", out)
+	}
+	if _, err := os.Stat(filepath.Join(dirs[0], "a.go")); err != nil {
+		t.Error(err)
+	}
+}
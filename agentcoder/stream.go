@@ -0,0 +1,134 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+)
+
+// jsonStreamBuffer accumulates streamed chunks of a JSON array. It tracks the
+// nesting depth outside of string literals so that parsing is only attempted
+// once the top-level array is balanced, no matter where a chunk boundary falls.
+type jsonStreamBuffer struct {
+	buf      strings.Builder
+	depth    int
+	started  bool
+	inString bool
+	escaped  bool
+	objects  int // Number of completed top-level objects
+	start    int // Offset of the top-level object being received
+
+	// onObject, if not nil, is called with the text of each top-level object
+	// of the array as soon as it is complete.
+	onObject func(object string)
+}
+
+// Write appends a chunk to the buffer and updates the bracket state.
+func (b *jsonStreamBuffer) Write(chunk string) {
+	offset := b.buf.Len()
+	b.buf.WriteString(chunk)
+	for i, c := range chunk {
+		if b.inString {
+			switch {
+			case b.escaped:
+				b.escaped = false
+			case c == '\\':
+				b.escaped = true
+			case c == '"':
+				b.inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			b.inString = true
+		case '[', '{':
+			if c == '{' && b.depth == 1 {
+				b.start = offset + i
+			}
+			b.depth++
+			b.started = true
+		case ']', '}':
+			b.depth--
+			if c == '}' && b.depth == 1 {
+				b.objects++
+				if b.onObject != nil {
+					b.onObject(b.buf.String()[b.start : offset+i+1])
+				}
+			}
+		}
+	}
+}
+
+// Complete reports whether the top-level array has been closed.
+func (b *jsonStreamBuffer) Complete() bool {
+	return b.started && b.depth == 0
+}
+
+// Files returns the number of file objects received so far.
+func (b *jsonStreamBuffer) Files() int {
+	return b.objects
+}
+
+// String returns everything written to the buffer.
+func (b *jsonStreamBuffer) String() string {
+	return b.buf.String()
+}
+
+// streamContent sends the prompt parts using the streaming API, reporting progress as
+// file objects arrive, and returns the concatenated reply. The metadata is
+// taken from the last chunk that carries it. If onObject is not nil, it is
+// called with each file object of a JSON reply as soon as it is complete.
+func streamContent(ctx context.Context, model *genai.GenerativeModel, parts []genai.Part, onObject func(string)) (*reply, error) {
+	iter := model.GenerateContentStream(ctx, parts...)
+	buf := jsonStreamBuffer{onObject: onObject}
+	var r reply
+	var calls []genai.FunctionCall
+	reported := 0
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if resp.UsageMetadata != nil {
+			r.Usage = resp.UsageMetadata
+		}
+		if len(resp.Candidates) == 0 {
+			continue
+		}
+		candidate := resp.Candidates[0]
+		if candidate.FinishReason != genai.FinishReasonUnspecified {
+			r.FinishReason = candidate.FinishReason
+		}
+		if len(candidate.SafetyRatings) > 0 {
+			r.SafetyRatings = candidate.SafetyRatings
+		}
+		if candidate.Content == nil {
+			continue
+		}
+		for _, part := range candidate.Content.Parts {
+			if text, ok := part.(genai.Text); ok {
+				buf.Write(string(text))
+			}
+		}
+		calls = append(calls, functionCalls(candidate.Content.Parts)...)
+		if n := buf.Files(); n > reported {
+			fmt.Printf("Received %d file(s) so far\n", n)
+			reported = n
+		}
+	}
+	r.Text = buf.String()
+	if len(calls) > 0 {
+		r.Text = encodeFunctionCalls(calls)
+	}
+	if model.ResponseMIMEType == "application/json" && !buf.Complete() && r.FinishReason != genai.FinishReasonMaxTokens {
+		return &r, fmt.Errorf("stream ended before the JSON array was complete")
+	}
+	return &r, nil
+}
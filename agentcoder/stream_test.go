@@ -0,0 +1,53 @@
+package agentcoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONStreamBufferChunkBoundaries(t *testing.T) {
+	response := `[{"file_name": "a.go", "source_code": "x := \"[{\\\"}]\""}, {"file_name": "b/c.txt", "source_code": "} ] { ["}]`
+	want := []File{
+		{Name: "a.go", Code: `x := "[{\"}]"`},
+		{Name: "b/c.txt", Code: "} ] { ["},
+	}
+	// Split the response at every offset so that a boundary falls inside
+	// each string, escape sequence and bracket of the array
+	for split := 1; split < len(response); split++ {
+		var objects []string
+		buf := jsonStreamBuffer{onObject: func(object string) { objects = append(objects, object) }}
+		buf.Write(response[:split])
+		if buf.Complete() {
+			t.Fatalf("split %d: complete after the first chunk %q", split, response[:split])
+		}
+		buf.Write(response[split:])
+		if !buf.Complete() {
+			t.Fatalf("split %d: not complete after the last chunk", split)
+		}
+		if buf.Files() != 2 || len(objects) != 2 {
+			t.Fatalf("split %d: %d files and %d objects, want 2", split, buf.Files(), len(objects))
+		}
+		files, err := parseResponse(DefaultConfig(), buf.String())
+		if err != nil {
+			t.Fatalf("split %d: %v", split, err)
+		}
+		if !reflect.DeepEqual(files, want) {
+			t.Fatalf("split %d: parsed %v, want %v", split, files, want)
+		}
+	}
+}
+
+func TestJSONStreamBufferObjects(t *testing.T) {
+	var objects []string
+	buf := jsonStreamBuffer{onObject: func(object string) { objects = append(objects, object) }}
+	for _, chunk := range []string{`[{"file_name": "a", "sou`, `rce_code": "{"}`, `, {"file_name": "b", "source_code": ""}`} {
+		buf.Write(chunk)
+	}
+	want := []string{`{"file_name": "a", "source_code": "{"}`, `{"file_name": "b", "source_code": ""}`}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("objects = %q, want %q", objects, want)
+	}
+	if buf.Complete() {
+		t.Error("complete before the array is closed")
+	}
+}
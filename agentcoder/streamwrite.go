@@ -0,0 +1,105 @@
+package agentcoder
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/text/encoding"
+)
+
+// streamWriter writes files into the output directory while the response is
+// still streaming, as soon as each file has been received completely. The
+// files go through the same rewrites and filters as the complete response.
+// With --language auto the language is not known until the response is
+// complete, so the files are formatted in it by formatStreamed afterwards.
+type streamWriter struct {
+	cfg   Config
+	enc   encoding.Encoding
+	owner *fileOwner
+	count int
+
+	// written holds the outcome of the files written so far by name, so that
+	// they are not written again once the response is complete.
+	written map[string]writeResult
+}
+
+// newStreamWriter prepares the output directory for writing files as they
+// arrive.
+func newStreamWriter(cfg Config) (*streamWriter, error) {
+	enc, err := lookupEncoding(cfg.OutputEncoding)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := newFileOwner(osFS{}, cfg.Owner)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkOutputDir(osFS{}, cfg.OutputDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(cfg.OutputDir, os.FileMode(cfg.DirMode)); err != nil {
+		return nil, fmt.Errorf("creating output directory: %w", err)
+	}
+	return &streamWriter{cfg: cfg, enc: enc, owner: owner, written: make(map[string]writeResult)}, nil
+}
+
+// Write writes a file received from the stream. Files that are rejected or
+// fail are left for the complete response, which reports them.
+func (w *streamWriter) Write(file File) {
+	files, err := filterFiles(w.cfg, transformFiles(w.cfg, []File{file}))
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		result, err := writeFile(osFS{}, w.cfg, w.enc, w.count, file)
+		if err != nil || result.Status != writeWritten {
+			continue
+		}
+		w.owner.chown(filepath.Join(w.cfg.OutputDir, file.Name))
+		if w.cfg.Readonly {
+			makeReadonly(osFS{}, filepath.Join(w.cfg.OutputDir, file.Name))
+		}
+		w.written[file.Name] = result
+		w.count++
+	}
+}
+
+// formatStreamed formats a file written while streaming if it is in the
+// language detected from the complete response, and returns its result with
+// the issue if formatting fails.
+func formatStreamed(cfg Config, file File, result writeResult) writeResult {
+	if cfg.FormatCode || !autoFormat(cfg, file.Name) {
+		return result
+	}
+	path := filepath.Join(cfg.OutputDir, file.Name)
+	formatted, err := formatContent(osFS{}, cfg.Formatters, path, file.Code, cfg.commandTimeout())
+	if err == nil && formatted != file.Code {
+		err = rewriteFile(cfg, path, formatted)
+	}
+	if err != nil {
+		fmt.Printf("Warning: could not format %s: %v\n", file.Name, err)
+		result.Issues = append(result.Issues, validationIssue{Category: "format", Message: err.Error()})
+	}
+	return result
+}
+
+// rewriteFile replaces the file at path, written before, with content in the
+// output encoding, keeping it read-only with --readonly.
+func rewriteFile(cfg Config, path, content string) error {
+	data := []byte(content)
+	enc, err := lookupEncoding(cfg.OutputEncoding)
+	if err != nil {
+		return err
+	}
+	if enc != nil {
+		if data, err = enc.NewEncoder().Bytes(data); err != nil {
+			return err
+		}
+	}
+	if cfg.Readonly {
+		makeWritable(osFS{}, path)
+		defer makeReadonly(osFS{}, path)
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,93 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// streamFiles streams the response in chunks into the output directory of
+// cfg like --stream-write does and returns the files on disk each time a file
+// was written while streaming, and the files once the complete response was
+// written.
+func streamFiles(t *testing.T, cfg Config, chunks ...string) ([]map[string]string, map[string]string) {
+	t.Helper()
+	var snapshots []map[string]string
+	var err error
+	var final map[string]string
+	captureStdout(t, func() {
+		var streamed *streamWriter
+		if streamed, err = newStreamWriter(cfg); err != nil {
+			return
+		}
+		buf := jsonStreamBuffer{onObject: func(object string) {
+			var file File
+			if err := json.Unmarshal([]byte(object), &file); err != nil {
+				t.Errorf("object %q: %v", object, err)
+				return
+			}
+			streamed.Write(file)
+			snapshots = append(snapshots, readTree(t, cfg.OutputDir))
+		}}
+		for _, chunk := range chunks {
+			buf.Write(chunk)
+		}
+		var files []File
+		if files, err = parseResponse(cfg, buf.String()); err != nil {
+			return
+		}
+		if files, err = filterFiles(cfg, transformFiles(cfg, files)); err != nil {
+			return
+		}
+		err = writeSinks(newSinks(cfg, nil, nil, streamed.written), files)
+		final = readTree(t, cfg.OutputDir)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshots, final
+}
+
+func TestStreamWrite(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Language = "none"
+	snapshots, final := streamFiles(t, cfg,
+		`[{"file_name": "a.txt", "source_`,
+		`code": "first"}, {"file_name": "b.txt", "source_code": "sec`,
+		`ond"}, {"file_name": "c.txt", "source_code": "third"}`,
+		`]`,
+	)
+	want := []map[string]string{
+		{"a.txt": "first"},
+		{"a.txt": "first", "b.txt": "second"},
+		{"a.txt": "first", "b.txt": "second", "c.txt": "third"},
+	}
+	if !reflect.DeepEqual(snapshots, want) {
+		t.Errorf("files while streaming = %v, want %v", snapshots, want)
+	}
+	if !reflect.DeepEqual(final, want[2]) {
+		t.Errorf("files after streaming = %v, want %v", final, want[2])
+	}
+}
+
+func TestStreamWriteFormatsDetectedLanguage(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.Language = "auto"
+	cfg.FormatCode = false
+	snapshots, final := streamFiles(t, cfg,
+		`[{"file_name": "main.go", "source_code": "package main\nfunc main(){}\n"}, `,
+		`{"file_name": "util.go", "source_code": "package main\nfunc util(){}\n"}]`,
+	)
+	if len(snapshots) != 2 {
+		t.Fatalf("%d file(s) written while streaming, want 2", len(snapshots))
+	}
+	want := map[string]string{
+		"main.go": "package main\n\nfunc main() {}\n",
+		"util.go": "package main\n\nfunc util() {}\n",
+	}
+	if !reflect.DeepEqual(final, want) {
+		t.Errorf("files after streaming = %q, want them formatted as %q", final, want)
+	}
+}
@@ -0,0 +1,45 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+)
+
+// summarizer is implemented by generators that can condense a context file
+// into a short description.
+type summarizer interface {
+	Summarize(ctx context.Context, name, content string, maxChars int) (string, error)
+}
+
+// summaryPrompt asks the model to describe a context file in at most maxChars
+// characters.
+func summaryPrompt(name, content string, maxChars int) string {
+	return fmt.Sprintf("Summarize the following file in at most %d characters of plain text. Describe its purpose and list the types, functions and other declarations other code may depend on, with their signatures. Respond with only the summary.\n\n--- %s ---\n%s", maxChars, name, content)
+}
+
+// summarizeContext replaces the context files longer than maxChars with a
+// summary of at most maxChars characters made by s. It returns the files and
+// the names of those that were summarized.
+func summarizeContext(ctx context.Context, s summarizer, files []File, maxChars int) ([]File, map[string]bool, error) {
+	summarized := make(map[string]bool)
+	result := make([]File, len(files))
+	for i, file := range files {
+		result[i] = file
+		if len(file.Code) <= maxChars {
+			continue
+		}
+		summary, err := s.Summarize(ctx, file.Name, file.Code, maxChars)
+		if err != nil {
+			return nil, nil, fmt.Errorf("summarizing %s: %w", file.Name, err)
+		}
+		if runes := []rune(summary); len(runes) > maxChars {
+			summary = string(runes[:maxChars])
+		}
+		result[i].Code = summary
+		summarized[file.Name] = true
+	}
+	if len(summarized) > 0 {
+		fmt.Printf("Summarized %d of %d context file(s)\n", len(summarized), len(files))
+	}
+	return result, summarized, nil
+}
@@ -0,0 +1,63 @@
+package agentcoder
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// summarizingGenerator is a fakeGenerator that summarizes files by name and
+// records the files it was asked to summarize.
+type summarizingGenerator struct {
+	fakeGenerator
+	summary    string
+	summarized []string
+}
+
+func (g *summarizingGenerator) Summarize(ctx context.Context, name, content string, maxChars int) (string, error) {
+	g.summarized = append(g.summarized, name)
+	return g.summary + name, nil
+}
+
+func TestSummariesReplaceContext(t *testing.T) {
+	big := "package big\n\n" + strings.Repeat("// the full content of a large file\n", 100)
+	cfg := contextConfig(t, map[string]string{"big.go": big, "small.go": "package small\n"})
+	cfg.SummarizeContext = true
+	cfg.SummaryMaxChars = 200
+	gen := &summarizingGenerator{summary: "SUMMARY OF "}
+
+	req, err := readPrompt(context.Background(), cfg, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gen.summarized) != 1 || gen.summarized[0] != "big.go" {
+		t.Errorf("summarized %v, want only big.go", gen.summarized)
+	}
+	if !strings.Contains(req.Text, "SUMMARY OF big.go") {
+		t.Error("prompt does not contain the summary of big.go")
+	}
+	if strings.Contains(req.Text, "the full content of a large file") {
+		t.Error("prompt contains the full content of big.go")
+	}
+	if !strings.Contains(req.Text, "package small") {
+		t.Error("prompt does not contain small.go")
+	}
+}
+
+func TestSummaryTruncated(t *testing.T) {
+	gen := &summarizingGenerator{summary: strings.Repeat("é", 50)}
+	files := []File{{Name: "a.go", Code: strings.Repeat("x", 100)}}
+	result, summarized, err := summarizeContext(context.Background(), gen, files, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := []rune(result[0].Code); len(got) != 20 {
+		t.Errorf("summary has %d characters, want 20", len(got))
+	}
+	if !summarized["a.go"] {
+		t.Errorf("summarized = %v, want a.go", summarized)
+	}
+	if files[0].Code != strings.Repeat("x", 100) {
+		t.Error("the input file was changed")
+	}
+}
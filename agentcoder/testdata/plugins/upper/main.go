@@ -0,0 +1,46 @@
+//go:build wasip1
+
+// Command upper is an example plugin that uppercases the names of the
+// generated files and rejects files named secret.txt. Build it with:
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o upper.wasm .
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"unsafe"
+)
+
+type result struct {
+	Name   string `json:"file_name"`
+	Code   string `json:"source_code"`
+	Reject string `json:"reject,omitempty"`
+}
+
+// buffers keeps the memory handed to the host reachable.
+var buffers [][]byte
+
+//go:wasmexport alloc
+func alloc(size uint32) uint32 {
+	buf := make([]byte, size)
+	buffers = append(buffers, buf)
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(buf))))
+}
+
+//go:wasmexport transform
+func transform(ptr, size uint32) uint64 {
+	input := unsafe.Slice((*byte)(unsafe.Pointer(uintptr(ptr))), size)
+	var file result
+	if err := json.Unmarshal(input, &file); err != nil {
+		file.Reject = err.Error()
+	} else if file.Name == "secret.txt" {
+		file.Reject = "secrets are not written"
+	}
+	file.Name = strings.ToUpper(file.Name)
+	out, _ := json.Marshal(file)
+	buffers = append(buffers, out)
+	return uint64(uintptr(unsafe.Pointer(unsafe.SliceData(out))))<<32 | uint64(len(out))
+}
+
+func main() {}
@@ -0,0 +1,113 @@
+package agentcoder
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// isTokenLimitError reports whether err is the API's rejection of a prompt that
+// exceeds the model's input token limit.
+func isTokenLimitError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "input token count") ||
+		strings.Contains(msg, "exceeds the maximum number of tokens") ||
+		strings.Contains(msg, "token limit")
+}
+
+// trimContext drops context files until the remaining ones take up at most
+// half of the original size. Files matching none of the priority patterns go
+// first, followed by those matching the patterns from last to first, and the
+// largest files go first among files of the same priority.
+func trimContext(files []File, priority []*regexp.Regexp) []File {
+	total := 0
+	for _, file := range files {
+		total += len(file.Code)
+	}
+	byDrop := append([]File(nil), files...)
+	sort.SliceStable(byDrop, func(i, j int) bool {
+		ri, rj := contextRank(byDrop[i].Name, priority), contextRank(byDrop[j].Name, priority)
+		if ri != rj {
+			return ri > rj
+		}
+		return len(byDrop[i].Code) > len(byDrop[j].Code)
+	})
+	dropped := make(map[string]bool)
+	size := total
+	for _, file := range byDrop {
+		if size <= total/2 {
+			break
+		}
+		dropped[file.Name] = true
+		size -= len(file.Code)
+	}
+	var kept []File
+	for _, file := range files {
+		if !dropped[file.Name] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// compilePriority compiles the --context-priority globs, which match like the
+// patterns of the ignore file: at any depth unless they contain a /.
+func compilePriority(globs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, len(globs))
+	for i, glob := range globs {
+		expr := globRegexp(strings.TrimPrefix(glob, "/"))
+		if !strings.Contains(glob, "/") {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid context priority %q: %w", glob, err)
+		}
+		res[i] = re
+	}
+	return res, nil
+}
+
+// contextRank returns the index of the first priority pattern matching name,
+// or the number of patterns if none does.
+func contextRank(name string, priority []*regexp.Regexp) int {
+	for i, re := range priority {
+		if re.MatchString(filepath.ToSlash(name)) {
+			return i
+		}
+	}
+	return len(priority)
+}
+
+// retryWithLessContext explains a token limit error, including the measured
+// size of the prompt, and with --auto-trim retries once with the least
+// important and largest context files removed. It returns the original error
+// when no retry is made.
+func retryWithLessContext(ctx context.Context, cfg Config, gen generator, req *promptRequest, cause error) (*reply, error) {
+	size := fmt.Sprintf("%d characters", len(req.Text))
+	if counter, ok := gen.(tokenCounter); ok {
+		if tokens, err := counter.CountTokens(ctx, req.Text); err == nil {
+			size = fmt.Sprintf("%d tokens", tokens)
+		}
+	}
+	fmt.Printf("\nThe prompt (%s) exceeds the input limit of %s.\n", size, cfg.Model)
+	if !cfg.AutoTrim || len(req.Context) == 0 {
+		fmt.Println("Reduce the context, for example with --context-since, or retry with --auto-trim.")
+		return nil, cause
+	}
+
+	priority, err := compilePriority(cfg.ContextPriority)
+	if err != nil {
+		return nil, err
+	}
+	trimmed := *req
+	trimmed.Context = trimContext(req.Context, priority)
+	fmt.Printf("Retrying with %d of %d context file(s)\n", len(trimmed.Context), len(req.Context))
+	return gen.Generate(ctx, buildPrompt(cfg, &trimmed))
+}
@@ -0,0 +1,130 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// countingGenerator is a fakeGenerator that counts a token per four
+// characters.
+type countingGenerator struct {
+	fakeGenerator
+}
+
+func (g *countingGenerator) CountTokens(ctx context.Context, prompt string) (int32, error) {
+	return int32(len(prompt) / 4), nil
+}
+
+// tokenLimitError is the error the API returns for a prompt that is too long.
+var tokenLimitError = errors.New("googleapi: Error 400: The input token count (1048577) exceeds the maximum number of tokens allowed (1048576)")
+
+// contextConfig returns a config whose context directory holds the files.
+func contextConfig(t *testing.T, files map[string]string) Config {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Prompt = "Refactor the code."
+	cfg.OutputDir = t.TempDir()
+	cfg.ContextDir = t.TempDir()
+	cfg.NoRaw = true
+	for name, code := range files {
+		if err := os.WriteFile(filepath.Join(cfg.ContextDir, name), []byte(code), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+func TestTokenLimitSuggestion(t *testing.T) {
+	cfg := contextConfig(t, map[string]string{"big.go": strings.Repeat("x", 400)})
+	gen := &countingGenerator{fakeGenerator{errs: []error{tokenLimitError}}}
+	var err error
+	out := captureStdout(t, func() { _, err = requestText(context.Background(), cfg, gen) })
+	if !errors.Is(err, tokenLimitError) {
+		t.Errorf("requestText() error = %v, want the token limit error", err)
+	}
+	if !strings.Contains(out, "tokens) exceeds the input limit") || !strings.Contains(out, "retry with --auto-trim") {
+		t.Errorf("output %q does not suggest reducing the context with the token count", out)
+	}
+	if len(gen.prompts) != 1 {
+		t.Errorf("%d requests, want no retry", len(gen.prompts))
+	}
+}
+
+func TestTokenLimitAutoTrim(t *testing.T) {
+	cfg := contextConfig(t, map[string]string{
+		"big.go":   strings.Repeat("b", 400),
+		"small.go": "package small",
+	})
+	cfg.AutoTrim = true
+	gen := &countingGenerator{fakeGenerator{errs: []error{tokenLimitError}, replies: []*reply{nil, {Text: "[]"}}}}
+	text, err := requestText(context.Background(), cfg, gen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "[]" || len(gen.prompts) != 2 {
+		t.Fatalf("requestText() = %q after %d requests, want the reply of the retry", text, len(gen.prompts))
+	}
+	if !strings.Contains(gen.prompts[0], "--- big.go ---") || strings.Contains(gen.prompts[1], "--- big.go ---") || !strings.Contains(gen.prompts[1], "--- small.go ---") {
+		t.Errorf("retry prompt %q still holds big.go or lost small.go", gen.prompts[1])
+	}
+}
+
+func TestTrimContextPriority(t *testing.T) {
+	files := func(sizes ...int) []File {
+		names := []string{"main.go", "util.go", "docs/guide.md", "docs/notes.md"}
+		var files []File
+		for i, size := range sizes {
+			files = append(files, File{Name: names[i], Code: strings.Repeat("x", size)})
+		}
+		return files
+	}
+	tests := []struct {
+		name     string
+		files    []File
+		priority []string
+		want     []string
+	}{
+		{"go files first", files(100, 50, 200), []string{"*.go"}, []string{"main.go", "util.go"}},
+		{"priority over size", files(120, 20, 100, 100), []string{"*.go"}, []string{"main.go", "util.go"}},
+		{"largest first without priority", files(120, 20, 100, 100), nil, []string{"util.go", "docs/notes.md"}},
+		{"patterns in order", files(120, 20, 100, 100), []string{"docs/notes.md", "*.go"}, []string{"util.go", "docs/notes.md"}},
+		{"pattern at any depth", files(120, 20, 100, 100), []string{"notes.md"}, []string{"util.go", "docs/notes.md"}},
+	}
+	for _, tt := range tests {
+		priority, err := compilePriority(tt.priority)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := fileNames(trimContext(tt.files, priority)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: trimContext() kept %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTokenLimitAutoTrimPriority(t *testing.T) {
+	cfg := contextConfig(t, map[string]string{
+		"api.go":   "package api\n\n" + strings.Repeat("// API\n", 50),
+		"guide.md": strings.Repeat("Guide\n", 40),
+		"notes.md": strings.Repeat("Notes\n", 40),
+	})
+	cfg.AutoTrim = true
+	cfg.ContextPriority = []string{"*.go"}
+	gen := &countingGenerator{fakeGenerator{errs: []error{tokenLimitError}, replies: []*reply{nil, {Text: "[]"}}}}
+	captureStdout(t, func() {
+		if _, err := requestText(context.Background(), cfg, gen); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(gen.prompts) != 2 {
+		t.Fatalf("%d requests, want a retry", len(gen.prompts))
+	}
+	retry := gen.prompts[1]
+	if !strings.Contains(retry, "--- api.go ---") || strings.Contains(retry, "--- guide.md ---") || strings.Contains(retry, "--- notes.md ---") {
+		t.Errorf("retry prompt %q does not keep only the prioritized api.go", retry)
+	}
+}
@@ -0,0 +1,55 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the spans of a run. It does nothing unless tracing was set
+// up with --trace.
+var tracer = otel.Tracer("agent_coder")
+
+// setupTracing exports the spans of the run over OTLP/HTTP to the configured
+// endpoint, or the one in the standard OTEL_EXPORTER_OTLP_* variables. The
+// returned function flushes the remaining spans and must be called before
+// exiting.
+func setupTracing(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	if !cfg.Trace {
+		return func(context.Context) error { return nil }, nil
+	}
+	var opts []otlptracehttp.Option
+	if cfg.TraceEndpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(cfg.TraceEndpoint))
+	} else if os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") == "" && os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") == "" {
+		return nil, errors.New("--trace requires --trace-endpoint or OTEL_EXPORTER_OTLP_ENDPOINT")
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", "agent_coder"))),
+	)
+	otel.SetTracerProvider(provider)
+	tracer = provider.Tracer("agent_coder")
+	return provider.Shutdown, nil
+}
+
+// endSpan records err, if any, on the span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,88 @@
+package agentcoder
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans makes the tracer record its spans in memory for the rest of the
+// test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	prev := tracer
+	tracer = provider.Tracer("agent_coder")
+	t.Cleanup(func() { tracer = prev })
+	return recorder
+}
+
+// spanAttributes returns the attributes of the span as a map.
+func spanAttributes(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestTraceSpans(t *testing.T) {
+	recorder := recordSpans(t)
+	cfg := DefaultConfig()
+	cfg.Model = "gemini-2.0-flash"
+	cfg.Prompt = "Write a package."
+	cfg.NoRaw = true
+	gen, _ := newTestGenerator(t, cfg, textResponse(`[{"file_name": "a.go", "source_code": "package a\n"}, {"file_name": "b.go", "source_code": "package b\n"}]`, "STOP"))
+	if _, err := generate(context.Background(), cfg, gen); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	generateSpan, ok := spans["generate"]
+	if !ok {
+		t.Fatalf("no generate span in %v", spans)
+	}
+	attrs := spanAttributes(generateSpan)
+	if got := attrs["model"].AsString(); got != "gemini-2.0-flash" {
+		t.Errorf("generate span model = %q, want gemini-2.0-flash", got)
+	}
+	if got := attrs["prompt_tokens"].AsInt64(); got != 10 {
+		t.Errorf("generate span prompt_tokens = %d, want 10", got)
+	}
+	if got := attrs["response_tokens"].AsInt64(); got != 20 {
+		t.Errorf("generate span response_tokens = %d, want 20", got)
+	}
+
+	parseSpan, ok := spans["parse"]
+	if !ok {
+		t.Fatalf("no parse span in %v", spans)
+	}
+	if got := spanAttributes(parseSpan)["files"].AsInt64(); got != 2 {
+		t.Errorf("parse span files = %d, want 2", got)
+	}
+}
+
+func TestSetupTracingRequiresEndpoint(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	cfg := DefaultConfig()
+	cfg.Trace = true
+	if _, err := setupTracing(context.Background(), cfg); err == nil {
+		t.Error("--trace accepted without an endpoint")
+	}
+	cfg.Trace = false
+	shutdown, err := setupTracing(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,80 @@
+package agentcoder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// transformFiles applies the configured rewrites to the generated files before
+// they are written.
+func transformFiles(cfg Config, files []File) []File {
+	if cfg.NormalizePaths {
+		files = normalizePaths(files)
+	}
+	if cfg.Redact {
+		files = redactFiles(files)
+	}
+	if cfg.StripComments {
+		files = stripFileComments(files)
+	}
+	if cfg.TrimTrailingWS {
+		files = trimFiles(files, cfg.TrimSkipExtensions)
+	}
+	if cfg.sortFiles() {
+		files = sortFiles(files)
+	}
+	return files
+}
+
+// sortFiles returns the files ordered by path, so that the output does not
+// depend on the order the model listed them in.
+func sortFiles(files []File) []File {
+	sorted := append([]File(nil), files...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+// filterFiles passes the generated files through the plugin, if any, drops or
+// rejects those that look binary or may not be written because of their
+// extension, content or the ignore file of the output directory, and checks
+// how they are spread over directories and the length of their lines.
+func filterFiles(cfg Config, files []File) ([]File, error) {
+	if cfg.Plugin != "" {
+		var err error
+		if files, err = applyPlugin(context.Background(), cfg.Plugin, files); err != nil {
+			return nil, err
+		}
+	}
+	files, err := filterBinary(files, cfg.AbortOnBinaryOutput)
+	if err != nil {
+		return nil, err
+	}
+	if files, err = filterExtensions(files, cfg.AllowedExtensions, cfg.DeniedExtensions, cfg.Strict); err != nil {
+		return nil, err
+	}
+	if files, err = filterIgnored(cfg.OutputDir, files); err != nil {
+		return nil, err
+	}
+	if files, err = filterForbidden(files, cfg.ForbidPatterns, cfg.ForbidAction); err != nil {
+		return nil, err
+	}
+	if err := checkFilesPerDir(files, cfg.MaxFilesPerDir, cfg.Strict); err != nil {
+		return nil, err
+	}
+	return files, checkLineLength(files, cfg.MaxLineLength, cfg.Strict)
+}
+
+// checkFileCount fails with --fail-if-empty when no files are left to write,
+// which usually means the generation failed even though the response parsed,
+// and with --min-files when fewer files than expected were generated.
+func checkFileCount(cfg Config, files []File) error {
+	if cfg.FailIfEmpty && len(files) == 0 {
+		return errors.New("the response contains no files")
+	}
+	if cfg.MinFiles > 0 && len(files) < cfg.MinFiles {
+		return fmt.Errorf("the response contains %d file(s), expected at least %d", len(files), cfg.MinFiles)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package agentcoder
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFailIfEmpty(t *testing.T) {
+	cfg := DefaultConfig()
+	if _, err := validateResponse(cfg, "[]"); err != nil {
+		t.Errorf("empty response failed without --fail-if-empty: %v", err)
+	}
+	cfg.FailIfEmpty = true
+	_, err := validateResponse(cfg, "[]")
+	if err == nil || err.Error() != "the response contains no files" {
+		t.Errorf("validateResponse() error = %v, want the response contains no files", err)
+	}
+	if _, err := validateResponse(cfg, `[{"file_name": "a.go", "source_code": "package a\n"}]`); err != nil {
+		t.Errorf("response with a file failed: %v", err)
+	}
+}
+
+func TestMinFiles(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MinFiles = 3
+	two := `[{"file_name": "a.go", "source_code": "package a\n"}, {"file_name": "b.go", "source_code": "package b\n"}]`
+	_, err := validateResponse(cfg, two)
+	if want := "the response contains 2 file(s), expected at least 3"; err == nil || err.Error() != want {
+		t.Errorf("validateResponse() error = %v, want %q", err, want)
+	}
+	cfg.MinFiles = 2
+	if _, err := validateResponse(cfg, two); err != nil {
+		t.Errorf("response with the minimum number of files failed: %v", err)
+	}
+}
+
+func TestSortFiles(t *testing.T) {
+	files := []File{{Name: "z.go"}, {Name: "cmd/main.go"}, {Name: "a.go"}, {Name: "cmd/a.go"}}
+	tests := []struct {
+		sort, preserve bool
+		want           []string
+	}{
+		{false, false, []string{"z.go", "cmd/main.go", "a.go", "cmd/a.go"}},
+		{true, false, []string{"a.go", "cmd/a.go", "cmd/main.go", "z.go"}},
+		{true, true, []string{"z.go", "cmd/main.go", "a.go", "cmd/a.go"}},
+	}
+	for _, tt := range tests {
+		cfg := DefaultConfig()
+		cfg.SortFiles = tt.sort
+		cfg.PreserveOrder = tt.preserve
+		if got := fileNames(transformFiles(cfg, files)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sort %v, preserve %v: order = %v, want %v", tt.sort, tt.preserve, got, tt.want)
+		}
+	}
+	if files[0].Name != "z.go" {
+		t.Error("sorting changed the order of the original files")
+	}
+}
+
+func TestSortFilesWriteAndManifestOrder(t *testing.T) {
+	fsys := newMemFS()
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	cfg.SortFiles = true
+	files := transformFiles(cfg, []File{{Name: "b.txt", Code: "b"}, {Name: "a/c.txt", Code: "c"}, {Name: "a.txt", Code: "a"}})
+	var written []string
+	captureStdout(t, func() {
+		if err := writeFilesFunc(fsys, cfg, files, func(file File, _ writeResult) { written = append(written, file.Name) }); err != nil {
+			t.Fatal(err)
+		}
+	})
+	want := []string{"a.txt", "a/c.txt", "b.txt"}
+	if !reflect.DeepEqual(written, want) {
+		t.Errorf("written in order %v, want %v", written, want)
+	}
+
+	// The manifest is sorted even when the files were not
+	reversed := []File{files[2], files[1], files[0]}
+	manifest, err := newManifest(fsys, cfg, reversed, make([]writeResult, len(reversed)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, entry := range manifest.Files {
+		names = append(names, entry.Name)
+	}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("manifest order = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,74 @@
+package agentcoder
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// readResponse reads a response saved with --debug-dump, or the raw text of a
+// response if the file is not a dump. The format and parser recorded in a dump
+// override those of cfg.
+func readResponse(cfg *Config, path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var dump responseDump
+	if json.Unmarshal(data, &dump) != nil || dump.Text == "" {
+		return string(data), nil
+	}
+	dump.apply(cfg)
+	return dump.Text, nil
+}
+
+// validateResponse parses the response and applies every check made before
+// files are written, returning the files that would be written.
+func validateResponse(cfg Config, text string) ([]File, error) {
+	files, err := filesFromText(cfg, text)
+	if err != nil {
+		return nil, err
+	}
+	files = transformFiles(cfg, files)
+	if files, err = filterFiles(cfg, files); err != nil {
+		return nil, err
+	}
+	if err := checkFileCount(cfg, files); err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		if err := checkPath(file.Name, cfg.MaxDirDepth); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// runValidateResponse implements the validate-response subcommand, which
+// checks that a saved response parses and passes validation without writing
+// anything. It exits with status 1 if the response is invalid.
+func runValidateResponse(args []string) {
+	cfg, rest, err := parseConfig(args, nil)
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+	if len(rest) != 1 {
+		fmt.Println("Usage: validate-response [flags] <response-file>")
+		os.Exit(1)
+	}
+	text, err := readResponse(&cfg, rest[0])
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	files, err := validateResponse(cfg, text)
+	if err != nil {
+		fmt.Printf("FAIL %s: %v\n", rest[0], err)
+		os.Exit(1)
+	}
+	fmt.Printf("PASS %s: %d file(s)\n", rest[0], len(files))
+	for _, file := range files {
+		fmt.Printf("  %s (%d bytes)\n", file.Name, len(file.Code))
+	}
+}
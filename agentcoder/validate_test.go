@@ -0,0 +1,57 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidateResponse(t *testing.T) {
+	raw := filepath.Join(t.TempDir(), "response.txt")
+	if err := os.WriteFile(raw, []byte(`[{"file_name": "main.go", "source_code": "package main\n"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	escaping := filepath.Join(t.TempDir(), "escaping.txt")
+	if err := os.WriteFile(escaping, []byte(`[{"file_name": "../main.go", "source_code": "package main\n"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path    string
+		want    []string
+		wantErr bool
+	}{
+		{path: raw, want: []string{"main.go"}},
+		{path: filepath.Join("testdata", "responses", "clean.json"), want: []string{"main.go", "README.md"}},
+		{path: filepath.Join("testdata", "responses", "text.json"), want: []string{"main.go"}},
+		{path: filepath.Join("testdata", "responses", "truncated.json"), wantErr: true},
+		{path: escaping, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(filepath.Base(tt.path), func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputDir = t.TempDir()
+			text, err := readResponse(&cfg, tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			files, err := validateResponse(cfg, text)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("validateResponse() accepted %v", fileNames(files))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := fileNames(files); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("validateResponse() = %v, want %v", got, tt.want)
+			}
+			// Nothing is written
+			if entries, _ := os.ReadDir(cfg.OutputDir); len(entries) != 0 {
+				t.Errorf("validation wrote %d file(s)", len(entries))
+			}
+		})
+	}
+}
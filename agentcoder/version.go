@@ -0,0 +1,26 @@
+package agentcoder
+
+import "runtime/debug"
+
+// version is the version of agent_coder, set for release builds with
+// -ldflags "-X agent_coder/agentcoder.version=v1.2.3".
+var version string
+
+// toolVersion returns the version of agent_coder, falling back to the module
+// version and VCS revision recorded in the build info.
+func toolVersion() string {
+	if version != "" {
+		return version
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	v := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" {
+			v += "+" + setting.Value
+		}
+	}
+	return v
+}
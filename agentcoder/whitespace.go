@@ -0,0 +1,44 @@
+package agentcoder
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// trimTrailingWhitespace removes spaces and tabs from the end of every line.
+func trimTrailingWhitespace(code string) string {
+	lines := strings.Split(code, "\n")
+	for i, line := range lines {
+		cr := strings.HasSuffix(line, "\r")
+		line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " \t")
+		if cr {
+			line += "\r"
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
+
+// trimFiles removes trailing whitespace from every file except those with one
+// of the skipped extensions, where it may be significant, and reports how many
+// files were modified. The skipped extensions are normalized like those of
+// --allowed-ext, so "md", ".md" and ".MD" are the same.
+func trimFiles(files []File, skip []string) []File {
+	modified := 0
+	for i, file := range files {
+		ext := strings.ToLower(filepath.Ext(file.Name))
+		if slices.ContainsFunc(skip, func(e string) bool { return normalizeExt(e) == ext }) {
+			continue
+		}
+		if trimmed := trimTrailingWhitespace(file.Code); trimmed != file.Code {
+			files[i].Code = trimmed
+			modified++
+		}
+	}
+	if modified > 0 {
+		fmt.Printf("Trimmed trailing whitespace in %d file(s)\n", modified)
+	}
+	return files
+}
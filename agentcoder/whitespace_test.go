@@ -0,0 +1,50 @@
+package agentcoder
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTrimTrailingWhitespace(t *testing.T) {
+	got := trimTrailingWhitespace("package main  \n\nfunc main() {\t\n\tx := \"a  \"\r\n}\t \n")
+	want := "package main\n\nfunc main() {\n\tx := \"a  \"\r\n}\n"
+	if got != want {
+		t.Errorf("trimTrailingWhitespace() = %q, want %q", got, want)
+	}
+}
+
+func TestTrimFilesSkipsExtensions(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.TrimTrailingWS = true
+	files := []File{
+		{Name: "main.go", Code: "package main \n"},
+		{Name: "README.md", Code: "line break  \nnext\n"},
+	}
+	var trimmed []File
+	out := captureStdout(t, func() { trimmed = transformFiles(cfg, files) })
+	if trimmed[0].Code != "package main\n" {
+		t.Errorf("main.go = %q, want the trailing space removed", trimmed[0].Code)
+	}
+	if trimmed[1].Code != "line break  \nnext\n" {
+		t.Errorf("README.md = %q, want it untouched", trimmed[1].Code)
+	}
+	if !strings.Contains(out, "Trimmed trailing whitespace in 1 file(s)") {
+		t.Errorf("output %q does not report one modified file", out)
+	}
+}
+
+func TestTrimFilesNormalizesSkippedExtensions(t *testing.T) {
+	for _, skip := range []string{"md", ".md", ".MD", " Md "} {
+		files := []File{
+			{Name: "README.md", Code: "line break  \n"},
+			{Name: "NOTES.Md", Code: "line break  \n"},
+		}
+		var trimmed []File
+		captureStdout(t, func() { trimmed = trimFiles(files, []string{skip}) })
+		for _, file := range trimmed {
+			if file.Code != "line break  \n" {
+				t.Errorf("skip %q: %s = %q, want it untouched", skip, file.Name, file.Code)
+			}
+		}
+	}
+}
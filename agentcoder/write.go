@@ -0,0 +1,313 @@
+package agentcoder
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding"
+)
+
+// Outcomes of writing a generated file.
+const (
+	writeWritten   = "written"
+	writeUnchanged = "unchanged"
+	writeRejected  = "rejected"
+	writeFailed    = "failed"
+)
+
+// writeResult is the outcome of writing one generated file.
+type writeResult struct {
+	Path   string `json:"path"`            // Path of the file in the output directory
+	Size   int    `json:"size"`            // Size of the generated content in bytes
+	Status string `json:"status"`          // One of written, unchanged, rejected, failed, skipped or linked
+	Error  string `json:"error,omitempty"` // Why the file was rejected or failed
+
+	// Lines added to and removed from the existing file, with --show-diff-stat
+	Added   int `json:"added,omitempty"`
+	Removed int `json:"removed,omitempty"`
+
+	// Problems found with the file, whether or not it was written
+	Issues []validationIssue `json:"issues,omitempty"`
+
+	// Milliseconds taken to write, validate and format the file, with --verbose
+	DurationMS float64 `json:"duration_ms,omitempty"`
+}
+
+// validationIssue is a problem found with a generated file.
+type validationIssue struct {
+	Category string `json:"category"` // One of path, write, parse, format, encoding or duplicate
+	Message  string `json:"message"`  // Description of the problem
+}
+
+// writeFiles writes the generated files into the configured output directory
+// of fsys. Failures for individual files are reported and skipped.
+func writeFiles(fsys FS, cfg Config, files []File) error {
+	return writeFilesFunc(fsys, cfg, files, nil)
+}
+
+// writeFilesFunc is like writeFiles but calls report, if not nil, with the
+// outcome of each file once it has been written and formatted, or skipped.
+func writeFilesFunc(fsys FS, cfg Config, files []File, report func(File, writeResult)) error {
+	outputDir := cfg.OutputDir
+	enc, err := lookupEncoding(cfg.OutputEncoding)
+	if err != nil {
+		return err
+	}
+
+	// Create output directory if it doesn't exist
+	if err := checkOutputDir(fsys, outputDir); err != nil {
+		return err
+	}
+	if err := fsys.MkdirAll(outputDir, os.FileMode(cfg.DirMode)); err != nil {
+		return fmt.Errorf("creating output directory: %w", err)
+	}
+
+	// Enable the formatter of the language of the files
+	cfg.Language = resolveLanguage(cfg.Language, files)
+
+	// Hand the files to the configured owner
+	owner, err := newFileOwner(fsys, cfg.Owner)
+	if err != nil {
+		return err
+	}
+
+	// Find files that repeat the content of another
+	var duplicates map[string]string
+	if cfg.DedupeContent {
+		if !dedupeActions[cfg.DedupeAction] {
+			return fmt.Errorf("unknown dedupe action %q, use warn, skip or symlink", cfg.DedupeAction)
+		}
+		duplicates = findDuplicates(files)
+	}
+
+	// Write each file to the output directory
+	unchanged, changed, malformed := 0, 0, 0
+	var stats []diffStat
+	statuses := make(map[string]string, len(files))
+	for i, file := range files {
+		var result writeResult
+		var err error
+		original, duplicate := duplicates[file.Name]
+		handled := false
+		if duplicate && (statuses[original] == writeWritten || statuses[original] == writeUnchanged) {
+			result, handled = writeDuplicate(fsys, cfg, i, file, original)
+		}
+		if !handled {
+			result, err = writeFile(fsys, cfg, enc, i, file)
+		}
+		if duplicate {
+			result.Issues = append(result.Issues, validationIssue{Category: "duplicate", Message: "same content as " + original})
+		}
+		statuses[file.Name] = result.Status
+		for _, issue := range result.Issues {
+			if issue.Category == "parse" {
+				malformed++
+				break
+			}
+		}
+		if result.Status == writeWritten {
+			owner.chown(filepath.Join(outputDir, file.Name))
+			if cfg.Readonly {
+				makeReadonly(fsys, filepath.Join(outputDir, file.Name))
+			}
+		}
+		switch result.Status {
+		case writeUnchanged:
+			unchanged++
+		case writeWritten:
+			changed++
+		}
+		if cfg.ShowDiffStat && result.Status == writeWritten {
+			stats = append(stats, diffStat{Name: file.Name, Added: result.Added, Removed: result.Removed})
+		}
+		if report != nil {
+			report(file, result)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if unchanged > 0 {
+		fmt.Printf("\n%d file(s) unchanged\n", unchanged)
+	}
+	if cfg.SkipIdentical {
+		fmt.Printf("%d file(s) changed\n", changed)
+	}
+	if len(stats) > 0 {
+		fmt.Println()
+		printDiffStat(stats)
+	}
+	fmt.Printf("\nAll files have been written to the '%s' directory\n", outputDir)
+	if cfg.Readonly && changed > 0 {
+		fmt.Println("The files are read-only to discourage manual edits. Change the prompt and run again with --readonly to regenerate them.")
+	}
+	if cfg.FailOnInvalid && malformed > 0 {
+		return fmt.Errorf("%d file(s) are not well-formed", malformed)
+	}
+	return nil
+}
+
+// checkOutputDir returns an error if the output directory exists but is not a
+// directory, which would otherwise only be reported once the files are written.
+func checkOutputDir(fsys FS, dir string) error {
+	info, err := fsys.Stat(dir)
+	if err != nil || info.IsDir() {
+		return nil
+	}
+	return fmt.Errorf("output path %s exists and is not a directory", dir)
+}
+
+// writeFile writes the i-th generated file, formatting and transcoding it as
+// configured, and returns the outcome. Errors that must stop the whole run are
+// returned in addition to the result.
+// In verbose mode the time taken is recorded and printed.
+func writeFile(fsys FS, cfg Config, enc encoding.Encoding, i int, file File) (writeResult, error) {
+	start := time.Now()
+	result, err := processFile(fsys, cfg, enc, i, file)
+	if cfg.Verbose {
+		elapsed := time.Since(start)
+		result.DurationMS = float64(elapsed) / float64(time.Millisecond)
+		fmt.Printf("%s processed in %v\n", file.Name, elapsed.Round(time.Microsecond))
+	}
+	return result, err
+}
+
+// processFile does the work of writeFile.
+func processFile(fsys FS, cfg Config, enc encoding.Encoding, i int, file File) (writeResult, error) {
+	result := writeResult{Path: file.Name, Size: len(file.Code)}
+	fail := func(status, category string, err error) (writeResult, error) {
+		result.Status = status
+		result.Error = err.Error()
+		result.Issues = append(result.Issues, validationIssue{Category: category, Message: err.Error()})
+		return result, nil
+	}
+
+	if err := checkPath(file.Name, cfg.MaxDirDepth); err != nil {
+		fmt.Printf("Error: rejected file %d: %v\n", i+1, err)
+		return fail(writeRejected, "path", err)
+	}
+	fullPath := filepath.Join(cfg.OutputDir, file.Name)
+	existingPath := fullPath
+	if cfg.existingDir != "" {
+		existingPath = filepath.Join(cfg.existingDir, file.Name)
+	}
+
+	// Leave files that are identical on disk untouched
+	if cfg.SkipIdentical && isUnchanged(fsys, cfg, enc, existingPath, file) {
+		fmt.Printf("\nFile %d: %s unchanged\n", i+1, file.Name)
+		result.Status = writeUnchanged
+		return result, nil
+	}
+
+	// Create subdirectories if necessary
+	dir := filepath.Dir(fullPath)
+	if err := fsys.MkdirAll(dir, os.FileMode(cfg.DirMode)); err != nil {
+		fmt.Printf("Error creating directory for %s: %v\n", file.Name, err)
+		return fail(writeFailed, "write", err)
+	}
+
+	// Count the changed lines before the existing file is replaced
+	if cfg.ShowDiffStat {
+		existing, _ := fsys.ReadFile(existingPath)
+		result.Added, result.Removed = countChanges(string(existing), file.Code)
+	}
+
+	// Report the declarations of an existing Go file that change
+	if cfg.SemanticDiff && strings.EqualFold(filepath.Ext(file.Name), ".go") {
+		if existing, err := fsys.ReadFile(existingPath); err == nil {
+			printSemanticDiff(file.Name, string(existing), file.Code)
+		}
+	}
+
+	// Write file
+	if cfg.Readonly {
+		makeWritable(fsys, fullPath)
+	}
+	if err := fsys.WriteFile(fullPath, []byte(file.Code), 0644); err != nil {
+		fmt.Printf("Error writing file %s: %v\n", file.Name, err)
+		return fail(writeFailed, "write", err)
+	}
+
+	fmt.Printf("\nFile %d: %s written to %s\n", i+1, file.Name, fullPath)
+
+	// Record Go files that do not parse, which no later step can fix
+	if strings.EqualFold(filepath.Ext(file.Name), ".go") {
+		if _, err := parser.ParseFile(token.NewFileSet(), file.Name, file.Code, parser.SkipObjectResolution); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			result.Issues = append(result.Issues, validationIssue{Category: "parse", Message: err.Error()})
+		}
+	}
+
+	// Record malformed data files
+	if cfg.Validate || cfg.FailOnInvalid {
+		if err := validateData(file.Name, file.Code); err != nil {
+			fmt.Printf("Warning: %v\n", err)
+			result.Issues = append(result.Issues, validationIssue{Category: "parse", Message: err.Error()})
+		}
+	}
+
+	// Format the file, reporting failures without stopping the run unless a
+	// formatter hangs in strict mode
+	if cfg.FormatCode || autoFormat(cfg, file.Name) {
+		if err := formatFile(fsys, cfg.Formatters, fullPath, cfg.commandTimeout()); err != nil {
+			if cfg.Strict && errors.Is(err, errCommandTimeout) {
+				result, _ = fail(writeFailed, "format", err)
+				return result, fmt.Errorf("formatting %s: %w", file.Name, err)
+			}
+			fmt.Printf("Warning: could not format %s: %v\n", file.Name, err)
+			result.Issues = append(result.Issues, validationIssue{Category: "format", Message: err.Error()})
+		}
+	}
+
+	// Transcode the file once it is final
+	if enc != nil {
+		if err := transcodeFile(fsys, fullPath, enc); err != nil {
+			fmt.Printf("Error encoding file %s as %s: %v\n", file.Name, cfg.OutputEncoding, err)
+			return fail(writeFailed, "encoding", err)
+		}
+	}
+
+	result.Status = writeWritten
+	return result, nil
+}
+
+// isUnchanged reports whether writing file to path would leave the file in
+// fsys as it is, either because it holds the generated content or because it
+// holds the content the formatter turned it into after it was last written.
+func isUnchanged(fsys FS, cfg Config, enc encoding.Encoding, path string, file File) bool {
+	if isIdentical(fsys, path, file.Code, enc) {
+		return true
+	}
+	if !cfg.FormatCode && !autoFormat(cfg, file.Name) {
+		return false
+	}
+	if _, err := fsys.Stat(path); err != nil {
+		return false
+	}
+	formatted, err := formatContent(fsys, cfg.Formatters, path, file.Code, cfg.commandTimeout())
+	return err == nil && formatted != file.Code && isIdentical(fsys, path, formatted, enc)
+}
+
+// isIdentical reports whether the file at path exists in fsys with exactly the
+// given content, in the output encoding enc if not nil, comparing content
+// hashes.
+func isIdentical(fsys FS, path, content string, enc encoding.Encoding) bool {
+	existing, err := fsys.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	data := []byte(content)
+	if enc != nil {
+		if data, err = enc.NewEncoder().Bytes(data); err != nil {
+			return false
+		}
+	}
+	return hashContent(existing) == hashContent(data)
+}
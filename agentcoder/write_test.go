@@ -0,0 +1,112 @@
+package agentcoder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeStatuses writes the files and returns the status of each by name.
+func writeStatuses(t *testing.T, fsys FS, cfg Config, files []File) map[string]string {
+	t.Helper()
+	statuses := make(map[string]string)
+	err := writeFilesFunc(fsys, cfg, files, func(file File, result writeResult) {
+		statuses[file.Name] = result.Status
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return statuses
+}
+
+func TestSkipIdenticalPreservesModTime(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.OutputDir = t.TempDir()
+	cfg.SkipIdentical = true
+	path := filepath.Join(cfg.OutputDir, "a.txt")
+	if err := os.WriteFile(path, []byte("same"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	statuses := writeStatuses(t, osFS{}, cfg, []File{{Name: "a.txt", Code: "same"}, {Name: "b.txt", Code: "new"}})
+	if statuses["a.txt"] != writeUnchanged || statuses["b.txt"] != writeWritten {
+		t.Errorf("statuses = %v, want a.txt unchanged and b.txt written", statuses)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.ModTime().Equal(old) {
+		t.Errorf("mod time = %v, want %v", info.ModTime(), old)
+	}
+}
+
+func TestSkipIdenticalAfterFormatting(t *testing.T) {
+	unformatted := File{Name: "main.go", Code: "package main\nfunc main() {\n}\n"}
+	tests := []struct {
+		name string
+		cfg  func(cfg *Config)
+		file File
+	}{
+		{name: "gofmt", cfg: func(cfg *Config) { cfg.FormatCode = true }, file: unformatted},
+		{name: "auto-format", cfg: func(cfg *Config) {}, file: unformatted},
+		{name: "external", cfg: func(cfg *Config) {
+			cfg.FormatCode = true
+			cfg.Formatters = map[string]string{".txt": fakeFormatter(t)}
+		}, file: File{Name: "notes.txt", Code: "hello"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.OutputDir = t.TempDir()
+			cfg.SkipIdentical = true
+			tt.cfg(&cfg)
+			if statuses := writeStatuses(t, osFS{}, cfg, []File{tt.file}); statuses[tt.file.Name] != writeWritten {
+				t.Fatalf("first run: %v, want written", statuses)
+			}
+			if statuses := writeStatuses(t, osFS{}, cfg, []File{tt.file}); statuses[tt.file.Name] != writeUnchanged {
+				t.Errorf("second run: %v, want unchanged", statuses)
+			}
+			entries, err := os.ReadDir(cfg.OutputDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Errorf("output directory has %d entries, want only %s", len(entries), tt.file.Name)
+			}
+		})
+	}
+}
+
+func TestOutputDirIsFile(t *testing.T) {
+	fsys := newMemFS()
+	if err := fsys.MkdirAll("dir", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := fsys.WriteFile("out", []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{"dir", "missing"} {
+		if err := checkOutputDir(fsys, dir); err != nil {
+			t.Errorf("checkOutputDir(%q) = %v, want nil", dir, err)
+		}
+	}
+
+	want := "output path out exists and is not a directory"
+	if err := checkOutputDir(fsys, "out"); err == nil || err.Error() != want {
+		t.Errorf("checkOutputDir() = %v, want %q", err, want)
+	}
+	cfg := DefaultConfig()
+	cfg.OutputDir = "out"
+	if err := writeFiles(fsys, cfg, []File{{Name: "main.go", Code: "package main\n"}}); err == nil || err.Error() != want {
+		t.Errorf("writeFiles() = %v, want %q", err, want)
+	}
+	if got := fsys.Paths(); len(got) != 1 {
+		t.Errorf("files after the failed write: %v", got)
+	}
+}